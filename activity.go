@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// accountActivity is a cached view of an account's on-chain history
+type accountActivity struct {
+	nonce     uint64
+	balance   *big.Int
+	fetchedAt time.Time
+}
+
+// ActivityCache briefly caches nonce and balance lookups for forward.from
+type ActivityCache struct {
+	mu      sync.Mutex
+	entries map[common.Address]accountActivity
+	ttl     time.Duration
+}
+
+// NewActivityCache creates a cache whose entries live for ttl
+func NewActivityCache(ttl time.Duration) *ActivityCache {
+	return &ActivityCache{
+		entries: make(map[common.Address]accountActivity),
+		ttl:     ttl,
+	}
+}
+
+// Cleanup drops expired entries
+func (c *ActivityCache) Cleanup(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for addr, entry := range c.entries {
+		if now.Sub(entry.fetchedAt) >= c.ttl {
+			delete(c.entries, addr)
+		}
+	}
+}
+
+// accountActivity returns the confirmed nonce and balance of addr, cached
+func (s *Server) accountActivity(addr common.Address) (accountActivity, error) {
+	c := s.activity
+	c.mu.Lock()
+	entry, ok := c.entries[addr]
+	c.mu.Unlock()
+	if ok && time.Since(entry.fetchedAt) < c.ttl {
+		return entry, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	nonce, err := s.client.NonceAt(ctx, addr, nil)
+	if err != nil {
+		return accountActivity{}, fmt.Errorf("failed to get nonce: %v", err)
+	}
+	balance, err := s.client.BalanceAt(ctx, addr, nil)
+	if err != nil {
+		return accountActivity{}, fmt.Errorf("failed to get balance: %v", err)
+	}
+
+	entry = accountActivity{nonce: nonce, balance: balance, fetchedAt: time.Now()}
+	c.mu.Lock()
+	c.entries[addr] = entry
+	c.mu.Unlock()
+	return entry, nil
+}
+
+// checkFromActivity enforces REQUIRE_FROM_NONCE and REQUIRE_FROM_BALANCE,
+// returning an error code and message when forward.from doesn't qualify
+func (s *Server) checkFromActivity(addr common.Address) (code, message string, err error) {
+	if !s.config.RequireFromNonce && s.config.RequireFromBalance == nil {
+		return "", "", nil
+	}
+
+	activity, err := s.accountActivity(addr)
+	if err != nil {
+		return "", "", err
+	}
+
+	if s.config.RequireFromNonce && activity.nonce == 0 {
+		return "FROM_NO_ACTIVITY", "Address has no prior on-chain transactions", nil
+	}
+	if s.config.RequireFromBalance != nil && activity.balance.Cmp(s.config.RequireFromBalance) < 0 {
+		return "FROM_LOW_BALANCE", fmt.Sprintf("Address balance is below the required %s wei", s.config.RequireFromBalance.String()), nil
+	}
+	return "", "", nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gorilla/mux"
+)
+
+// requireAdmin guards a handler with the configured admin API key. The key is
+// accepted from the X-Admin-Key header or as a bearer token. When no key is
+// configured the admin API is disabled entirely.
+func (s *Server) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.config.AdminAPIKey == "" {
+			s.sendError(w, http.StatusNotFound, "Admin API is disabled", "")
+			return
+		}
+
+		key := r.Header.Get("X-Admin-Key")
+		if key == "" {
+			key = strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		}
+
+		if subtle.ConstantTimeCompare([]byte(key), []byte(s.config.AdminAPIKey)) != 1 {
+			log.Printf("❌ Unauthorized admin request: %s %s\n", r.Method, r.URL.Path)
+			s.sendError(w, http.StatusUnauthorized, "Unauthorized", "")
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// blocklistHandler lists addresses auto-blocked for repeated reverts
+func (s *Server) blocklistHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.reverts.List())
+}
+
+// unblockHandler manually removes an address from the auto-blocklist
+func (s *Server) unblockHandler(w http.ResponseWriter, r *http.Request) {
+	addrStr := mux.Vars(r)["address"]
+	if !common.IsHexAddress(addrStr) {
+		s.sendError(w, http.StatusBadRequest, "Invalid address", "")
+		return
+	}
+	address := common.HexToAddress(addrStr).Hex()
+
+	if !s.reverts.Unblock(address) {
+		s.sendError(w, http.StatusNotFound, "Address is not blocked", "")
+		return
+	}
+
+	log.Printf("🔓 Address manually unblocked: %s\n", address)
+	s.metrics.Set("relay_autoblocklist_size", float64(len(s.reverts.List())))
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"address": address,
+	})
+}
+
+// registerPprof exposes the runtime profiler under /debug/pprof/ behind the
+// admin API key. It is only called when ENABLE_PPROF is set.
+func (s *Server) registerPprof(r *mux.Router) {
+	r.HandleFunc("/debug/pprof/cmdline", s.requireAdmin(pprof.Cmdline))
+	r.HandleFunc("/debug/pprof/profile", s.requireAdmin(pprof.Profile))
+	r.HandleFunc("/debug/pprof/symbol", s.requireAdmin(pprof.Symbol))
+	r.HandleFunc("/debug/pprof/trace", s.requireAdmin(pprof.Trace))
+	r.PathPrefix("/debug/pprof/").HandlerFunc(s.requireAdmin(pprof.Index))
+}
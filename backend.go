@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// EthBackend is the subset of the node API the relayer uses
+type EthBackend interface {
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	BlockNumber(ctx context.Context) (uint64, error)
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+	CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+	ChainID(ctx context.Context) (*big.Int, error)
+	CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error)
+	EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error)
+	PendingNonceAt(ctx context.Context, account common.Address) (uint64, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	Client() *rpc.Client
+}
+
+// instrumentedBackend records latency and errors for every RPC call as
+// rpc_call_duration_seconds{method} and rpc_call_errors_total{method}
+type instrumentedBackend struct {
+	client  *ethclient.Client
+	metrics *Metrics
+}
+
+// newInstrumentedBackend wraps client with RPC metrics
+func newInstrumentedBackend(client *ethclient.Client, metrics *Metrics) *instrumentedBackend {
+	return &instrumentedBackend{client: client, metrics: metrics}
+}
+
+// observe records one call to method that started at start
+func (b *instrumentedBackend) observe(method string, start time.Time, err error) {
+	b.metrics.Observe("rpc_call_duration_seconds", time.Since(start).Seconds(), "method", method)
+	if err != nil {
+		b.metrics.Inc("rpc_call_errors_total", "method", method)
+	}
+}
+
+func (b *instrumentedBackend) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	start := time.Now()
+	balance, err := b.client.BalanceAt(ctx, account, blockNumber)
+	b.observe("BalanceAt", start, err)
+	return balance, err
+}
+
+func (b *instrumentedBackend) BlockNumber(ctx context.Context) (uint64, error) {
+	start := time.Now()
+	number, err := b.client.BlockNumber(ctx)
+	b.observe("BlockNumber", start, err)
+	return number, err
+}
+
+func (b *instrumentedBackend) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	start := time.Now()
+	block, err := b.client.BlockByNumber(ctx, number)
+	b.observe("BlockByNumber", start, err)
+	return block, err
+}
+
+func (b *instrumentedBackend) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	start := time.Now()
+	result, err := b.client.CallContract(ctx, msg, blockNumber)
+	b.observe("CallContract", start, err)
+	return result, err
+}
+
+func (b *instrumentedBackend) ChainID(ctx context.Context) (*big.Int, error) {
+	start := time.Now()
+	chainID, err := b.client.ChainID(ctx)
+	b.observe("ChainID", start, err)
+	return chainID, err
+}
+
+func (b *instrumentedBackend) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	start := time.Now()
+	code, err := b.client.CodeAt(ctx, account, blockNumber)
+	b.observe("CodeAt", start, err)
+	return code, err
+}
+
+func (b *instrumentedBackend) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	start := time.Now()
+	gas, err := b.client.EstimateGas(ctx, msg)
+	b.observe("EstimateGas", start, err)
+	return gas, err
+}
+
+func (b *instrumentedBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	start := time.Now()
+	header, err := b.client.HeaderByNumber(ctx, number)
+	b.observe("HeaderByNumber", start, err)
+	return header, err
+}
+
+func (b *instrumentedBackend) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	start := time.Now()
+	nonce, err := b.client.NonceAt(ctx, account, blockNumber)
+	b.observe("NonceAt", start, err)
+	return nonce, err
+}
+
+func (b *instrumentedBackend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	start := time.Now()
+	nonce, err := b.client.PendingNonceAt(ctx, account)
+	b.observe("PendingNonceAt", start, err)
+	return nonce, err
+}
+
+func (b *instrumentedBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	start := time.Now()
+	err := b.client.SendTransaction(ctx, tx)
+	b.observe("SendTransaction", start, err)
+	return err
+}
+
+func (b *instrumentedBackend) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	start := time.Now()
+	price, err := b.client.SuggestGasPrice(ctx)
+	b.observe("SuggestGasPrice", start, err)
+	return price, err
+}
+
+func (b *instrumentedBackend) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	start := time.Now()
+	receipt, err := b.client.TransactionReceipt(ctx, txHash)
+	// A missing receipt is the normal answer while a transaction is pending
+	if err == ethereum.NotFound {
+		b.observe("TransactionReceipt", start, nil)
+	} else {
+		b.observe("TransactionReceipt", start, err)
+	}
+	return receipt, err
+}
+
+// Client exposes the raw RPC client for calls without a typed wrapper
+func (b *instrumentedBackend) Client() *rpc.Client {
+	return b.client.Client()
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// fakeBackend is an in-memory EthBackend. Sent transactions are mined
+// immediately unless noMine is set. sendHook, when set, runs first on every
+// SendTransaction with the backend locked and can fail the send; sendErrs
+// are then returned in order by successive calls before any send succeeds.
+type fakeBackend struct {
+	mu           sync.Mutex
+	gasPrice     *big.Int
+	baseFee      *big.Int
+	estimate     uint64
+	estimateErr  error
+	balance      *big.Int
+	pendingNonce uint64
+	latestNonce  uint64
+	sendHook     func(tx *types.Transaction) error
+	sendErrs     []error
+	sent         []*types.Transaction
+	noMine       bool
+	receipts     map[common.Hash]*types.Receipt
+	blocks       []*types.Block
+	blockTime    uint64
+	callContract func(msg ethereum.CallMsg) ([]byte, error)
+	// views answers boolean view calls by method signature; unknown calls
+	// return false
+	views map[string]bool
+}
+
+// viewSelectors maps the selectors of the boolean views the relayer reads
+var viewSelectors = map[string]string{}
+
+func init() {
+	for _, sig := range []string{"minted(address)", "isNonceUsed(address,uint32,uint256)", "isCallerAllowed(address)"} {
+		viewSelectors[string(crypto.Keccak256([]byte(sig))[:4])] = sig
+	}
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{
+		gasPrice: big.NewInt(10e9),
+		baseFee:  big.NewInt(10e9),
+		estimate: 100000,
+		balance:  new(big.Int).Mul(big.NewInt(100), big.NewInt(1e18)),
+		receipts: make(map[common.Hash]*types.Receipt),
+		views:    map[string]bool{"isCallerAllowed(address)": true},
+	}
+}
+
+// mine records a successful receipt for tx in a new block
+func (b *fakeBackend) mine(tx *types.Transaction) *types.Receipt {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.mineLocked(tx)
+}
+
+func (b *fakeBackend) mineLocked(tx *types.Transaction) *types.Receipt {
+	number := big.NewInt(int64(len(b.blocks) + 1))
+	block := types.NewBlockWithHeader(&types.Header{Number: number, Time: b.blockTime}).WithBody(types.Body{Transactions: []*types.Transaction{tx}})
+	b.blocks = append(b.blocks, block)
+	receipt := &types.Receipt{
+		Status:            types.ReceiptStatusSuccessful,
+		TxHash:            tx.Hash(),
+		BlockNumber:       number,
+		BlockHash:         block.Hash(),
+		GasUsed:           21000,
+		EffectiveGasPrice: tx.GasPrice(),
+	}
+	b.receipts[tx.Hash()] = receipt
+	if tx.Nonce() >= b.latestNonce {
+		b.latestNonce = tx.Nonce() + 1
+	}
+	return receipt
+}
+
+func (b *fakeBackend) sentTxs() []*types.Transaction {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]*types.Transaction(nil), b.sent...)
+}
+
+func (b *fakeBackend) BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return new(big.Int).Set(b.balance), nil
+}
+
+func (b *fakeBackend) BlockNumber(ctx context.Context) (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return uint64(len(b.blocks)), nil
+}
+
+func (b *fakeBackend) BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := number.Int64()
+	if n < 1 || n > int64(len(b.blocks)) {
+		return types.NewBlockWithHeader(&types.Header{Number: number}), nil
+	}
+	return b.blocks[n-1], nil
+}
+
+func (b *fakeBackend) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if b.callContract != nil {
+		return b.callContract(msg)
+	}
+	word := make([]byte, 32)
+	if len(msg.Data) >= 4 {
+		b.mu.Lock()
+		if b.views[viewSelectors[string(msg.Data[:4])]] {
+			word[31] = 1
+		}
+		b.mu.Unlock()
+	}
+	return word, nil
+}
+
+func (b *fakeBackend) ChainID(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(1337), nil
+}
+
+func (b *fakeBackend) CodeAt(ctx context.Context, account common.Address, blockNumber *big.Int) ([]byte, error) {
+	return nil, nil
+}
+
+func (b *fakeBackend) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.estimate, b.estimateErr
+}
+
+func (b *fakeBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return &types.Header{Number: big.NewInt(int64(len(b.blocks))), BaseFee: b.baseFee, Time: b.blockTime}, nil
+}
+
+func (b *fakeBackend) NonceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.latestNonce, nil
+}
+
+func (b *fakeBackend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return max(b.pendingNonce, b.latestNonce), nil
+}
+
+func (b *fakeBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.sendHook != nil {
+		if err := b.sendHook(tx); err != nil {
+			return err
+		}
+	}
+	if len(b.sendErrs) > 0 {
+		err := b.sendErrs[0]
+		b.sendErrs = b.sendErrs[1:]
+		return err
+	}
+	b.sent = append(b.sent, tx)
+	if tx.Nonce() >= b.pendingNonce {
+		b.pendingNonce = tx.Nonce() + 1
+	}
+	if !b.noMine {
+		b.mineLocked(tx)
+	}
+	return nil
+}
+
+func (b *fakeBackend) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return new(big.Int).Set(b.gasPrice), nil
+}
+
+func (b *fakeBackend) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if receipt, ok := b.receipts[txHash]; ok {
+		return receipt, nil
+	}
+	return nil, ethereum.NotFound
+}
+
+func (b *fakeBackend) Client() *rpc.Client { return nil }
+
+// newTestServer builds a Server around backend with a fresh relayer key and
+// in-memory stores
+func newTestServer(t *testing.T, backend EthBackend) *Server {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	signer := &localSigner{key: key, address: crypto.PubkeyToAddress(key.PublicKey)}
+
+	txStore, err := NewTxStore("")
+	if err != nil {
+		t.Fatalf("NewTxStore: %v", err)
+	}
+	mintHistory, err := NewMintHistory("")
+	if err != nil {
+		t.Fatalf("NewMintHistory: %v", err)
+	}
+	maintenance, err := NewMaintenance("")
+	if err != nil {
+		t.Fatalf("NewMaintenance: %v", err)
+	}
+
+	config := Config{
+		Hubs:        []HubConfig{testHub(t)},
+		NFTContract: common.HexToAddress("0x00000000000000000000000000000000000000b1"),
+		ChainID:     big.NewInt(1337),
+		MaxGasPrice: big.NewInt(500e9),
+		ReceiptPoll: 10 * time.Millisecond,
+		Retry:       RetryPolicy{MaxAttempts: 1},
+	}
+	return &Server{
+		config:            config,
+		client:            backend,
+		nonces:            NewNonceManager(backend),
+		watchers:          NewWatcherPool(0),
+		receipts:          NewPollingReceiptWatcher(backend, config.ReceiptPoll),
+		budget:            NewGasBudget(nil),
+		maintenance:       maintenance,
+		signer:            signer,
+		relayerAddress:    signer.address,
+		processedRequests: make(map[string]time.Time),
+		rateLimit:         newRateLimit(0, rateLimitFailOpen, 0),
+		inflight:          make(map[string]map[uint32]int),
+		reverts:           NewRevertTracker(0, time.Minute, time.Minute),
+		metrics:           NewMetrics(),
+		dailyCap:          NewDailyCap(0),
+		mintedCache:       NewMintedCache(0, nil),
+		fees:              NewFeeLedger(),
+		activity:          NewActivityCache(0),
+		txStore:           txStore,
+		mintHistory:       mintHistory,
+		gasAverage:        NewGasAverage(10, 1, 200000),
+		economics:         NewGasEconomics("", ""),
+		breaker:           NewCircuitBreaker(0, time.Minute, time.Minute),
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// BlocklistEntry describes an address that was automatically blocked
+type BlocklistEntry struct {
+	Address      string `json:"address"`
+	BlockedUntil int64  `json:"blockedUntil"`
+	Reverts      int    `json:"reverts"`
+}
+
+// RevertTracker counts on-chain reverts per address and temporarily blocks
+// addresses that exceed the configured threshold within the window
+type RevertTracker struct {
+	mu        sync.Mutex
+	reverts   map[string][]int64
+	blocked   map[string]time.Time
+	threshold int
+	window    time.Duration
+	blockFor  time.Duration
+}
+
+// NewRevertTracker creates a revert tracker. A threshold of 0 disables blocking.
+func NewRevertTracker(threshold int, window, blockFor time.Duration) *RevertTracker {
+	return &RevertTracker{
+		reverts:   make(map[string][]int64),
+		blocked:   make(map[string]time.Time),
+		threshold: threshold,
+		window:    window,
+		blockFor:  blockFor,
+	}
+}
+
+// Record registers a revert for address and reports whether it is now blocked
+func (t *RevertTracker) Record(address string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	cutoff := now.Add(-t.window).Unix()
+
+	var recent []int64
+	for _, ts := range t.reverts[address] {
+		if ts > cutoff {
+			recent = append(recent, ts)
+		}
+	}
+	recent = append(recent, now.Unix())
+	t.reverts[address] = recent
+
+	if t.threshold > 0 && len(recent) >= t.threshold {
+		t.blocked[address] = now.Add(t.blockFor)
+		return true
+	}
+	return false
+}
+
+// IsBlocked reports whether address is currently on the auto-blocklist
+func (t *RevertTracker) IsBlocked(address string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	until, ok := t.blocked[address]
+	return ok && time.Now().Before(until)
+}
+
+// Unblock removes address from the auto-blocklist and resets its revert count
+func (t *RevertTracker) Unblock(address string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, ok := t.blocked[address]
+	delete(t.blocked, address)
+	delete(t.reverts, address)
+	return ok
+}
+
+// List returns the current auto-blocklist sorted by address
+func (t *RevertTracker) List() []BlocklistEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	entries := make([]BlocklistEntry, 0, len(t.blocked))
+	for addr, until := range t.blocked {
+		if now.After(until) {
+			continue
+		}
+		entries = append(entries, BlocklistEntry{
+			Address:      addr,
+			BlockedUntil: until.Unix(),
+			Reverts:      len(t.reverts[addr]),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Address < entries[j].Address })
+	return entries
+}
+
+// Cleanup drops expired blocks and reverts outside the window
+func (t *RevertTracker) Cleanup(now time.Time) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for addr, until := range t.blocked {
+		if now.After(until) {
+			delete(t.blocked, addr)
+		}
+	}
+
+	cutoff := now.Add(-t.window).Unix()
+	for addr, times := range t.reverts {
+		var recent []int64
+		for _, ts := range times {
+			if ts > cutoff {
+				recent = append(recent, ts)
+			}
+		}
+		if len(recent) == 0 {
+			delete(t.reverts, addr)
+		} else {
+			t.reverts[addr] = recent
+		}
+	}
+
+	return len(t.blocked)
+}
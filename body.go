@@ -0,0 +1,90 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// errMalformedGzip is returned for a gzip body whose header can't be read
+var errMalformedGzip = errors.New("malformed gzip body")
+
+// decodeBody decodes a JSON request body into v, transparently inflating
+// Content-Encoding: gzip. MAX_BODY_BYTES is enforced on the decompressed
+// stream so a small compressed body can't expand without bound.
+func (s *Server) decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) error {
+	var body io.Reader = r.Body
+	compressed := false
+	if strings.EqualFold(strings.TrimSpace(r.Header.Get("Content-Encoding")), "gzip") {
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errMalformedGzip, err)
+		}
+		defer gz.Close()
+		body = gzipReader{gz}
+		compressed = true
+	}
+
+	limited := http.MaxBytesReader(w, io.NopCloser(body), s.config.MaxBodyBytes)
+	if err := json.NewDecoder(limited).Decode(v); err != nil {
+		return err
+	}
+	// The gzip checksum is only verified at the end of the stream, past
+	// where the decoder stops reading
+	if compressed {
+		if _, err := io.Copy(io.Discard, limited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// gzipReader tags decompression failures, including a truncated stream, so
+// they aren't reported as truncated or malformed JSON
+type gzipReader struct {
+	gz *gzip.Reader
+}
+
+func (g gzipReader) Read(p []byte) (int, error) {
+	n, err := g.gz.Read(p)
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("%w: %v", errMalformedGzip, err)
+	}
+	return n, err
+}
+
+// isJSONContentType reports whether the request declares a JSON body,
+// tolerating parameters such as charset
+func isJSONContentType(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
+
+// sendBodyError reports a decodeBody failure with a status matching its cause
+func (s *Server) sendBodyError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	// Checked first: a broken gzip stream also ends in an unexpected EOF
+	case errors.Is(err, errMalformedGzip):
+		s.sendErrorCode(w, http.StatusBadRequest, "MALFORMED_GZIP", "Malformed gzip request body", err.Error())
+	case errors.Is(err, io.EOF):
+		s.sendErrorCode(w, http.StatusBadRequest, "EMPTY_BODY", "Request body is empty", "")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		s.sendErrorCode(w, http.StatusBadRequest, "MALFORMED_JSON", "Request body is truncated JSON", err.Error())
+	case errors.As(err, &syntaxErr):
+		s.sendErrorCode(w, http.StatusBadRequest, "MALFORMED_JSON", fmt.Sprintf("Malformed JSON at byte offset %d", syntaxErr.Offset), err.Error())
+	case errors.As(err, &typeErr):
+		s.sendErrorCode(w, http.StatusBadRequest, "INVALID_FIELD_TYPE", fmt.Sprintf("Field %q must be of type %s, got %s", typeErr.Field, typeErr.Type, typeErr.Value), err.Error())
+	case errors.As(err, &tooLarge):
+		s.sendErrorCode(w, http.StatusRequestEntityTooLarge, "BODY_TOO_LARGE", fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit), "")
+	default:
+		s.sendError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// gzipped compresses body
+func gzipped(body string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(body))
+	gz.Close()
+	return buf.Bytes()
+}
+
+func TestSendBodyError(t *testing.T) {
+	valid := gzipped(`{"callData":"0x01"}`)
+	badChecksum := append([]byte(nil), valid...)
+	badChecksum[len(badChecksum)-8] ^= 0xff
+
+	tests := []struct {
+		name   string
+		body   []byte
+		gzip   bool
+		status int
+		code   string
+	}{
+		{"empty body", nil, false, http.StatusBadRequest, "EMPTY_BODY"},
+		{"truncated JSON", []byte(`{"callData":`), false, http.StatusBadRequest, "MALFORMED_JSON"},
+		{"syntax error", []byte(`{"callData" "0x01"}`), false, http.StatusBadRequest, "MALFORMED_JSON"},
+		{"wrong field type", []byte(`{"callData":1}`), false, http.StatusBadRequest, "INVALID_FIELD_TYPE"},
+		{"too large", []byte(`{"callData":"0x` + string(bytes.Repeat([]byte("00"), 64)) + `"}`), false, http.StatusRequestEntityTooLarge, "BODY_TOO_LARGE"},
+		{"gzip too large once inflated", gzipped(`{"callData":"0x` + string(bytes.Repeat([]byte("00"), 64)) + `"}`), true, http.StatusRequestEntityTooLarge, "BODY_TOO_LARGE"},
+		{"empty gzip body", nil, true, http.StatusBadRequest, "MALFORMED_GZIP"},
+		{"bad gzip header", []byte("not gzip at all"), true, http.StatusBadRequest, "MALFORMED_GZIP"},
+		{"truncated gzip stream", valid[:len(valid)-12], true, http.StatusBadRequest, "MALFORMED_GZIP"},
+		{"gzip checksum mismatch", badChecksum, true, http.StatusBadRequest, "MALFORMED_GZIP"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{config: Config{MaxBodyBytes: 64}}
+			r := httptest.NewRequest("POST", "/relay", bytes.NewReader(tt.body))
+			if tt.gzip {
+				r.Header.Set("Content-Encoding", "gzip")
+			}
+			w := httptest.NewRecorder()
+
+			var req RelayRequest
+			err := s.decodeBody(w, r, &req)
+			if err == nil {
+				t.Fatal("decodeBody accepted the body")
+			}
+			s.sendBodyError(w, err)
+
+			var resp RelayResponse
+			json.Unmarshal(w.Body.Bytes(), &resp)
+			if w.Code != tt.status || resp.Code != tt.code {
+				t.Fatalf("got %d %q, want %d %q (%v)", w.Code, resp.Code, tt.status, tt.code, err)
+			}
+		})
+	}
+}
+
+func TestDecodeBodyInflatesGzip(t *testing.T) {
+	s := &Server{config: Config{MaxBodyBytes: 64}}
+	r := httptest.NewRequest("POST", "/relay", bytes.NewReader(gzipped(`{"callData":"0x01"}`)))
+	r.Header.Set("Content-Encoding", "gzip")
+
+	var req RelayRequest
+	if err := s.decodeBody(httptest.NewRecorder(), r, &req); err != nil {
+		t.Fatalf("decodeBody: %v", err)
+	}
+	if req.CallData != "0x01" {
+		t.Fatalf("callData = %q, want 0x01", req.CallData)
+	}
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Circuit breaker states
+const (
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half-open"
+)
+
+// CircuitBreaker pauses relaying after consecutive transaction failures.
+// Once open it rejects relays until the cooldown elapses, then lets a single
+// probe through; a successful probe closes it, a failed one re-opens it.
+type CircuitBreaker struct {
+	mu          sync.Mutex
+	threshold   int
+	window      time.Duration
+	cooldown    time.Duration
+	failures    []time.Time
+	state       string
+	openedAt    time.Time
+	probeSentAt time.Time
+}
+
+// NewCircuitBreaker creates a breaker. A threshold of 0 disables it.
+func NewCircuitBreaker(threshold int, window, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		window:    window,
+		cooldown:  cooldown,
+		state:     breakerClosed,
+	}
+}
+
+// Allow reports whether a relay may proceed
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	switch b.state {
+	case breakerOpen:
+		if now.Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		b.probeSentAt = now
+		return true
+	case breakerHalfOpen:
+		// Only one probe at a time; re-issue if a probe never reported back
+		if now.Sub(b.probeSentAt) < b.cooldown {
+			return false
+		}
+		b.probeSentAt = now
+		return true
+	default:
+		return true
+	}
+}
+
+// RecordSuccess closes the breaker and clears the failure streak
+func (b *CircuitBreaker) RecordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = breakerClosed
+	b.failures = nil
+}
+
+// RecordFailure extends the failure streak and reports whether the breaker
+// tripped open as a result
+func (b *CircuitBreaker) RecordFailure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.threshold <= 0 {
+		return false
+	}
+
+	now := time.Now()
+	if b.state == breakerHalfOpen {
+		b.state = breakerOpen
+		b.openedAt = now
+		return true
+	}
+
+	cutoff := now.Add(-b.window)
+	recent := b.failures[:0]
+	for _, t := range b.failures {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	b.failures = append(recent, now)
+
+	if b.state == breakerClosed && len(b.failures) >= b.threshold {
+		b.state = breakerOpen
+		b.openedAt = now
+		return true
+	}
+	return false
+}
+
+// State returns the current breaker state
+func (b *CircuitBreaker) State() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
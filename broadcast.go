@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// broadcastBackend sends each signed transaction to the primary endpoint and
+// every BROADCAST_RPCS endpoint at once, and polls all of them for receipts.
+// Re-sending a signed transaction is safe: every endpoint gets the same
+// bytes and so the same hash, and at most one can be included for its nonce.
+// All other calls go to the primary endpoint only.
+type broadcastBackend struct {
+	EthBackend
+	extra []EthBackend
+}
+
+// newBroadcastBackend connects to each url and checks it serves chainID
+func newBroadcastBackend(primary EthBackend, urls []string, chainID *big.Int, metrics *Metrics) (*broadcastBackend, error) {
+	b := &broadcastBackend{EthBackend: primary}
+	for _, url := range urls {
+		client, err := ethclient.Dial(url)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to broadcast RPC %s: %v", url, err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		id, err := client.ChainID(ctx)
+		cancel()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get chain ID from broadcast RPC %s: %v", url, err)
+		}
+		if id.Cmp(chainID) != 0 {
+			return nil, fmt.Errorf("broadcast RPC %s is on chain %s, expected %s", url, id.String(), chainID.String())
+		}
+		b.extra = append(b.extra, newInstrumentedBackend(client, metrics))
+	}
+	log.Printf("📡 Broadcasting transactions to %d additional RPC endpoint(s)\n", len(b.extra))
+	return b, nil
+}
+
+// endpoints returns the primary endpoint followed by the extra ones
+func (b *broadcastBackend) endpoints() []EthBackend {
+	return append([]EthBackend{b.EthBackend}, b.extra...)
+}
+
+// SendTransaction sends tx to every endpoint at once and succeeds if any
+// accepts it, counting "already known" as accepted. The primary's verdict
+// is awaited first: a nonce too low or underpriced rejection from it is
+// returned even if another endpoint took tx, so that nonce and gas price
+// handling behave as with a single endpoint. If every endpoint rejects tx
+// the primary's error is returned.
+func (b *broadcastBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	errs := make(chan error, len(b.extra))
+	for _, endpoint := range b.extra {
+		go func() {
+			errs <- endpoint.SendTransaction(ctx, tx)
+		}()
+	}
+
+	primary := b.EthBackend.SendTransaction(ctx, tx)
+	if primary == nil || isAlreadyKnown(primary) {
+		return nil
+	}
+	if isNonceRejection(primary) {
+		return primary
+	}
+	for range b.extra {
+		if err := <-errs; err == nil || isAlreadyKnown(err) {
+			return nil
+		}
+	}
+	return primary
+}
+
+// isNonceRejection reports whether a send error means the nonce is already
+// used or its pool slot needs a higher gas price
+func isNonceRejection(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "nonce too low") || strings.Contains(msg, "underpriced")
+}
+
+// receiptClients returns the RPC clients to poll for receipts: every
+// endpoint of a broadcast backend, primary first, otherwise just backend's
+func receiptClients(backend EthBackend) []*rpc.Client {
+	b, ok := backend.(*broadcastBackend)
+	if !ok {
+		return []*rpc.Client{backend.Client()}
+	}
+	var clients []*rpc.Client
+	for _, endpoint := range b.endpoints() {
+		clients = append(clients, endpoint.Client())
+	}
+	return clients
+}
+
+// TransactionReceipt returns the first receipt any endpoint has. If none has
+// one the primary's error is returned, normally ethereum.NotFound.
+func (b *broadcastBackend) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	type result struct {
+		receipt *types.Receipt
+		err     error
+	}
+
+	endpoints := b.endpoints()
+	results := make(chan result, len(endpoints))
+	primary := make(chan error, 1)
+	for i, endpoint := range endpoints {
+		go func(i int, endpoint EthBackend) {
+			receipt, err := endpoint.TransactionReceipt(ctx, txHash)
+			if i == 0 {
+				primary <- err
+			}
+			results <- result{receipt, err}
+		}(i, endpoint)
+	}
+
+	for range endpoints {
+		if res := <-results; res.err == nil {
+			return res.receipt, nil
+		}
+	}
+	return nil, <-primary
+}
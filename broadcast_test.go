@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestBroadcastSendTransaction(t *testing.T) {
+	tests := []struct {
+		name    string
+		primary error
+		extra   error
+		want    string
+	}{
+		{"primary accepts", nil, errors.New("connection refused"), ""},
+		{"primary already knows it", errors.New("already known"), errors.New("connection refused"), ""},
+		{"extra accepts after a primary outage", errors.New("connection refused"), nil, ""},
+		{"primary nonce too low", errors.New("nonce too low"), nil, "nonce too low"},
+		{"primary replacement underpriced", errors.New("replacement transaction underpriced"), nil, "replacement transaction underpriced"},
+		{"primary transaction underpriced", errors.New("transaction underpriced"), nil, "transaction underpriced"},
+		{"every endpoint rejects", errors.New("insufficient funds"), errors.New("connection refused"), "insufficient funds"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary, extra := newFakeBackend(), newFakeBackend()
+			if tt.primary != nil {
+				primary.sendErrs = []error{tt.primary}
+			}
+			if tt.extra != nil {
+				extra.sendErrs = []error{tt.extra}
+			}
+			b := &broadcastBackend{EthBackend: primary, extra: []EthBackend{extra}}
+
+			err := b.SendTransaction(context.Background(), types.NewTx(&types.LegacyTx{Nonce: 3}))
+			if tt.want == "" && err != nil {
+				t.Fatalf("err = %v, want accepted", err)
+			}
+			if tt.want != "" && (err == nil || err.Error() != tt.want) {
+				t.Fatalf("err = %v, want the primary's %q", err, tt.want)
+			}
+		})
+	}
+}
+
+// clientBackend is a fakeBackend reporting an RPC client
+type clientBackend struct {
+	*fakeBackend
+	client *rpc.Client
+}
+
+func (b *clientBackend) Client() *rpc.Client { return b.client }
+
+func TestBatchedReceiptsPollEveryBroadcastEndpoint(t *testing.T) {
+	mined := common.HexToHash("0x01")
+	// Only the extra endpoint has seen the transaction mined; the primary
+	// is down
+	primary := &clientBackend{newFakeBackend(), newRPCServer(t, nil)}
+	extra := &clientBackend{newFakeBackend(), newRPCServer(t, func(method string, params []json.RawMessage) interface{} {
+		var hash common.Hash
+		json.Unmarshal(params[0], &hash)
+		if hash != mined {
+			return nil
+		}
+		return &types.Receipt{TxHash: hash, Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{}}
+	})}
+	backend := &broadcastBackend{EthBackend: primary, extra: []EthBackend{extra}}
+
+	clients := receiptClients(backend)
+	if len(clients) != 2 {
+		t.Fatalf("polling %d client(s), want both endpoints", len(clients))
+	}
+	rw := NewReceiptWatcher(clients, 10*time.Millisecond, NewMetrics())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	receipt, err := rw.Wait(ctx, mined)
+	if err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if receipt.TxHash != mined {
+		t.Fatalf("receipt for %s, want %s", receipt.TxHash.Hex(), mined.Hex())
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// gasBudgetWindow is the rolling window DAILY_GAS_BUDGET_WEI applies to
+const gasBudgetWindow = 24 * time.Hour
+
+// gasSpend is the cost of one mined relay transaction
+type gasSpend struct {
+	at  time.Time
+	wei *big.Int
+}
+
+// GasBudget caps how much gas each relayer key spends per rolling day
+type GasBudget struct {
+	mu     sync.Mutex
+	budget *big.Int
+	spends map[common.Address][]gasSpend
+}
+
+// NewGasBudget creates a budget of budget wei per key. A nil budget
+// disables it.
+func NewGasBudget(budget *big.Int) *GasBudget {
+	return &GasBudget{budget: budget, spends: make(map[common.Address][]gasSpend)}
+}
+
+// Record adds the cost of a mined transaction sent by key
+func (b *GasBudget) Record(key common.Address, wei *big.Int) {
+	if b.budget == nil || wei == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.spends[key] = append(b.spends[key], gasSpend{at: time.Now(), wei: new(big.Int).Set(wei)})
+}
+
+// spent prunes spends older than the window and returns the rest's total.
+// Callers hold b.mu.
+func (b *GasBudget) spent(key common.Address, now time.Time) *big.Int {
+	cutoff := now.Add(-gasBudgetWindow)
+	spends := b.spends[key]
+	for len(spends) > 0 && !spends[0].at.After(cutoff) {
+		spends = spends[1:]
+	}
+	if len(spends) == 0 {
+		delete(b.spends, key)
+	} else {
+		b.spends[key] = spends
+	}
+
+	total := new(big.Int)
+	for _, spend := range spends {
+		total.Add(total, spend.wei)
+	}
+	return total
+}
+
+// Allow reports whether key still has budget left. When it doesn't, the
+// second value is the number of seconds until enough spend ages out of the
+// window to go back under budget.
+func (b *GasBudget) Allow(key common.Address) (bool, int) {
+	if b.budget == nil {
+		return true, 0
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	total := b.spent(key, now)
+	if total.Cmp(b.budget) < 0 {
+		return true, 0
+	}
+
+	for _, spend := range b.spends[key] {
+		total.Sub(total, spend.wei)
+		if total.Cmp(b.budget) < 0 {
+			return false, int(spend.at.Add(gasBudgetWindow).Sub(now).Seconds()) + 1
+		}
+	}
+	return false, int(gasBudgetWindow.Seconds())
+}
+
+// GasBudgetState reports a key's usage of its daily gas budget
+type GasBudgetState struct {
+	BudgetWei    string `json:"budgetWei"`
+	SpentWei     string `json:"spentWei"`
+	RemainingWei string `json:"remainingWei"`
+}
+
+// State returns key's usage, or nil when no budget is configured
+func (b *GasBudget) State(key common.Address) *GasBudgetState {
+	if b.budget == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	spent := b.spent(key, time.Now())
+	remaining := new(big.Int).Sub(b.budget, spent)
+	if remaining.Sign() < 0 {
+		remaining.SetInt64(0)
+	}
+	return &GasBudgetState{
+		BudgetWei:    b.budget.String(),
+		SpentWei:     spent.String(),
+		RemainingWei: remaining.String(),
+	}
+}
+
+// receiptCost is what a mined transaction cost its sender, reverted or not
+func receiptCost(receipt *types.Receipt, fallbackPrice *big.Int) *big.Int {
+	price := receipt.EffectiveGasPrice
+	if price == nil {
+		price = fallbackPrice
+	}
+	if price == nil {
+		return nil
+	}
+	return new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), price)
+}
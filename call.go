@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// CallRequest is a read-only contract call proxied through /call
+type CallRequest struct {
+	To   common.Address `json:"to"`
+	Data string         `json:"data"`
+}
+
+// CallResponse carries the ABI-decoded result of a /call
+type CallResponse struct {
+	Success bool          `json:"success"`
+	Method  string        `json:"method"`
+	Result  []interface{} `json:"result"`
+	Raw     string        `json:"raw"`
+}
+
+// callAllowlist maps target contract -> selector -> method for /call
+type callAllowlist map[common.Address]map[string]abi.Method
+
+// buildCallAllowlist allows the configured method names on the NFT contract
+// and every configured hub, using each contract's ABI
+func buildCallAllowlist(config Config) (callAllowlist, error) {
+	nftParsed, err := abi.JSON(strings.NewReader(nftABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse NFT ABI: %v", err)
+	}
+
+	contracts := map[common.Address]abi.ABI{config.NFTContract: nftParsed}
+	for _, hub := range config.Hubs {
+		contracts[hub.Address] = hub.ABI
+	}
+
+	allowlist := make(callAllowlist)
+	for addr, contractABI := range contracts {
+		selectors := make(map[string]abi.Method)
+		for _, name := range config.CallAllowedMethods {
+			method, ok := contractABI.Methods[name]
+			if !ok || !method.IsConstant() {
+				continue
+			}
+			selectors[hex.EncodeToString(method.ID)] = method
+		}
+		if len(selectors) > 0 {
+			allowlist[addr] = selectors
+		}
+	}
+	return allowlist, nil
+}
+
+// callHandler proxies allowlisted view calls so frontends can read contract
+// state without their own RPC, without exposing arbitrary eth_call access
+func (s *Server) callHandler(w http.ResponseWriter, r *http.Request) {
+	var req CallRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.sendError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+
+	selectors, ok := s.callAllowlist[req.To]
+	if !ok {
+		s.sendError(w, http.StatusForbidden, "Target contract is not allowed", req.To.Hex())
+		return
+	}
+
+	data, err := decodeHex(req.Data)
+	if err != nil || len(data) < 4 {
+		s.sendError(w, http.StatusBadRequest, "Invalid call data", "")
+		return
+	}
+
+	method, ok := selectors[hex.EncodeToString(data[:4])]
+	if !ok {
+		s.sendError(w, http.StatusForbidden, "Method is not allowed", "0x"+hex.EncodeToString(data[:4]))
+		return
+	}
+
+	log.Printf("📖 /call %s.%s\n", req.To.Hex(), method.Name)
+
+	result, err := s.client.CallContract(context.Background(), ethereum.CallMsg{
+		To:   &req.To,
+		Data: data,
+	}, nil)
+	if err != nil {
+		s.sendError(w, http.StatusBadGateway, "Contract call failed", err.Error())
+		return
+	}
+
+	values, err := method.Outputs.Unpack(result)
+	if err != nil {
+		s.sendError(w, http.StatusBadGateway, "Failed to decode result", err.Error())
+		return
+	}
+
+	// Emit addresses checksummed rather than in the lowercase JSON default
+	for i, v := range values {
+		if addr, ok := v.(common.Address); ok {
+			values[i] = addr.Hex()
+		}
+	}
+
+	response := CallResponse{
+		Success: true,
+		Method:  method.Sig,
+		Result:  values,
+		Raw:     "0x" + hex.EncodeToString(result),
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DecodedCall is relayed calldata decoded against the target contract's ABI,
+// as returned with ?decode_calldata=true
+type DecodedCall struct {
+	Function string                 `json:"function"`
+	Args     map[string]interface{} `json:"args"`
+}
+
+// decodeCallData decodes the arguments of a relay's calldata by name. Only
+// calls to the NFT contract whose method is in DECODE_CALLDATA_METHODS are
+// decoded; anything else returns nil.
+func (s *Server) decodeCallData(req RelayRequest) (*DecodedCall, error) {
+	if req.Forward.To != s.config.NFTContract {
+		return nil, nil
+	}
+	callData, err := decodeHex(req.CallData)
+	if err != nil || len(callData) < 4 {
+		return nil, nil
+	}
+
+	parsedABI, err := abi.JSON(strings.NewReader(nftABI))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range s.config.DecodeCallDataMethods {
+		method, ok := parsedABI.Methods[name]
+		if !ok || !bytes.Equal(callData[:4], method.ID) {
+			continue
+		}
+
+		args := make(map[string]interface{})
+		if err := method.Inputs.UnpackIntoMap(args, callData[4:]); err != nil {
+			return nil, fmt.Errorf("failed to decode %s arguments: %v", name, err)
+		}
+		// Emit addresses checksummed and bytes as hex rather than the JSON
+		// defaults of lowercase and base64
+		for key, value := range args {
+			switch v := value.(type) {
+			case common.Address:
+				args[key] = v.Hex()
+			case []byte:
+				args[key] = "0x" + hex.EncodeToString(v)
+			}
+		}
+		return &DecodedCall{Function: method.Sig, Args: args}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// checkChainID compares the RPC endpoint's chain ID with CHAIN_ID and
+// records any mismatch, so relays stop instead of signing for the wrong chain
+func (s *Server) checkChainID(ctx context.Context) error {
+	chainID, err := s.client.ChainID(ctx)
+	if err != nil {
+		return err
+	}
+
+	if chainID.Cmp(s.config.ChainID) != 0 {
+		if s.chainMismatch.Swap(chainID) == nil {
+			log.Printf("🚨🚨🚨 CHAIN ID MISMATCH: RPC reports chain %s but CHAIN_ID is %s. Relaying stopped.\n", chainID.String(), s.config.ChainID.String())
+		}
+		s.metrics.Set("relayer_chain_id_mismatch", 1)
+		return fmt.Errorf("RPC reports chain %s, configured %s", chainID.String(), s.config.ChainID.String())
+	}
+
+	if s.chainMismatch.Swap(nil) != nil {
+		log.Printf("✅ RPC chain ID matches CHAIN_ID %s again, relaying resumed\n", s.config.ChainID.String())
+	}
+	s.metrics.Set("relayer_chain_id_mismatch", 0)
+	return nil
+}
+
+// chainIDRoutine periodically re-checks the RPC endpoint's chain ID
+func (s *Server) chainIDRoutine() {
+	ticker := time.NewTicker(s.config.ChainIDCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := s.checkChainID(ctx); err != nil && s.chainMismatch.Load() == nil {
+			log.Printf("⚠️  Failed to check chain ID: %v\n", err)
+		}
+		cancel()
+	}
+}
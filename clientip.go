@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// clientIP returns the requesting client's IP address.
+//
+// Forwarding headers are only honored when TRUST_PROXY is set, since any
+// client can send them. X-Forwarded-For is read from the right: each trusted
+// proxy appends the address it received the request from, so with
+// TRUSTED_PROXY_HOPS proxies in front of the relayer the client is that many
+// entries from the end. Anything further left is client-supplied.
+func (s *Server) clientIP(r *http.Request) string {
+	if s.config.TrustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			hops := strings.Split(forwarded, ",")
+			idx := len(hops) - s.config.TrustedProxyHops
+			if idx < 0 {
+				idx = 0
+			}
+			if ip := parseIP(hops[idx]); ip != "" {
+				return ip
+			}
+		}
+		if ip := parseIP(r.Header.Get("X-Real-IP")); ip != "" {
+			return ip
+		}
+	}
+	return parseIP(r.RemoteAddr)
+}
+
+// parseIP normalizes an address that may carry a port, IPv6 brackets or
+// surrounding whitespace, returning "" if it isn't an IP
+func parseIP(addr string) string {
+	addr = strings.TrimSpace(addr)
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	addr = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
+
+// parseCIDRs parses IP_BLOCKLIST entries. A bare IP is treated as a single
+// host range.
+func parseCIDRs(entries []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP_BLOCKLIST entry %q", entry)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP_BLOCKLIST entry %q: %v", entry, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// cidrBlocklist returns a blocklist function matching any of nets, or nil
+// when there are none
+func cidrBlocklist(nets []*net.IPNet) func(ip net.IP) bool {
+	if len(nets) == 0 {
+		return nil
+	}
+	return func(ip net.IP) bool {
+		for _, ipNet := range nets {
+			if ipNet.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ipBlocked reports whether the client IP is rejected by the blocklist hook.
+// Unparseable addresses are let through.
+func (s *Server) ipBlocked(clientIP string) bool {
+	if s.blocklist == nil {
+		return false
+	}
+	ip := net.ParseIP(clientIP)
+	return ip != nil && s.blocklist(ip)
+}
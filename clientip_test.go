@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name       string
+		trustProxy bool
+		hops       int
+		remoteAddr string
+		headers    map[string]string
+		want       string
+	}{
+		{"IPv4", false, 1, "203.0.113.7:51234", nil, "203.0.113.7"},
+		{"IPv6", false, 1, "[2001:db8::1]:51234", nil, "2001:db8::1"},
+		{"IPv6 loopback", false, 1, "[::1]:8080", nil, "::1"},
+		{"IPv4-mapped IPv6", false, 1, "[::ffff:203.0.113.7]:51234", nil, "203.0.113.7"},
+		{
+			"forwarding headers ignored without TRUST_PROXY", false, 1, "203.0.113.7:51234",
+			map[string]string{"X-Forwarded-For": "198.51.100.1", "X-Real-IP": "198.51.100.2"},
+			"203.0.113.7",
+		},
+		{
+			"trusted proxy", true, 1, "10.0.0.2:443",
+			map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			"198.51.100.1",
+		},
+		{
+			"spoofed X-Forwarded-For entry is skipped", true, 1, "10.0.0.2:443",
+			map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.1"},
+			"198.51.100.1",
+		},
+		{
+			"two trusted hops", true, 2, "10.0.0.3:443",
+			map[string]string{"X-Forwarded-For": "1.2.3.4, 198.51.100.1, 10.0.0.2"},
+			"198.51.100.1",
+		},
+		{
+			"more hops than entries", true, 3, "10.0.0.2:443",
+			map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			"198.51.100.1",
+		},
+		{
+			"IPv6 in X-Forwarded-For", true, 1, "10.0.0.2:443",
+			map[string]string{"X-Forwarded-For": "[2001:db8::5]:1234"},
+			"2001:db8::5",
+		},
+		{
+			"X-Real-IP fallback", true, 1, "10.0.0.2:443",
+			map[string]string{"X-Real-IP": "198.51.100.2"},
+			"198.51.100.2",
+		},
+		{
+			"garbage header falls back to X-Real-IP", true, 1, "10.0.0.2:443",
+			map[string]string{"X-Forwarded-For": "not-an-ip", "X-Real-IP": "198.51.100.2"},
+			"198.51.100.2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{config: Config{TrustProxy: tt.trustProxy, TrustedProxyHops: tt.hops}}
+			r := httptest.NewRequest("POST", "/relay", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			if got := s.clientIP(r); got != tt.want {
+				t.Fatalf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
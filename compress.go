@@ -0,0 +1,99 @@
+package main
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+)
+
+// compressWriter buffers a response until it reaches the size threshold and
+// only then switches to gzip, so small responses are sent uncompressed
+type compressWriter struct {
+	http.ResponseWriter
+	minSize int
+	status  int
+	buf     []byte
+	gz      *gzip.Writer
+}
+
+// WriteHeader defers the status until we know whether to compress
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.status == 0 {
+		cw.status = status
+	}
+}
+
+// Write buffers until the threshold, then streams through gzip
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.gz != nil {
+		return cw.gz.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= cw.minSize && cw.Header().Get("Content-Encoding") == "" {
+		cw.startGzip()
+	}
+	return len(p), nil
+}
+
+// startGzip sets the compression headers and flushes the buffer into gzip
+func (cw *compressWriter) startGzip() {
+	h := cw.Header()
+	if h.Get("Content-Type") == "" {
+		h.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+	h.Set("Content-Encoding", "gzip")
+	h.Del("Content-Length")
+
+	cw.ResponseWriter.WriteHeader(cw.statusOrOK())
+	cw.gz = gzip.NewWriter(cw.ResponseWriter)
+	cw.gz.Write(cw.buf)
+	cw.buf = nil
+}
+
+// finish completes the response, sending any buffered body uncompressed
+func (cw *compressWriter) finish() {
+	if cw.gz != nil {
+		cw.gz.Close()
+		return
+	}
+	cw.ResponseWriter.WriteHeader(cw.statusOrOK())
+	cw.ResponseWriter.Write(cw.buf)
+}
+
+func (cw *compressWriter) statusOrOK() int {
+	if cw.status == 0 {
+		return http.StatusOK
+	}
+	return cw.status
+}
+
+// compressMiddleware gzips responses of at least COMPRESS_MIN_BYTES for
+// clients that accept it. /health is left alone so probes stay cheap, and
+// event streams are left alone so each event reaches the client at once.
+func (s *Server) compressMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" || !acceptsGzip(r) || wantsEventStream(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		cw := &compressWriter{ResponseWriter: w, minSize: s.config.CompressMinBytes}
+		defer cw.finish()
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// acceptsGzip reports whether the client listed gzip in Accept-Encoding
+// without disabling it via q=0
+func acceptsGzip(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if strings.EqualFold(strings.TrimSpace(coding), "gzip") {
+			q := strings.ReplaceAll(params, " ", "")
+			return q != "q=0" && q != "q=0.0" && q != "q=0.00" && q != "q=0.000"
+		}
+	}
+	return false
+}
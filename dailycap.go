@@ -0,0 +1,102 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// dailyCapWindow is the sliding window DAILY_CAP_PER_ADDRESS applies to
+const dailyCapWindow = 24 * time.Hour
+
+// DailyCap limits how many successful relays an address gets per day,
+// independent of the on-chain minted flag
+type DailyCap struct {
+	mu     sync.Mutex
+	relays map[string][]int64
+	limit  int
+}
+
+// NewDailyCap creates a cap of limit relays per address. A limit of 0
+// disables it.
+func NewDailyCap(limit int) *DailyCap {
+	return &DailyCap{
+		relays: make(map[string][]int64),
+		limit:  limit,
+	}
+}
+
+// Allow reports whether address is under its cap. When it isn't, the
+// second value is the number of seconds until its oldest relay ages out.
+func (d *DailyCap) Allow(address string) (bool, int) {
+	if d.limit <= 0 {
+		return true, 0
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now().Unix()
+	cutoff := now - int64(dailyCapWindow.Seconds())
+
+	var recent []int64
+	for _, ts := range d.relays[address] {
+		if ts > cutoff {
+			recent = append(recent, ts)
+		}
+	}
+	d.relays[address] = recent
+
+	if len(recent) >= d.limit {
+		return false, int(recent[0] - cutoff)
+	}
+	return true, 0
+}
+
+// Used returns the number of relays address has had in the window
+func (d *DailyCap) Used(address string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	cutoff := time.Now().Unix() - int64(dailyCapWindow.Seconds())
+	used := 0
+	for _, ts := range d.relays[address] {
+		if ts > cutoff {
+			used++
+		}
+	}
+	return used
+}
+
+// Record counts a successful relay for address
+func (d *DailyCap) Record(address string) {
+	if d.limit <= 0 {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.relays[address] = append(d.relays[address], time.Now().Unix())
+}
+
+// Cleanup drops relays older than the window and returns the number of
+// addresses still tracked
+func (d *DailyCap) Cleanup(now time.Time) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	cutoff := now.Add(-dailyCapWindow).Unix()
+	for addr, times := range d.relays {
+		var recent []int64
+		for _, ts := range times {
+			if ts > cutoff {
+				recent = append(recent, ts)
+			}
+		}
+		if len(recent) == 0 {
+			delete(d.relays, addr)
+		} else {
+			d.relays[addr] = recent
+		}
+	}
+	return len(d.relays)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// isDeploy reports whether a forward targets contract deployment: either
+// the zero address or the configured DEPLOY_FACTORY
+func (s *Server) isDeploy(to common.Address) bool {
+	if to == (common.Address{}) {
+		return true
+	}
+	return s.config.DeployFactory != (common.Address{}) && to == s.config.DeployFactory
+}
+
+// checkDeploy validates deployment bytecode, returning an error code and
+// message when the deployment must be rejected
+func (s *Server) checkDeploy(bytecode []byte) (code, message string) {
+	switch {
+	case !s.config.AllowDeploy:
+		return "DEPLOY_NOT_ENABLED", "Contract deployments are not accepted by this relayer"
+	case len(bytecode) == 0:
+		return "EMPTY_BYTECODE", "Deployment bytecode is empty"
+	case len(bytecode) > s.config.DeployMaxBytes:
+		return "DEPLOY_TOO_LARGE", fmt.Sprintf("Deployment bytecode exceeds %d bytes", s.config.DeployMaxBytes)
+	}
+	return "", ""
+}
+
+// deployedAddress extracts the new contract address from the hub's
+// ContractDeployed event
+func deployedAddress(hub HubConfig, logs []*types.Log) (common.Address, error) {
+	event, ok := hub.ABI.Events["ContractDeployed"]
+	if !ok {
+		return common.Address{}, fmt.Errorf("hub %s ABI has no ContractDeployed event", hub.Version)
+	}
+
+	for _, l := range logs {
+		if l.Address != hub.Address || len(l.Topics) == 0 || l.Topics[0] != event.ID {
+			continue
+		}
+		values, err := event.Inputs.NonIndexed().Unpack(l.Data)
+		if err != nil {
+			return common.Address{}, fmt.Errorf("failed to decode ContractDeployed: %v", err)
+		}
+		return values[0].(common.Address), nil
+	}
+	return common.Address{}, fmt.Errorf("ContractDeployed event not found in receipt")
+}
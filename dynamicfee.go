@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+)
+
+// dynamicFeeCap computes GasFeeCap = baseFee * multiplier + tip, clamped to
+// ceiling, with the tip never exceeding the fee cap
+func dynamicFeeCap(baseFee *big.Int, multiplier float64, tip, ceiling *big.Int) (*big.Int, *big.Int) {
+	scaled, _ := new(big.Float).Mul(new(big.Float).SetInt(baseFee), big.NewFloat(multiplier)).Int(nil)
+	feeCap := new(big.Int).Add(scaled, tip)
+	feeCap = clampGasPrice(feeCap, nil, ceiling)
+	return feeCap, capTip(tip, feeCap)
+}
+
+// capTip returns tip lowered to feeCap if it exceeds it
+func capTip(tip, feeCap *big.Int) *big.Int {
+	return new(big.Int).Set(minBigInt(tip, feeCap))
+}
+
+// dynamicFees prices a submission from the latest base fee. It returns nil
+// fees when the chain has no base fee, in which case legacy pricing is used.
+// A resubmission outbids the previous broadcast's fee cap and tip by 10%.
+func (s *Server) dynamicFees(sub *submission) (feeCap, tip *big.Int, err error) {
+	header, err := s.client.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get base fee: %v", err)
+	}
+	if header.BaseFee == nil {
+		log.Println("⚠️  Chain reports no base fee, using legacy gas pricing")
+		return nil, nil, nil
+	}
+
+	ceiling := sub.gasCeiling(s.config)
+	feeCap, tip = dynamicFeeCap(header.BaseFee, s.config.BaseFeeMultiplier, s.config.PriorityFee, ceiling)
+	if sub.gasPrice != nil && sub.tip != nil {
+		if bumped := bumpGasPrice(sub.gasPrice); feeCap.Cmp(bumped) < 0 {
+			feeCap = clampGasPrice(bumped, nil, ceiling)
+		}
+		if bumped := bumpGasPrice(sub.tip); tip.Cmp(bumped) < 0 {
+			tip = capTip(bumped, feeCap)
+		}
+	}
+
+	log.Printf("   Base fee: %s gwei, fee cap: %s gwei, tip: %s gwei\n",
+		new(big.Int).Div(header.BaseFee, big.NewInt(1e9)).String(),
+		new(big.Int).Div(feeCap, big.NewInt(1e9)).String(),
+		new(big.Int).Div(tip, big.NewInt(1e9)).String())
+	return feeCap, tip, nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestDynamicFeeCap(t *testing.T) {
+	gwei := func(n float64) *big.Int {
+		v, _ := new(big.Float).Mul(big.NewFloat(n), big.NewFloat(1e9)).Int(nil)
+		return v
+	}
+
+	tests := []struct {
+		name       string
+		baseFee    *big.Int
+		multiplier float64
+		tip        *big.Int
+		ceiling    *big.Int
+		wantCap    *big.Int
+		wantTip    *big.Int
+	}{
+		{"multiplier 1", gwei(10), 1, gwei(2), gwei(500), gwei(12), gwei(2)},
+		{"multiplier 2", gwei(10), 2, gwei(2), gwei(500), gwei(22), gwei(2)},
+		{"fractional multiplier", gwei(10), 1.125, gwei(1.5), gwei(500), gwei(12.75), gwei(1.5)},
+		{"odd wei base fee", big.NewInt(7), 1.5, big.NewInt(1), gwei(500), big.NewInt(11), big.NewInt(1)},
+		{"zero tip", gwei(10), 2, big.NewInt(0), gwei(500), gwei(20), big.NewInt(0)},
+		{"clamped to ceiling", gwei(300), 2, gwei(2), gwei(500), gwei(500), gwei(2)},
+		{"tip above clamped cap", gwei(10), 2, gwei(600), gwei(500), gwei(500), gwei(500)},
+		{"no ceiling", gwei(300), 2, gwei(2), nil, gwei(602), gwei(2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feeCap, tip := dynamicFeeCap(tt.baseFee, tt.multiplier, tt.tip, tt.ceiling)
+			if feeCap.Cmp(tt.wantCap) != 0 || tip.Cmp(tt.wantTip) != 0 {
+				t.Fatalf("got cap %s tip %s, want cap %s tip %s", feeCap, tip, tt.wantCap, tt.wantTip)
+			}
+			if tip.Cmp(feeCap) > 0 {
+				t.Fatalf("tip %s exceeds fee cap %s", tip, feeCap)
+			}
+		})
+	}
+}
+
+func TestCapTip(t *testing.T) {
+	tests := []struct {
+		tip, feeCap, want int64
+	}{
+		{1, 10, 1},
+		{10, 10, 10},
+		{11, 10, 10},
+		{0, 10, 0},
+	}
+	for _, tt := range tests {
+		tip := big.NewInt(tt.tip)
+		got := capTip(tip, big.NewInt(tt.feeCap))
+		if got.Int64() != tt.want {
+			t.Errorf("capTip(%d, %d) = %s, want %d", tt.tip, tt.feeCap, got, tt.want)
+		}
+		// The result must not alias the input it may be bumped from later
+		got.Add(got, big.NewInt(1))
+		if tip.Int64() != tt.tip {
+			t.Fatalf("capTip modified its input: %s", tip)
+		}
+	}
+}
+
+func TestDynamicFeesBumpsResubmission(t *testing.T) {
+	backend := newFakeBackend()
+	s := newTestServer(t, backend)
+	s.config.BaseFeeMultiplier = 2
+	s.config.PriorityFee = big.NewInt(1e9)
+
+	// The previous broadcast already paid more than the fresh quote
+	sub := &submission{gasPrice: big.NewInt(100e9), tip: big.NewInt(5e9)}
+	feeCap, tip, err := s.dynamicFees(sub)
+	if err != nil {
+		t.Fatalf("dynamicFees: %v", err)
+	}
+	if feeCap.Cmp(big.NewInt(110e9)) != 0 || tip.Cmp(big.NewInt(5.5e9)) != 0 {
+		t.Fatalf("got cap %s tip %s, want both bumped by 10%%", feeCap, tip)
+	}
+
+	// A bump past the ceiling is clamped rather than sent
+	sub = &submission{gasPrice: big.NewInt(480e9), tip: big.NewInt(5e9)}
+	if feeCap, _, _ = s.dynamicFees(sub); feeCap.Cmp(s.config.MaxGasPrice) != 0 {
+		t.Fatalf("cap = %s, want the %s ceiling", feeCap, s.config.MaxGasPrice)
+	}
+}
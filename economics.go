@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// priceFeedCacheTTL is how long a fetched native token price is reused
+const priceFeedCacheTTL = 5 * time.Minute
+
+// GasEconomics tracks the relayer's cumulative gas spend
+type GasEconomics struct {
+	mu        sync.Mutex
+	totalWei  *big.Int
+	txCount   uint64
+	price     float64
+	priceAt   time.Time
+	feedURL   string
+	feedField string
+}
+
+// NewGasEconomics creates a tracker. feedURL may be empty to disable fiat
+// estimates; otherwise it must return a JSON object whose feedField holds
+// the native token price.
+func NewGasEconomics(feedURL, feedField string) *GasEconomics {
+	return &GasEconomics{
+		totalWei:  new(big.Int),
+		feedURL:   feedURL,
+		feedField: feedField,
+	}
+}
+
+// Record adds a transaction cost and returns the new cumulative total
+func (g *GasEconomics) Record(costWei *big.Int) (*big.Int, uint64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.totalWei.Add(g.totalWei, costWei)
+	g.txCount++
+	return new(big.Int).Set(g.totalWei), g.txCount
+}
+
+// Total returns the cumulative spend and transaction count
+func (g *GasEconomics) Total() (*big.Int, uint64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return new(big.Int).Set(g.totalWei), g.txCount
+}
+
+// FiatPrice returns the native token price from the feed, cached briefly
+func (g *GasEconomics) FiatPrice() (float64, error) {
+	if g.feedURL == "" {
+		return 0, fmt.Errorf("no price feed configured")
+	}
+
+	g.mu.Lock()
+	if !g.priceAt.IsZero() && time.Since(g.priceAt) < priceFeedCacheTTL {
+		price := g.price
+		g.mu.Unlock()
+		return price, nil
+	}
+	g.mu.Unlock()
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(g.feedURL)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return 0, fmt.Errorf("invalid price feed response: %v", err)
+	}
+	price, ok := body[g.feedField].(float64)
+	if !ok {
+		return 0, fmt.Errorf("price feed response has no numeric %q field", g.feedField)
+	}
+
+	g.mu.Lock()
+	g.price = price
+	g.priceAt = time.Now()
+	g.mu.Unlock()
+	return price, nil
+}
+
+// weiToEther converts wei to a float ether amount for display
+func weiToEther(wei *big.Int) float64 {
+	eth, _ := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e18)).Float64()
+	return eth
+}
+
+// recordGasEconomics logs and exports the cost of a confirmed relay
+func (s *Server) recordGasEconomics(result *TxResult) {
+	if result.CostWei == nil {
+		return
+	}
+
+	total, count := s.economics.Record(result.CostWei)
+	costWei, _ := new(big.Float).SetInt(result.CostWei).Float64()
+	s.metrics.Add("relay_gas_cost_wei_total", costWei)
+	s.metrics.Set("relay_gas_cost_ether_total", weiToEther(total))
+
+	if !s.config.LogGasEconomics {
+		return
+	}
+
+	go func() {
+		line := fmt.Sprintf("💸 Gas economics: tx %s cost %s wei (%.6f) at %s wei/gas, cumulative %.6f over %d txs",
+			result.TxHash, result.CostWei.String(), weiToEther(result.CostWei), result.EffectiveGasPrice.String(), weiToEther(total), count)
+
+		if price, err := s.economics.FiatPrice(); err == nil {
+			line += fmt.Sprintf(", ~%.4f %s this tx, ~%.2f %s total",
+				weiToEther(result.CostWei)*price, s.config.PriceFeedField, weiToEther(total)*price, s.config.PriceFeedField)
+		} else if s.config.PriceFeedURL != "" {
+			log.Printf("⚠️  Price feed unavailable: %v\n", err)
+		}
+
+		log.Println(line)
+	}()
+}
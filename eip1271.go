@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// eip1271ABI is the EIP-1271 signature validation method of contract wallets
+const eip1271ABI = `[
+	{
+		"inputs": [
+			{"name": "hash", "type": "bytes32"},
+			{"name": "signature", "type": "bytes"}
+		],
+		"name": "isValidSignature",
+		"outputs": [{"name": "magicValue", "type": "bytes4"}],
+		"stateMutability": "view",
+		"type": "function"
+	}
+]`
+
+// eip1271MagicValue is returned by isValidSignature for a valid signature
+var eip1271MagicValue = []byte{0x16, 0x26, 0xba, 0x7e}
+
+// verify1271 asks the contract wallet at wallet whether sig is valid for hash
+func (s *Server) verify1271(wallet common.Address, hash common.Hash, sig []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	code, err := s.client.CodeAt(ctx, wallet, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get code: %v", err)
+	}
+	if len(code) == 0 {
+		return fmt.Errorf("%s is not a contract", wallet.Hex())
+	}
+
+	parsedABI, err := abi.JSON(strings.NewReader(eip1271ABI))
+	if err != nil {
+		return err
+	}
+	data, err := parsedABI.Pack("isValidSignature", hash, sig)
+	if err != nil {
+		return fmt.Errorf("failed to pack isValidSignature: %v", err)
+	}
+	result, err := s.client.CallContract(ctx, ethereum.CallMsg{To: &wallet, Data: data}, nil)
+	if err != nil {
+		return fmt.Errorf("isValidSignature failed: %v", err)
+	}
+
+	var magic [4]byte
+	if err := parsedABI.UnpackIntoInterface(&magic, "isValidSignature", result); err != nil {
+		return fmt.Errorf("failed to unpack isValidSignature: %v", err)
+	}
+	if !bytes.Equal(magic[:], eip1271MagicValue) {
+		return fmt.Errorf("wallet rejected the signature")
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// forwardTypeHash is the EIP-712 type hash of the hub's Forward struct
+var forwardTypeHash = crypto.Keccak256Hash([]byte("Forward(address from,address to,uint256 value,uint32 space,uint256 nonce,uint256 deadline,bytes32 dataHash,address caller)"))
+
+// domainSeparator computes the hub's EIP-712 domain separator. The salt
+// field is part of the domain type only when EIP712_DOMAIN_SALT is set, so
+// it matches contracts with and without a salted domain.
+func (s *Server) domainSeparator(hub common.Address) common.Hash {
+	typeString := "EIP712Domain(string name,string version,uint256 chainId,address verifyingContract"
+	if s.config.DomainSalt != nil {
+		typeString += ",bytes32 salt"
+	}
+	typeString += ")"
+
+	encoded := [][]byte{
+		crypto.Keccak256([]byte(typeString)),
+		crypto.Keccak256([]byte(s.config.DomainName)),
+		crypto.Keccak256([]byte(s.config.DomainVersion)),
+		math.U256Bytes(new(big.Int).Set(s.config.ChainID)),
+		common.LeftPadBytes(hub.Bytes(), 32),
+	}
+	if s.config.DomainSalt != nil {
+		encoded = append(encoded, s.config.DomainSalt.Bytes())
+	}
+	return crypto.Keccak256Hash(encoded...)
+}
+
+// forwardStructHash computes the EIP-712 struct hash of a forward
+func forwardStructHash(f Forward) common.Hash {
+	return crypto.Keccak256Hash(
+		forwardTypeHash.Bytes(),
+		common.LeftPadBytes(f.From.Bytes(), 32),
+		common.LeftPadBytes(f.To.Bytes(), 32),
+		math.U256Bytes(new(big.Int).Set(bigOrZero(f.Value))),
+		math.U256Bytes(big.NewInt(int64(f.Space))),
+		math.U256Bytes(new(big.Int).Set(bigOrZero(f.Nonce))),
+		math.U256Bytes(new(big.Int).Set(bigOrZero(f.Deadline))),
+		f.DataHash[:],
+		common.LeftPadBytes(f.Caller.Bytes(), 32),
+	)
+}
+
+// verifySignature checks that sig is forward.from's EIP-712 signature of the
+// forward for the given hub. With ALLOW_1271, a signature that doesn't
+// recover to forward.from is checked against forward.from as an EIP-1271
+// contract wallet instead.
+func (s *Server) verifySignature(hub common.Address, f Forward, sig []byte) error {
+	domain := s.domainSeparator(hub)
+	structHash := forwardStructHash(f)
+	digest := crypto.Keccak256([]byte{0x19, 0x01}, domain.Bytes(), structHash.Bytes())
+
+	err := recoverSigner(digest, f.From, sig)
+	if err == nil || !s.config.Allow1271 {
+		return err
+	}
+	if err1271 := s.verify1271(f.From, common.BytesToHash(digest), sig); err1271 != nil {
+		return fmt.Errorf("%v; EIP-1271: %v", err, err1271)
+	}
+	return nil
+}
+
+// recoverSigner checks that sig is an ECDSA signature of digest by from
+func recoverSigner(digest []byte, from common.Address, sig []byte) error {
+	if len(sig) != signatureLength {
+		return fmt.Errorf("invalid signature length %d", len(sig))
+	}
+
+	// Recover expects a 0/1 recovery id
+	rsv := make([]byte, signatureLength)
+	copy(rsv, sig)
+	if rsv[64] >= 27 {
+		rsv[64] -= 27
+	}
+
+	pub, err := crypto.SigToPub(digest, rsv)
+	if err != nil {
+		return fmt.Errorf("failed to recover signer: %v", err)
+	}
+	if signer := crypto.PubkeyToAddress(*pub); signer != from {
+		return fmt.Errorf("signature is from %s, not forward.from", signer.Hex())
+	}
+	return nil
+}
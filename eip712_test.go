@@ -0,0 +1,207 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"math/big"
+	"net/http"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// typedForward builds the EIP-712 typed data of fwd for s and hub with
+// go-ethereum's reference encoder
+func typedForward(s *Server, hub common.Address, fwd Forward) apitypes.TypedData {
+	domainType := []apitypes.Type{
+		{Name: "name", Type: "string"},
+		{Name: "version", Type: "string"},
+		{Name: "chainId", Type: "uint256"},
+		{Name: "verifyingContract", Type: "address"},
+	}
+	domain := apitypes.TypedDataDomain{
+		Name:              s.config.DomainName,
+		Version:           s.config.DomainVersion,
+		ChainId:           (*math.HexOrDecimal256)(s.config.ChainID),
+		VerifyingContract: hub.Hex(),
+	}
+	if s.config.DomainSalt != nil {
+		domainType = append(domainType, apitypes.Type{Name: "salt", Type: "bytes32"})
+		domain.Salt = s.config.DomainSalt.Hex()
+	}
+
+	return apitypes.TypedData{
+		Types: apitypes.Types{
+			"EIP712Domain": domainType,
+			"Forward": {
+				{Name: "from", Type: "address"},
+				{Name: "to", Type: "address"},
+				{Name: "value", Type: "uint256"},
+				{Name: "space", Type: "uint32"},
+				{Name: "nonce", Type: "uint256"},
+				{Name: "deadline", Type: "uint256"},
+				{Name: "dataHash", Type: "bytes32"},
+				{Name: "caller", Type: "address"},
+			},
+		},
+		PrimaryType: "Forward",
+		Domain:      domain,
+		Message: apitypes.TypedDataMessage{
+			"from":     fwd.From.Hex(),
+			"to":       fwd.To.Hex(),
+			"value":    fwd.Value.String(),
+			"space":    big.NewInt(int64(fwd.Space)).String(),
+			"nonce":    fwd.Nonce.String(),
+			"deadline": fwd.Deadline.String(),
+			"dataHash": common.Hash(fwd.DataHash).Hex(),
+			"caller":   fwd.Caller.Hex(),
+		},
+	}
+}
+
+// signForward signs fwd for hub with key using the reference encoder
+func signForward(t *testing.T, s *Server, hub common.Address, fwd Forward, key *ecdsa.PrivateKey) []byte {
+	t.Helper()
+	digest, _, err := apitypes.TypedDataAndHash(typedForward(s, hub, fwd))
+	if err != nil {
+		t.Fatalf("TypedDataAndHash: %v", err)
+	}
+	sig, err := crypto.Sign(digest, key)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	sig[64] += 27
+	return sig
+}
+
+// eip712Server returns a test server with a named signing domain
+func eip712Server(t *testing.T, salt *common.Hash) *Server {
+	s := newTestServer(t, newFakeBackend())
+	s.config.DomainName = "HalloweenHub"
+	s.config.DomainVersion = "1"
+	s.config.DomainSalt = salt
+	return s
+}
+
+func TestDomainSeparatorMatchesEIP712(t *testing.T) {
+	hub := testHub(t).Address
+	salt := common.HexToHash("0x5a17")
+
+	var separators []common.Hash
+	for _, salt := range []*common.Hash{nil, &salt} {
+		s := eip712Server(t, salt)
+		typed := typedForward(s, hub, testForward(nil))
+		want, err := typed.HashStruct("EIP712Domain", typed.Domain.Map())
+		if err != nil {
+			t.Fatalf("HashStruct: %v", err)
+		}
+		got := s.domainSeparator(hub)
+		if got != common.BytesToHash(want) {
+			t.Fatalf("salt %v: separator = %s, want %s", salt, got.Hex(), common.BytesToHash(want).Hex())
+		}
+		separators = append(separators, got)
+	}
+	if separators[0] == separators[1] {
+		t.Fatal("salt did not change the domain separator")
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	hub := testHub(t).Address
+	salt := common.HexToHash("0x5a17")
+
+	for _, salt := range []*common.Hash{nil, &salt} {
+		s := eip712Server(t, salt)
+		key, _ := crypto.GenerateKey()
+		fwd := testForward([]byte{1})
+		fwd.From = crypto.PubkeyToAddress(key.PublicKey)
+		sig := signForward(t, s, hub, fwd, key)
+
+		if err := s.verifySignature(hub, fwd, sig); err != nil {
+			t.Fatalf("salt %v: valid signature rejected: %v", salt, err)
+		}
+
+		tampered := fwd
+		tampered.Nonce = big.NewInt(8)
+		if err := s.verifySignature(hub, tampered, sig); err == nil {
+			t.Fatalf("salt %v: signature accepted for a different nonce", salt)
+		}
+		if err := s.verifySignature(common.HexToAddress("0xa2"), fwd, sig); err == nil {
+			t.Fatalf("salt %v: signature accepted for a different hub", salt)
+		}
+
+		// A signature over the other domain variant must not verify
+		other := eip712Server(t, nil)
+		if salt == nil {
+			other.config.DomainSalt = &common.Hash{}
+		}
+		if err := other.verifySignature(hub, fwd, sig); err == nil {
+			t.Fatalf("salt %v: signature accepted under a different domain", salt)
+		}
+	}
+}
+
+func TestRelayVerifiesSignature(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	otherKey, _ := crypto.GenerateKey()
+
+	tests := []struct {
+		name   string
+		sign   func(s *Server, fwd Forward) []byte
+		reject bool
+	}{
+		{"valid", func(s *Server, fwd Forward) []byte { return signForward(t, s, testHub(t).Address, fwd, key) }, false},
+		{"other signer", func(s *Server, fwd Forward) []byte { return signForward(t, s, testHub(t).Address, fwd, otherKey) }, true},
+		{"zero signature", func(s *Server, fwd Forward) []byte { return make([]byte, signatureLength) }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := eip712Server(t, nil)
+			s.config.VerifySignature = true
+			req := testRelayRequest()
+			req.Forward.From = crypto.PubkeyToAddress(key.PublicKey)
+			req.Signature = "0x" + hex.EncodeToString(tt.sign(s, req.Forward))
+
+			status, response := relayResponse(t, s, req, "")
+			rejected := response.Code == "INVALID_SIGNATURE"
+			if rejected != tt.reject {
+				t.Fatalf("got %d %q %q, want rejected=%v", status, response.Code, response.Error, tt.reject)
+			}
+			if rejected && status != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d", status, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestRecoverSignerChecksLength(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	digest := crypto.Keccak256([]byte("forward"))
+	sig, err := crypto.Sign(digest, key)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+
+	if err := recoverSigner(digest, from, sig); err != nil {
+		t.Fatalf("0/1 recovery id rejected: %v", err)
+	}
+	legacy := append([]byte(nil), sig...)
+	legacy[64] += 27
+	if err := recoverSigner(digest, from, legacy); err != nil {
+		t.Fatalf("27/28 recovery id rejected: %v", err)
+	}
+	if legacy[64] < 27 {
+		t.Fatal("recoverSigner modified the caller's signature")
+	}
+
+	if err := recoverSigner(digest, from, sig[:signatureLength-1]); err == nil {
+		t.Fatal("short signature accepted")
+	}
+	if err := recoverSigner(digest, from, append(append([]byte(nil), sig...), 0)); err == nil {
+		t.Fatal("long signature accepted")
+	}
+}
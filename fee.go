@@ -0,0 +1,149 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// FeeAuthorization lets the user reimburse the relayer in an ERC-20. It is
+// signed by forward.from as a personal_sign message over
+//
+//	keccak256(abi.encodePacked(hub, chainId, from, nonce, token, amount))
+//
+// which binds the fee to one specific forward on one hub.
+type FeeAuthorization struct {
+	Token     common.Address `json:"token"`
+	Amount    *big.Int       `json:"amount"`
+	Signature string         `json:"signature"`
+}
+
+// UnmarshalJSON accepts amount as a decimal or 0x-prefixed string as well as
+// a JSON number, since token amounts routinely exceed float precision
+func (f *FeeAuthorization) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Token     common.Address  `json:"token"`
+		Amount    json.RawMessage `json:"amount"`
+		Signature string          `json:"signature"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	var amount math.HexOrDecimal256
+	if err := amount.UnmarshalJSON(raw.Amount); err != nil {
+		return fmt.Errorf("invalid fee amount: %v", err)
+	}
+
+	f.Token = raw.Token
+	f.Amount = (*big.Int)(&amount)
+	f.Signature = raw.Signature
+	return nil
+}
+
+// feeDigest is the message hash the user signs to authorize a fee
+func feeDigest(hub common.Address, chainID *big.Int, fwd Forward, fee *FeeAuthorization) []byte {
+	packed := crypto.Keccak256(
+		hub.Bytes(),
+		common.LeftPadBytes(chainID.Bytes(), 32),
+		fwd.From.Bytes(),
+		common.LeftPadBytes(fwd.Nonce.Bytes(), 32),
+		fee.Token.Bytes(),
+		common.LeftPadBytes(fee.Amount.Bytes(), 32),
+	)
+	return accounts.TextHash(packed)
+}
+
+// validateFee checks a fee authorization against the configured tokens and
+// verifies it was signed by the forward's sender
+func (s *Server) validateFee(hub HubConfig, fwd Forward, fee *FeeAuthorization) error {
+	minAmount, ok := s.config.FeeTokens[fee.Token]
+	if !ok {
+		return fmt.Errorf("fee token %s is not accepted", fee.Token.Hex())
+	}
+	if fee.Amount == nil || fee.Amount.Cmp(minAmount) < 0 {
+		return fmt.Errorf("fee amount below the minimum of %s", minAmount.String())
+	}
+
+	sig, err := decodeHex(fee.Signature)
+	if err != nil || len(sig) != 65 {
+		return fmt.Errorf("invalid fee signature format")
+	}
+	// Normalize v to 0/1 for recovery
+	sig = append([]byte(nil), sig...)
+	if sig[64] >= 27 {
+		sig[64] -= 27
+	}
+
+	pub, err := crypto.SigToPub(feeDigest(hub.Address, s.config.ChainID, fwd, fee), sig)
+	if err != nil {
+		return fmt.Errorf("invalid fee signature: %v", err)
+	}
+	if signer := crypto.PubkeyToAddress(*pub); signer != fwd.From {
+		return fmt.Errorf("fee signed by %s, not forward.from", signer.Hex())
+	}
+	return nil
+}
+
+// FeeLedger accrues expected fee reimbursements per token
+type FeeLedger struct {
+	mu      sync.Mutex
+	accrued map[common.Address]*big.Int
+}
+
+// NewFeeLedger creates an empty ledger
+func NewFeeLedger() *FeeLedger {
+	return &FeeLedger{accrued: make(map[common.Address]*big.Int)}
+}
+
+// Record adds a reimbursement for token
+func (l *FeeLedger) Record(token common.Address, amount *big.Int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	total, ok := l.accrued[token]
+	if !ok {
+		total = new(big.Int)
+		l.accrued[token] = total
+	}
+	total.Add(total, amount)
+}
+
+// Totals returns accrued amounts keyed by checksummed token address
+func (l *FeeLedger) Totals() map[string]string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	totals := make(map[string]string, len(l.accrued))
+	for token, amount := range l.accrued {
+		totals[token.Hex()] = amount.String()
+	}
+	return totals
+}
+
+// parseFeeTokens parses FEE_TOKENS entries of the form token[:minAmount]
+func parseFeeTokens(entries []string) (map[common.Address]*big.Int, error) {
+	tokens := make(map[common.Address]*big.Int, len(entries))
+	for _, entry := range entries {
+		addr, minStr, _ := strings.Cut(strings.TrimSpace(entry), ":")
+		if !common.IsHexAddress(addr) {
+			return nil, fmt.Errorf("invalid FEE_TOKENS entry %q", entry)
+		}
+
+		minAmount := new(big.Int)
+		if minStr != "" {
+			if _, ok := minAmount.SetString(minStr, 10); !ok {
+				return nil, fmt.Errorf("invalid minimum in FEE_TOKENS entry %q", entry)
+			}
+		}
+		tokens[common.HexToAddress(addr)] = minAmount
+	}
+	return tokens, nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import "sync"
+
+// GasAverage keeps a moving average of gasUsed over the most recent
+// successful relays, used as the gas limit when estimation fails
+type GasAverage struct {
+	mu         sync.Mutex
+	samples    []uint64
+	next       int
+	filled     bool
+	minSamples int
+	seed       uint64
+}
+
+// NewGasAverage creates an average over the last window samples. Until
+// minSamples have been recorded, Value returns seed.
+func NewGasAverage(window, minSamples int, seed uint64) *GasAverage {
+	return &GasAverage{
+		samples:    make([]uint64, window),
+		minSamples: minSamples,
+		seed:       seed,
+	}
+}
+
+// Record adds a gasUsed sample, evicting the oldest once the window is full
+func (g *GasAverage) Record(gasUsed uint64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.samples[g.next] = gasUsed
+	g.next = (g.next + 1) % len(g.samples)
+	if g.next == 0 {
+		g.filled = true
+	}
+}
+
+// Value returns the current average and the number of samples behind it
+func (g *GasAverage) Value() (uint64, int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	count := g.next
+	if g.filled {
+		count = len(g.samples)
+	}
+	if count < g.minSamples || count == 0 {
+		return g.seed, count
+	}
+
+	var sum uint64
+	for _, gas := range g.samples[:count] {
+		sum += gas
+	}
+	return sum / uint64(count), count
+}
+
+// Fallback returns the average scaled by bufferPercent, or the unscaled
+// seed while there are too few samples
+func (g *GasAverage) Fallback(bufferPercent int) uint64 {
+	avg, count := g.Value()
+	if count < g.minSamples || count == 0 {
+		return avg
+	}
+	return avg * uint64(bufferPercent) / 100
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// healthCheckTimeout bounds each individual health sub-check
+const healthCheckTimeout = 5 * time.Second
+
+// HealthCheck is the outcome of one named health sub-check
+type HealthCheck struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latencyMs"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// runHealthChecks runs every sub-check concurrently and returns them by name
+func (s *Server) runHealthChecks() map[string]HealthCheck {
+	checks := map[string]func(ctx context.Context) (string, error){
+		"rpc":            s.checkRPC,
+		"signer":         s.checkSigner,
+		"hub_code":       func(ctx context.Context) (string, error) { return s.checkCode(ctx, s.config.HubAddress) },
+		"nft_code":       func(ctx context.Context) (string, error) { return s.checkCode(ctx, s.config.NFTContract) },
+		"caller_allowed": s.checkCallerAllowed,
+		"balance":        s.checkBalance,
+		"chain_id":       s.checkChainIDHealth,
+		"hub_selectors":  s.checkHubSelectorsHealth,
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]HealthCheck, len(checks))
+	for name, check := range checks {
+		wg.Add(1)
+		go func(name string, check func(ctx context.Context) (string, error)) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			detail, err := check(ctx)
+			result := HealthCheck{Status: "ok", LatencyMs: time.Since(start).Milliseconds(), Detail: detail}
+			if err != nil {
+				result.Status = "fail"
+				result.Detail = err.Error()
+			}
+
+			mu.Lock()
+			results[name] = result
+			mu.Unlock()
+		}(name, check)
+	}
+	wg.Wait()
+	return results
+}
+
+// checkRPC verifies the node answers and reports its head block
+func (s *Server) checkRPC(ctx context.Context) (string, error) {
+	head, err := s.client.BlockNumber(ctx)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("block %d", head), nil
+}
+
+// checkSigner verifies the signer still reports the relayer address
+func (s *Server) checkSigner(ctx context.Context) (string, error) {
+	if addr := s.currentSigner().Address(); addr != s.relayer() {
+		return "", fmt.Errorf("signer address %s does not match relayer %s", addr.Hex(), s.relayer().Hex())
+	}
+	return s.relayer().Hex(), nil
+}
+
+// checkCode verifies a contract is deployed at addr
+func (s *Server) checkCode(ctx context.Context, addr common.Address) (string, error) {
+	code, err := s.client.CodeAt(ctx, addr, nil)
+	if err != nil {
+		return "", err
+	}
+	if len(code) == 0 {
+		return "", fmt.Errorf("no contract code at %s", addr.Hex())
+	}
+	return fmt.Sprintf("%d bytes", len(code)), nil
+}
+
+// checkCallerAllowed verifies the hub accepts the expected caller
+func (s *Server) checkCallerAllowed(ctx context.Context) (string, error) {
+	hub := s.config.Hubs[0]
+	if _, ok := hub.ABI.Methods["isCallerAllowed"]; !ok {
+		return "hub has no isCallerAllowed", nil
+	}
+
+	caller := s.expectedCaller()
+	allowed, err := s.isCallerAllowed(ctx, hub, caller)
+	if err != nil {
+		return "", err
+	}
+	if !allowed {
+		return "", fmt.Errorf("caller %s is not an allowed caller", caller.Hex())
+	}
+	return "", nil
+}
+
+// checkExpectedCaller refuses to start when EXPECTED_CALLER is set but a hub
+// that exposes isCallerAllowed doesn't allow it, on s or any network
+func (s *Server) checkExpectedCaller() error {
+	for name, network := range s.networks {
+		if err := network.checkExpectedCaller(); err != nil {
+			return fmt.Errorf("network %q: %v", name, err)
+		}
+	}
+	if s.config.ExpectedCaller == (common.Address{}) {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	for _, hub := range s.config.Hubs {
+		if _, ok := hub.ABI.Methods["isCallerAllowed"]; !ok {
+			continue
+		}
+		allowed, err := s.isCallerAllowed(ctx, hub, s.config.ExpectedCaller)
+		if err != nil {
+			return fmt.Errorf("failed to check EXPECTED_CALLER on hub %s: %v", hub.Version, err)
+		}
+		if !allowed {
+			return fmt.Errorf("EXPECTED_CALLER %s is not an allowed caller on hub %s", s.config.ExpectedCaller.Hex(), hub.Version)
+		}
+	}
+	log.Printf("🔗 Expecting forward.caller %s\n", s.config.ExpectedCaller.Hex())
+	return nil
+}
+
+// isCallerAllowed asks hub whether caller may submit forwards
+func (s *Server) isCallerAllowed(ctx context.Context, hub HubConfig, caller common.Address) (bool, error) {
+	data, err := hub.ABI.Pack("isCallerAllowed", caller)
+	if err != nil {
+		return false, err
+	}
+	result, err := s.client.CallContract(ctx, ethereum.CallMsg{To: &hub.Address, Data: data}, nil)
+	if err != nil {
+		return false, err
+	}
+
+	var allowed bool
+	if err := hub.ABI.UnpackIntoInterface(&allowed, "isCallerAllowed", result); err != nil {
+		return false, err
+	}
+	return allowed, nil
+}
+
+// checkChainIDHealth verifies the RPC endpoint is still on CHAIN_ID
+func (s *Server) checkChainIDHealth(ctx context.Context) (string, error) {
+	if err := s.checkChainID(ctx); err != nil {
+		return "", err
+	}
+	return s.config.ChainID.String(), nil
+}
+
+// checkBalance verifies the relayer has funds to pay for gas
+func (s *Server) checkBalance(ctx context.Context) (string, error) {
+	balance, err := s.client.BalanceAt(ctx, s.relayer(), nil)
+	if err != nil {
+		return "", err
+	}
+	if balance.Sign() == 0 {
+		return "", fmt.Errorf("relayer balance is zero")
+	}
+	return fmt.Sprintf("%.6f", weiToEther(balance)), nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gorilla/mux"
+)
+
+// MintCount is an address's successful relay history
+type MintCount struct {
+	Successes     uint64 `json:"successes"`
+	LastSuccessAt int64  `json:"lastSuccessAt,omitempty"`
+}
+
+// MintHistory counts successful relays per address. When backed by a file
+// the counts survive restarts. Record only updates memory; Flush writes the
+// file, so a crash loses at most the counts since the last flush.
+type MintHistory struct {
+	mu     sync.Mutex
+	path   string
+	counts map[string]*MintCount
+	dirty  bool
+}
+
+// NewMintHistory opens a history persisted at path. An empty path keeps
+// counts in memory only.
+func NewMintHistory(path string) (*MintHistory, error) {
+	history := &MintHistory{path: path, counts: make(map[string]*MintCount)}
+	if path == "" {
+		return history, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return history, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mint history: %v", err)
+	}
+	if err := json.Unmarshal(raw, &history.counts); err != nil {
+		return nil, fmt.Errorf("failed to parse mint history: %v", err)
+	}
+	return history, nil
+}
+
+// Record counts a successful relay for address
+func (h *MintHistory) Record(address string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	count, ok := h.counts[address]
+	if !ok {
+		count = &MintCount{}
+		h.counts[address] = count
+	}
+	count.Successes++
+	count.LastSuccessAt = time.Now().Unix()
+	h.dirty = true
+}
+
+// Get returns the history for address
+func (h *MintHistory) Get(address string) MintCount {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if count, ok := h.counts[address]; ok {
+		return *count
+	}
+	return MintCount{}
+}
+
+// Flush writes the history if it changed since the last write
+func (h *MintHistory) Flush() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.write()
+}
+
+// write writes the history atomically if it changed. Callers hold h.mu.
+func (h *MintHistory) write() error {
+	if h.path == "" || !h.dirty {
+		return nil
+	}
+
+	raw, err := json.Marshal(h.counts)
+	if err != nil {
+		return fmt.Errorf("failed to encode mint history: %v", err)
+	}
+	if err := writeFileAtomic(h.path, raw); err != nil {
+		return fmt.Errorf("failed to write mint history: %v", err)
+	}
+	h.dirty = false
+	return nil
+}
+
+// Close flushes the history one last time on shutdown
+func (h *MintHistory) Close() error {
+	return h.Flush()
+}
+
+// AddressResponse is everything the relayer knows about one address
+type AddressResponse struct {
+	Address       string         `json:"address"`
+	Mints         MintCount      `json:"mints"`
+	RateLimitUsed int            `json:"rateLimitUsed"`
+	RateLimitMax  int            `json:"rateLimitMax"`
+	DailyCapUsed  int            `json:"dailyCapUsed"`
+	DailyCapMax   int            `json:"dailyCapMax,omitempty"`
+	InFlight      map[uint32]int `json:"inFlight"`
+	RevertBlocked bool           `json:"revertBlocked"`
+}
+
+// addressHandler reports an address's mint history alongside its current
+// rate limit, daily cap, in-flight relays and auto-blocklist status
+func (s *Server) addressHandler(w http.ResponseWriter, r *http.Request) {
+	addrStr := mux.Vars(r)["address"]
+	if !common.IsHexAddress(addrStr) {
+		s.sendError(w, http.StatusBadRequest, "Invalid address", "")
+		return
+	}
+	address := common.HexToAddress(addrStr).Hex()
+
+	used, limit := s.rateLimitStatus(address)
+	_, inFlight := s.suggestSpace(address)
+	writeJSON(w, http.StatusOK, AddressResponse{
+		Address:       address,
+		Mints:         s.mintHistory.Get(address),
+		RateLimitUsed: used,
+		RateLimitMax:  limit,
+		DailyCapUsed:  s.dailyCap.Used(address),
+		DailyCapMax:   s.config.DailyCapPerAddress,
+		InFlight:      inFlight,
+		RevertBlocked: s.reverts.IsBlocked(address),
+	})
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMintHistoryBatchesWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	history, err := NewMintHistory(path)
+	if err != nil {
+		t.Fatalf("NewMintHistory: %v", err)
+	}
+	address := "0x00000000000000000000000000000000000000F1"
+
+	history.Record(address)
+	history.Record(address)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("Record wrote the file before a flush: %v", err)
+	}
+
+	if err := history.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	reloaded, err := NewMintHistory(path)
+	if err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if count := reloaded.Get(address); count.Successes != 2 {
+		t.Fatalf("reloaded successes = %d, want 2", count.Successes)
+	}
+
+	// Nothing changed since the flush, so nothing is rewritten
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if err := history.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("unchanged history rewritten: %v", err)
+	}
+
+	history.Record(address)
+	if err := history.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if reloaded, err = NewMintHistory(path); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if count := reloaded.Get(address); count.Successes != 3 {
+		t.Fatalf("successes after Close = %d, want 3", count.Successes)
+	}
+}
@@ -1,24 +1,32 @@
 package main
 
 import (
+	"container/list"
 	"context"
-	"crypto/ecdsa"
+	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math/big"
+	"math/rand/v2"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
@@ -29,13 +37,149 @@ import (
 
 // Configuration holds server configuration
 type Config struct {
-	Port              string
-	RPCURL            string
-	RelayerPrivateKey string
-	HubAddress        common.Address
-	NFTContract       common.Address
-	ChainID           *big.Int
-	MaxGasPrice       *big.Int
+	Port                  string
+	RPCURL                string
+	RelayerPrivateKey     string
+	RemoteSignerURL       string
+	RemoteSignerType      string
+	RemoteSignerAddr      common.Address
+	HubAddress            common.Address
+	Hubs                  []HubConfig
+	NFTContract           common.Address
+	ChainID               *big.Int
+	MaxGasPrice           *big.Int
+	MinGasPrice           *big.Int
+	NonceSpaces           int
+	AdminAPIKey           string
+	RevertThreshold       int
+	RevertWindow          time.Duration
+	RevertBlockTime       time.Duration
+	UseAccessList         bool
+	SuccessEventTopic     common.Hash
+	Retry                 RetryPolicy
+	ReceiptBatching       bool
+	ReceiptPoll           time.Duration
+	CORSOrigins           []string
+	AdminCORSOrigins      []string
+	CORSMaxAge            time.Duration
+	BalanceCacheTTL       time.Duration
+	DeadlineClock         string
+	BlockTimeCacheTTL     time.Duration
+	CallAllowedMethods    []string
+	GasRetryAfter         time.Duration
+	WebhookURL            string
+	WebhookSecret         string
+	WebhookTimeout        time.Duration
+	TestMode              bool
+	LogGasEconomics       bool
+	PriceFeedURL          string
+	PriceFeedField        string
+	BreakerThreshold      int
+	BreakerWindow         time.Duration
+	BreakerCooldown       time.Duration
+	MerkleBatch           bool
+	MerkleExecuteMethod   string
+	MaxBatchSize          int
+	ReorgMonitor          bool
+	ReorgWindow           time.Duration
+	ReorgCheckInterval    time.Duration
+	AllowAnyCaller        bool
+	GasFallbackLimit      uint64
+	GasAverageWindow      int
+	GasAverageMinSamples  int
+	GasFallbackBuffer     int
+	TrustProxy            bool
+	TrustedProxyHops      int
+	DailyCapPerAddress    int
+	NetworkName           string
+	Networks              []string
+	MintedFalseTTL        time.Duration
+	MintedFalseTTLs       map[common.Address]time.Duration
+	MaxBodyBytes          int64
+	RequireHTTPS          bool
+	HTTPSRedirect         bool
+	TLSCertFile           string
+	TLSKeyFile            string
+	DedupeDataHash        bool
+	SelfTest              bool
+	SelfTestFixture       string
+	SelfTestStrict        bool
+	FeeMode               bool
+	FeeTokens             map[common.Address]*big.Int
+	FeeExecuteMethod      string
+	FeeRequired           bool
+	CompressResponses     bool
+	CompressMinBytes      int
+	KeystoreDir           string
+	MaxGlobalInflight     int
+	RequireFromNonce      bool
+	RequireFromBalance    *big.Int
+	ActivityCacheTTL      time.Duration
+	TxStoreFile           string
+	MintHistoryFile       string
+	TxRetention           time.Duration
+	EnablePprof           bool
+	ReadTimeout           time.Duration
+	ReadHeaderTimeout     time.Duration
+	WriteTimeout          time.Duration
+	IdleTimeout           time.Duration
+	EnforceSupplyCap      bool
+	SupplyTotalMethod     string
+	SupplyMaxMethod       string
+	SupplyCacheTTL        time.Duration
+	AllowedURIHosts       []string
+	MaxRateLimitEntries   int
+	RateLimitFullPolicy   string
+	DynamicFees           bool
+	BaseFeeMultiplier     float64
+	PriorityFee           *big.Int
+	AllowDeploy           bool
+	DeployFactory         common.Address
+	DeployMaxBytes        int
+	DeployMaxGas          uint64
+	SoftTimeout           time.Duration
+	MaxNonce              *big.Int
+	MaxRelayDuration      time.Duration
+	SpaceAssignment       string
+	SignerType            string
+	StrictContentType     bool
+	FirstRequestBonus     int
+	MaintenanceMessage    string
+	MaintenanceFile       string
+	MulticallAddress      common.Address
+	AllowValue            bool
+	AllowedValues         []*big.Int
+	ProcessedFile         string
+	MetricsFile           string
+	ShutdownTimeout       time.Duration
+	MaxReceiptWatchers    int
+	IPBlocklist           []*net.IPNet
+	ServerCallData        bool
+	DefaultTokenURI       string
+	Allow1271             bool
+	DailyGasBudget        *big.Int
+	ChainIDCheckInterval  time.Duration
+	WebhookConfirmations  uint64
+	MaxRequestCost        *big.Int
+	ExpectedCaller        common.Address
+	HubSelectorStrict     bool
+	CleanupJitter         time.Duration
+	DecodeCallDataMethods []string
+	SignResponses         bool
+	BroadcastRPCs         []string
+	GasSoftLimit          *big.Int
+	GasShedExponent       float64
+	VerifySignature       bool
+	DomainName            string
+	DomainVersion         string
+	DomainSalt            *common.Hash
+}
+
+// HubConfig describes one deployed Hub contract version
+type HubConfig struct {
+	Version string
+	Address common.Address
+	ABI     abi.ABI
 }
 
 // Bytes32 is a custom type for handling hex string to [32]byte conversion
@@ -48,13 +192,9 @@ func (b *Bytes32) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
-	// Remove 0x prefix if present
-	hexStr = strings.TrimPrefix(hexStr, "0x")
-
-	// Decode hex string
-	decoded, err := hex.DecodeString(hexStr)
+	decoded, err := decodeHex(hexStr)
 	if err != nil {
-		return fmt.Errorf("invalid hex string: %v", err)
+		return err
 	}
 
 	if len(decoded) != 32 {
@@ -70,6 +210,27 @@ func (b Bytes32) MarshalJSON() ([]byte, error) {
 	return json.Marshal("0x" + hex.EncodeToString(b[:]))
 }
 
+// SignatureRSV represents a signature split into its r, s and v components
+type SignatureRSV struct {
+	R Bytes32 `json:"r"`
+	S Bytes32 `json:"s"`
+	V uint8   `json:"v"`
+}
+
+// Bytes assembles the 65-byte r || s || v signature
+func (sig SignatureRSV) Bytes() []byte {
+	v := sig.V
+	// Normalize 0/1 recovery ids to the 27/28 form produced by eth_signTypedData
+	if v < 27 {
+		v += 27
+	}
+
+	out := make([]byte, 0, 65)
+	out = append(out, sig.R[:]...)
+	out = append(out, sig.S[:]...)
+	return append(out, v)
+}
+
 // Forward struct matches the smart contract's Forward struct
 type Forward struct {
 	From     common.Address `json:"from"`
@@ -82,53 +243,225 @@ type Forward struct {
 	Caller   common.Address `json:"caller"`
 }
 
+// ForwardTuple is the Forward struct as expected by ABI encoding.
+// The order MUST match the Hub contract's Forward struct.
+type ForwardTuple struct {
+	From     common.Address
+	To       common.Address
+	Value    *big.Int
+	Space    uint32
+	Nonce    *big.Int
+	Deadline *big.Int
+	DataHash [32]byte
+	Caller   common.Address
+}
+
+// Tuple converts the Forward into its ABI encoding form
+func (f Forward) Tuple() ForwardTuple {
+	return ForwardTuple{
+		From:     f.From,
+		To:       f.To,
+		Value:    f.Value,
+		Space:    f.Space,
+		Nonce:    f.Nonce,
+		Deadline: f.Deadline,
+		DataHash: f.DataHash,
+		Caller:   f.Caller,
+	}
+}
+
 // RelayRequest represents the incoming relay request
 type RelayRequest struct {
-	Forward   Forward `json:"forward"`
-	Signature string  `json:"signature"`
-	CallData  string  `json:"callData"`
+	Forward         Forward           `json:"forward"`
+	Signature       string            `json:"signature"`
+	SignatureRSV    *SignatureRSV     `json:"signatureRSV,omitempty"`
+	CallData        string            `json:"callData"`
+	HubVersion      string            `json:"hubVersion,omitempty"`
+	Network         string            `json:"network,omitempty"`
+	Fee             *FeeAuthorization `json:"fee,omitempty"`
+	MaxGasPriceGwei json.Number       `json:"maxGasPriceGwei,omitempty"`
+}
+
+// flatSignature returns the signature as a hex string, assembling it from
+// signatureRSV when that form was sent instead
+func (req RelayRequest) flatSignature() string {
+	if req.SignatureRSV != nil {
+		return "0x" + hex.EncodeToString(req.SignatureRSV.Bytes())
+	}
+	return req.Signature
 }
 
 // RelayResponse represents the relay response
 type RelayResponse struct {
-	Success         bool   `json:"success"`
-	TxHash          string `json:"txHash,omitempty"`
-	TransactionHash string `json:"transactionHash,omitempty"`
-	BlockNumber     uint64 `json:"blockNumber,omitempty"`
-	GasUsed         string `json:"gasUsed,omitempty"`
-	Error           string `json:"error,omitempty"`
-	Details         string `json:"details,omitempty"`
+	Success              bool              `json:"success"`
+	TxHash               string            `json:"txHash,omitempty"`
+	TransactionHash      string            `json:"transactionHash,omitempty"`
+	BlockNumber          uint64            `json:"blockNumber,omitempty"`
+	GasUsed              string            `json:"gasUsed,omitempty"`
+	Error                string            `json:"error,omitempty"`
+	Code                 string            `json:"code,omitempty"`
+	Details              string            `json:"details,omitempty"`
+	Attempts             int               `json:"attempts,omitempty"`
+	RetryAfter           int               `json:"retryAfter,omitempty"`
+	Simulated            bool              `json:"simulated,omitempty"`
+	RawTransaction       string            `json:"rawTransaction,omitempty"`
+	MaxFeePerGas         string            `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas string            `json:"maxPriorityFeePerGas,omitempty"`
+	DeployedAddress      string            `json:"deployedAddress,omitempty"`
+	Status               string            `json:"status,omitempty"`
+	Space                *uint32           `json:"space,omitempty"`
+	EffectiveGasPrice    string            `json:"effectiveGasPrice,omitempty"`
+	TxCostWei            string            `json:"txCostWei,omitempty"`
+	Errors               []ValidationError `json:"errors,omitempty"`
+	DecodedCallData      *DecodedCall      `json:"decodedCallData,omitempty"`
+}
+
+// TxResult describes a confirmed relayed transaction
+type TxResult struct {
+	TxHash            string
+	BlockNumber       uint64
+	GasUsed           *big.Int
+	Attempts          int
+	Simulated         bool
+	EffectiveGasPrice *big.Int
+	CostWei           *big.Int
+	BlockHash         common.Hash
+	RawTx             []byte
+	GasFeeCap         *big.Int
+	GasTipCap         *big.Int
+	Logs              []*types.Log
+}
+
+// SpaceResponse represents a nonce space suggestion
+type SpaceResponse struct {
+	Address  string         `json:"address"`
+	Space    uint32         `json:"space"`
+	InFlight map[uint32]int `json:"inFlight"`
+	Advisory bool           `json:"advisory"`
 }
 
 // HealthResponse represents health check response
 type HealthResponse struct {
-	Status    string `json:"status"`
-	Relayer   string `json:"relayer"`
-	Timestamp int64  `json:"timestamp"`
+	Status    string                 `json:"status"`
+	Relayer   string                 `json:"relayer"`
+	Breaker   string                 `json:"breaker"`
+	Checks    map[string]HealthCheck `json:"checks"`
+	Timestamp int64                  `json:"timestamp"`
 }
 
-// RateLimit tracks request rates per address
+// RateLimit tracks request rates per address, optionally capped to a
+// maximum number of addresses with least-recently-used eviction
 type RateLimit struct {
-	mu       sync.RWMutex
-	requests map[string][]int64
+	mu         sync.RWMutex
+	requests   map[string][]int64
+	order      *list.List
+	elems      map[string]*list.Element
+	maxEntries int
+	policy     string
+	firstSeen  map[string]int64
+	firstBonus int
 }
 
 // Server holds the relayer server state
 type Server struct {
 	config            Config
-	client            *ethclient.Client
-	relayerKey        *ecdsa.PrivateKey
+	client            EthBackend
+	signer            TxSigner
 	relayerAddress    common.Address
+	signerMutex       sync.RWMutex
+	submitMutex       sync.RWMutex
 	processedRequests map[string]time.Time
 	reqMutex          sync.RWMutex
 	rateLimit         *RateLimit
+	inflight          map[string]map[uint32]int
+	inflightMutex     sync.Mutex
+	reverts           *RevertTracker
+	metrics           *Metrics
+	receipts          *ReceiptWatcher
+	reorgs            *ReorgMonitor
+	gasAverage        *GasAverage
+	dailyCap          *DailyCap
+	networks          map[string]*Server
+	mintedCache       *MintedCache
+	fees              *FeeLedger
+	globalInflight    atomic.Int64
+	activity          *ActivityCache
+	txStore           *TxStore
+	mintHistory       *MintHistory
+	spaceCursor       atomic.Uint32
+	nonces            *NonceManager
+	// relays tracks background work that records relay outcomes: executing
+	// relays, resumed transactions and webhook deliveries
+	relays   sync.WaitGroup
+	watchers *WatcherPool
+	// webhookQueue holds success webhooks waiting for confirmations
+	webhookQueue webhookQueue
+	// blocklist decides whether a client IP may relay. It defaults to the
+	// IP_BLOCKLIST ranges and can be replaced, e.g. with a geo/ASN lookup.
+	blocklist func(ip net.IP) bool
+	budget    *GasBudget
+	// chainMismatch holds the RPC's chain ID while it differs from CHAIN_ID
+	chainMismatch    atomic.Pointer[big.Int]
+	maintenance      *Maintenance
+	supply           *SupplyCache
+	balanceMutex     sync.Mutex
+	balance          *big.Int
+	balanceFetchedAt time.Time
+	blockTimeMutex   sync.Mutex
+	blockTime        int64
+	blockTimeAt      time.Time
+	callAllowlist    callAllowlist
+	economics        *GasEconomics
+	breaker          *CircuitBreaker
 }
 
+// simulatedBlockNumber is reported for transactions stubbed in TEST_MODE
+const simulatedBlockNumber = 1
+
+// signatureLength is the size of an r || s || v ECDSA signature
+const signatureLength = 65
+
+// spaceSentinel in forward.space asks the relayer to assign a space when
+// SPACE_ASSIGNMENT is enabled
+const spaceSentinel = ^uint32(0)
+
+// Space assignment strategies
+const (
+	spaceRoundRobin  = "round-robin"
+	spaceLeastLoaded = "least-loaded"
+)
+
 const (
 	cacheDuration        = 5 * time.Minute
 	rateLimitWindow      = 1 * time.Minute
 	maxRequestsPerWindow = 5
 	cleanupInterval      = 1 * time.Minute
+	receiptTimeout       = 2 * time.Minute
+)
+
+var (
+	// errTxReverted is returned when a mined transaction has a failed receipt status
+	errTxReverted = errors.New("transaction reverted by contract")
+	// errReceiptTimeout is returned when no receipt arrives before the wait timeout
+	errReceiptTimeout = errors.New("timeout waiting for transaction receipt")
+	// errInsufficientRelayerFunds is returned when the relayer balance can't
+	// cover the worst-case cost of a transaction
+	errInsufficientRelayerFunds = errors.New("insufficient relayer funds for this transaction")
+	// errSoftFailure is returned when a transaction succeeded but the configured
+	// success event is missing from its logs
+	errSoftFailure = errors.New("transaction succeeded but the success event was not emitted")
+	// errRequestTooExpensive is returned when a gas estimate implies a cost
+	// above MAX_REQUEST_COST_WEI
+	errRequestTooExpensive = errors.New("estimated cost exceeds the per-request budget")
+	// errReplacementCapped is returned when a resubmission can't outbid the
+	// pending transaction because the gas price ceiling has been reached
+	errReplacementCapped = errors.New("pending transaction can't be replaced: gas price ceiling reached")
+	// errNonceConsumed is returned when another transaction was mined at the
+	// nonce of a relay's broadcasts, so none of them can ever confirm
+	errNonceConsumed = errors.New("relayer nonce consumed by another transaction")
+	// errSettledElsewhere is returned when a reconcile pass recorded a relay
+	// transaction's outcome, and did its bookkeeping, before the relay did
+	errSettledElsewhere = errors.New("transaction outcome already recorded")
 )
 
 // Hub Contract ABI (execute function)
@@ -218,40 +551,98 @@ func main() {
 	}
 
 	// Create server
-	server, err := NewServer(config)
+	server, err := NewServer(config, nil)
 	if err != nil {
 		log.Fatalf("Failed to create server: %v", err)
 	}
+	if err := server.addNetworks(); err != nil {
+		log.Fatalf("Failed to configure networks: %v", err)
+	}
+	if err := server.checkExpectedCaller(); err != nil {
+		log.Fatalf("Refusing to start: %v", err)
+	}
+	if err := server.logHubSelectors(); err != nil {
+		log.Fatalf("Refusing to start: hub ABI mismatch: %v", err)
+	}
+	if err := server.runSelfTest(); err != nil {
+		log.Fatalf("Refusing to start: self-test failed: %v", err)
+	}
 
 	// Setup HTTP server
 	r := mux.NewRouter()
 	r.HandleFunc("/health", server.healthHandler).Methods("GET")
 	r.HandleFunc("/relay", server.relayHandler).Methods("POST")
+	r.HandleFunc("/space/{address}", server.spaceHandler).Methods("GET")
+	r.HandleFunc("/call", server.callHandler).Methods("POST")
+	r.HandleFunc("/simulate/batch", server.simulateBatchHandler).Methods("POST")
+	r.HandleFunc("/stats", server.statsHandler).Methods("GET")
+	r.HandleFunc("/tx/{hash}", server.txHandler).Methods("GET")
+	if config.MerkleBatch {
+		r.HandleFunc("/relay/merkle", server.merkleBatchHandler).Methods("POST")
+	}
+	r.Handle("/metrics", server.metrics).Methods("GET")
+	r.HandleFunc("/admin/blocklist", server.requireAdmin(server.blocklistHandler)).Methods("GET")
+	r.HandleFunc("/admin/blocklist/{address}", server.requireAdmin(server.unblockHandler)).Methods("DELETE")
+	r.HandleFunc("/admin/rotate-key", server.requireAdmin(server.rotateKeyHandler)).Methods("POST")
+	r.HandleFunc("/admin/reconcile", server.requireAdmin(server.reconcileHandler)).Methods("POST")
+	r.HandleFunc("/admin/maintenance", server.requireAdmin(server.maintenanceHandler)).Methods("POST")
+	r.HandleFunc("/admin/address/{address}", server.requireAdmin(server.addressHandler)).Methods("GET")
+	if config.EnablePprof {
+		server.registerPprof(r)
+	}
+	if len(config.Networks) > 0 {
+		r.HandleFunc("/relay/{network}", server.relayHandler).Methods("POST")
+	}
+	r.Use(requestIDMiddleware, server.recoverMiddleware, prettyMiddleware)
+	if config.SignResponses {
+		r.Use(server.signResponsesMiddleware)
+		log.Printf("🔏 Signing responses as %s in %s\n", server.relayer().Hex(), responseSignatureHeader)
+	}
 
 	// CORS configuration
-	c := cors.New(cors.Options{
-		AllowedOrigins: []string{"*"},
-		AllowedMethods: []string{"GET", "POST", "OPTIONS"},
-		AllowedHeaders: []string{"*"},
-	})
-
-	handler := c.Handler(r)
+	handler := corsHandler(config, r)
+	if config.CompressResponses {
+		handler = server.compressMiddleware(handler)
+	}
+	if config.RequireHTTPS {
+		handler = server.requireHTTPSMiddleware(handler)
+	}
 
-	// Start cleanup routine
-	go server.cleanupRoutine()
+	// Start cleanup, receipt and reorg workers for every network
+	server.startBackground()
 
 	// HTTP server with graceful shutdown
 	srv := &http.Server{
-		Addr:    ":" + config.Port,
-		Handler: handler,
+		Addr:              ":" + config.Port,
+		Handler:           handler,
+		ReadTimeout:       config.ReadTimeout,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		WriteTimeout:      config.WriteTimeout,
+		IdleTimeout:       config.IdleTimeout,
 	}
 
 	// Start server in goroutine
 	go func() {
 		log.Printf("\n🎃 Halloween NFT Relayer Server running on port %s\n", config.Port)
 		log.Printf("📝 POST /relay - Submit meta-transaction\n")
+		if len(config.Networks) > 0 {
+			log.Printf("🌐 POST /relay/{network} - Submit on a named network (%s)\n", strings.Join(append([]string{config.NetworkName}, config.Networks...), ", "))
+		}
+		log.Printf("📖 POST /call - Allowlisted read-only contract call\n")
+		log.Printf("🧪 POST /simulate/batch - Dry-run a batch of forwards\n")
+		log.Printf("🧭 GET  /space/{address} - Suggest an idle nonce space\n")
+		log.Printf("🔎 GET  /tx/{hash} - Look up a relayed transaction\n")
+		log.Printf("📊 GET  /metrics - Prometheus metrics\n")
 		log.Printf("💚 GET  /health - Health check\n\n")
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+
+		var err error
+		if config.TLSCertFile != "" {
+			log.Println("🔒 Serving HTTPS")
+			err = srv.ListenAndServeTLS(config.TLSCertFile, config.TLSKeyFile)
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Server error: %v", err)
 		}
 	}()
@@ -262,13 +653,18 @@ func main() {
 	<-quit
 
 	log.Println("\n👋 Shutting down relayer server...")
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatal("Server forced to shutdown:", err)
 	}
 
+	// Let background relays finish, then flush state
+	if err := server.Close(ctx); err != nil {
+		log.Printf("⚠️  Shutdown incomplete: %v\n", err)
+	}
+
 	log.Println("Server exited")
 }
 
@@ -280,8 +676,16 @@ func loadConfig() (Config, error) {
 		return Config{}, fmt.Errorf("RPC_URL is required")
 	}
 
+	// With a remote signer the private key stays in clef/web3signer and only
+	// the signing account address is configured here
+	remoteSignerURL := os.Getenv("REMOTE_SIGNER_URL")
+	remoteSignerAddr := os.Getenv("REMOTE_SIGNER_ADDRESS")
+	if remoteSignerURL != "" && !common.IsHexAddress(remoteSignerAddr) {
+		return Config{}, fmt.Errorf("REMOTE_SIGNER_ADDRESS is required with REMOTE_SIGNER_URL")
+	}
+
 	relayerKey := os.Getenv("RELAYER_PRIVATE_KEY")
-	if relayerKey == "" {
+	if relayerKey == "" && remoteSignerURL == "" {
 		return Config{}, fmt.Errorf("RELAYER_PRIVATE_KEY is required")
 	}
 
@@ -303,469 +707,2205 @@ func loadConfig() (Config, error) {
 
 	maxGasPrice := new(big.Int).Mul(big.NewInt(100), big.NewInt(1e9)) // 100 gwei
 
-	return Config{
-		Port:              port,
-		RPCURL:            rpcURL,
-		RelayerPrivateKey: relayerKey,
-		HubAddress:        common.HexToAddress(hubAddr),
-		NFTContract:       common.HexToAddress(nftAddr),
-		ChainID:           chainID,
-		MaxGasPrice:       maxGasPrice,
-	}, nil
-}
-
-// NewServer creates a new relayer server
-func NewServer(config Config) (*Server, error) {
-	// Connect to Ethereum client
-	client, err := ethclient.Dial(config.RPCURL)
+	minGasPrice, err := parseGwei(getEnv("MIN_GAS_PRICE_GWEI", "0"))
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to Ethereum client: %v", err)
+		return Config{}, fmt.Errorf("invalid MIN_GAS_PRICE_GWEI: %v", err)
+	}
+	if minGasPrice.Cmp(maxGasPrice) > 0 {
+		return Config{}, fmt.Errorf("MIN_GAS_PRICE_GWEI exceeds the max gas price")
 	}
 
-	// Load relayer private key
-	privateKey, err := crypto.HexToECDSA(strings.TrimPrefix(config.RelayerPrivateKey, "0x"))
+	nonceSpaces, err := getEnvInt("NONCE_SPACES", 8)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load private key: %v", err)
+		return Config{}, err
+	}
+	if nonceSpaces < 1 {
+		return Config{}, fmt.Errorf("NONCE_SPACES must be at least 1")
 	}
 
-	relayerAddress := crypto.PubkeyToAddress(privateKey.PublicKey)
+	revertThreshold, err := getEnvInt("REVERT_THRESHOLD", 3)
+	if err != nil {
+		return Config{}, err
+	}
 
-	log.Println("🚀 Starting Relayer Server...")
-	log.Printf("📍 Relayer Address: %s\n", relayerAddress.Hex())
-	log.Printf("🌐 Network: %s\n", config.ChainID.String())
-	log.Printf("📜 Hub Contract: %s\n", config.HubAddress.Hex())
-	log.Printf("🎃 NFT Contract: %s\n", config.NFTContract.Hex())
+	revertWindow, err := getEnvDuration("REVERT_WINDOW", 10*time.Minute)
+	if err != nil {
+		return Config{}, err
+	}
 
-	return &Server{
-		config:            config,
-		client:            client,
-		relayerKey:        privateKey,
-		relayerAddress:    relayerAddress,
-		processedRequests: make(map[string]time.Time),
-		rateLimit:         &RateLimit{requests: make(map[string][]int64)},
-	}, nil
-}
+	revertBlockTime, err := getEnvDuration("REVERT_BLOCK_DURATION", 1*time.Hour)
+	if err != nil {
+		return Config{}, err
+	}
 
-// healthHandler handles health check requests
-func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
-	response := HealthResponse{
-		Status:    "ok",
-		Relayer:   s.relayerAddress.Hex(),
-		Timestamp: time.Now().Unix(),
+	// SUCCESS_EVENT accepts a topic hash or an event signature such as
+	// "Transfer(address,address,uint256)"
+	var successEventTopic common.Hash
+	if successEvent := os.Getenv("SUCCESS_EVENT"); successEvent != "" {
+		if strings.HasPrefix(successEvent, "0x") && len(successEvent) == 66 {
+			successEventTopic = common.HexToHash(successEvent)
+		} else {
+			successEventTopic = crypto.Keccak256Hash([]byte(successEvent))
+		}
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-}
+	retry, err := loadRetryPolicy()
+	if err != nil {
+		return Config{}, err
+	}
 
-// relayHandler handles relay requests
-func (s *Server) relayHandler(w http.ResponseWriter, r *http.Request) {
-	log.Println("\n=== 🔍 NEW RELAY REQUEST ===")
-	log.Printf("Method: %s\n", r.Method)
-	log.Printf("Content-Type: %s\n", r.Header.Get("Content-Type"))
-	log.Printf("Content-Length: %d\n", r.ContentLength)
+	hubs, err := loadHubs(hubAddr, os.Getenv("HUB_VERSIONS"), os.Getenv("HUB_ABI_FILES"))
+	if err != nil {
+		return Config{}, err
+	}
 
-	var req RelayRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.Printf("❌ JSON Decode Error: %v\n", err)
-		s.sendError(w, http.StatusBadRequest, "Invalid request body", err.Error())
-		return
+	receiptPoll, err := getEnvDuration("RECEIPT_POLL_INTERVAL", 2*time.Second)
+	if err != nil {
+		return Config{}, err
 	}
 
-	log.Println("✅ Request body decoded successfully")
-	log.Printf("Signature present: %v (length: %d)\n", req.Signature != "", len(req.Signature))
-	log.Printf("CallData present: %v (length: %d)\n", req.CallData != "", len(req.CallData))
+	corsOrigins := splitList(getEnv("CORS_ALLOWED_ORIGINS", "*"))
+	adminCORSOrigins := corsOrigins
+	if origins := os.Getenv("ADMIN_CORS_ALLOWED_ORIGINS"); origins != "" {
+		adminCORSOrigins = splitList(origins)
+	}
 
-	// Log the entire forward struct for debugging
-	log.Println("📦 Forward struct:")
-	log.Printf("  From: %s\n", req.Forward.From.Hex())
-	log.Printf("  To: %s\n", req.Forward.To.Hex())
-	log.Printf("  Value: %s\n", req.Forward.Value.String())
-	log.Printf("  Space: %d\n", req.Forward.Space)
-	log.Printf("  Nonce: %s\n", req.Forward.Nonce.String())
-	log.Printf("  Deadline: %s\n", req.Forward.Deadline.String())
-	log.Printf("  DataHash: 0x%s\n", hex.EncodeToString(req.Forward.DataHash[:]))
-	log.Printf("  Caller: %s\n", req.Forward.Caller.Hex())
+	corsMaxAge, err := getEnvDuration("CORS_MAX_AGE", 0)
+	if err != nil {
+		return Config{}, err
+	}
 
-	// Validate required fields
-	if req.Signature == "" || req.CallData == "" {
-		log.Println("❌ Validation failed: Missing signature or callData")
-		s.sendError(w, http.StatusBadRequest, "Missing required fields: forward, signature, callData", "")
-		return
+	balanceCacheTTL, err := getEnvDuration("BALANCE_CACHE_TTL", 30*time.Second)
+	if err != nil {
+		return Config{}, err
 	}
 
-	log.Println("✅ Required fields validation passed")
+	// DEADLINE_CLOCK selects what deadlines are validated against: the local
+	// wall clock, the latest block timestamp the contract will see, or both,
+	// where a request is only rejected when both clocks agree it has expired
+	deadlineClock := getEnv("DEADLINE_CLOCK", "wall")
+	if deadlineClock != "wall" && deadlineClock != "block" && deadlineClock != "both" {
+		return Config{}, fmt.Errorf("DEADLINE_CLOCK must be \"wall\", \"block\" or \"both\"")
+	}
 
-	userAddress := req.Forward.From
-	log.Printf("\n📨 Processing mint request from: %s\n", userAddress.Hex())
-	log.Printf("🔢 Nonce: %s\n", req.Forward.Nonce.String())
-	log.Printf("📦 Space: %d\n", req.Forward.Space)
-	deadlineTime := time.Unix(req.Forward.Deadline.Int64(), 0)
-	log.Printf("⏰ Deadline: %s (timestamp: %s)\n", deadlineTime.Format(time.RFC3339), req.Forward.Deadline.String())
+	blockTimeCacheTTL, err := getEnvDuration("BLOCK_TIME_CACHE_TTL", 2*time.Second)
+	if err != nil {
+		return Config{}, err
+	}
 
-	// Rate limiting
-	log.Println("🔍 Checking rate limit...")
-	if !s.checkRateLimit(userAddress.Hex()) {
-		log.Printf("❌ Rate limit exceeded for: %s\n", userAddress.Hex())
-		s.sendError(w, http.StatusTooManyRequests, "Too many requests. Please try again later.", "")
-		return
+	gasRetryAfter, err := getEnvDuration("GAS_RETRY_AFTER", 60*time.Second)
+	if err != nil {
+		return Config{}, err
 	}
-	log.Println("✅ Rate limit check passed")
 
-	// Check for duplicate requests
-	requestID := fmt.Sprintf("%s-%s", userAddress.Hex(), req.Forward.Nonce.String())
-	log.Printf("🔍 Checking for duplicate request: %s\n", requestID)
-	if s.isProcessed(requestID) {
-		log.Printf("❌ Duplicate request detected: %s\n", requestID)
-		s.sendError(w, http.StatusBadRequest, "This request has already been processed", "")
-		return
+	webhookTimeout, err := getEnvDuration("WEBHOOK_TIMEOUT", 5*time.Second)
+	if err != nil {
+		return Config{}, err
 	}
-	log.Println("✅ Duplicate check passed")
 
-	// Verify target contract
-	log.Printf("🔍 Verifying target contract...\n")
-	log.Printf("   Expected: %s\n", s.config.NFTContract.Hex())
-	log.Printf("   Received: %s\n", req.Forward.To.Hex())
-	if !bytes32Equal(req.Forward.To, s.config.NFTContract) {
-		log.Printf("❌ Invalid target contract: %s\n", req.Forward.To.Hex())
-		s.sendError(w, http.StatusBadRequest, "Invalid target contract", "")
-		return
+	// WEBHOOK_CONFIRMATIONS delays relay.succeeded until the transaction is
+	// that many blocks deep; 1 sends it as soon as it is mined
+	webhookConfirmations, err := getEnvInt("WEBHOOK_CONFIRMATIONS", 1)
+	if err != nil {
+		return Config{}, err
+	}
+	if webhookConfirmations < 1 {
+		return Config{}, fmt.Errorf("WEBHOOK_CONFIRMATIONS must be at least 1")
 	}
-	log.Println("✅ Target contract verification passed")
 
-	// Verify caller
-	log.Printf("🔍 Verifying caller address...\n")
-	log.Printf("   Expected: %s\n", s.relayerAddress.Hex())
-	log.Printf("   Received: %s\n", req.Forward.Caller.Hex())
-	if !bytes32Equal(req.Forward.Caller, s.relayerAddress) {
-		log.Printf("❌ Caller mismatch. Expected: %s, Got: %s\n", s.relayerAddress.Hex(), req.Forward.Caller.Hex())
-		s.sendError(w, http.StatusBadRequest, "Invalid caller address", "")
-		return
+	breakerThreshold, err := getEnvInt("BREAKER_THRESHOLD", 5)
+	if err != nil {
+		return Config{}, err
 	}
-	log.Println("✅ Caller verification passed")
 
-	// Verify dataHash
-	log.Println("🔍 Verifying dataHash...")
-	log.Printf("   CallData: %s\n", req.CallData)
-	callDataBytes, err := hex.DecodeString(strings.TrimPrefix(req.CallData, "0x"))
+	breakerWindow, err := getEnvDuration("BREAKER_WINDOW", 5*time.Minute)
 	if err != nil {
-		log.Printf("❌ Invalid callData format: %v\n", err)
-		s.sendError(w, http.StatusBadRequest, "Invalid callData format", err.Error())
-		return
+		return Config{}, err
 	}
-	log.Printf("   CallData bytes length: %d\n", len(callDataBytes))
 
-	computedHash := crypto.Keccak256Hash(callDataBytes)
-	receivedHash := common.BytesToHash(req.Forward.DataHash[:])
-	log.Printf("   Computed hash: %s\n", computedHash.Hex())
-	log.Printf("   Received hash: %s\n", receivedHash.Hex())
+	breakerCooldown, err := getEnvDuration("BREAKER_COOLDOWN", 1*time.Minute)
+	if err != nil {
+		return Config{}, err
+	}
 
-	if computedHash != receivedHash {
-		log.Println("❌ DataHash mismatch!")
-		log.Printf("   Computed: %s\n", computedHash.Hex())
-		log.Printf("   Received: %s\n", receivedHash.Hex())
-		s.sendError(w, http.StatusBadRequest, "DataHash mismatch - signature invalid", "")
-		return
+	maxBatchSize, err := getEnvInt("MAX_BATCH_SIZE", 20)
+	if err != nil {
+		return Config{}, err
+	}
+	if maxBatchSize < 1 {
+		return Config{}, fmt.Errorf("MAX_BATCH_SIZE must be at least 1")
 	}
-	log.Println("✅ DataHash verification passed")
 
-	// Check deadline
-	now := time.Now().Unix()
-	log.Printf("🔍 Checking deadline...\n")
-	log.Printf("   Current time: %d (%s)\n", now, time.Unix(now, 0).Format(time.RFC3339))
-	log.Printf("   Deadline: %d (%s)\n", req.Forward.Deadline.Int64(), deadlineTime.Format(time.RFC3339))
-	log.Printf("   Time remaining: %d seconds\n", req.Forward.Deadline.Int64()-now)
-
-	if now > req.Forward.Deadline.Int64() {
-		log.Println("❌ Transaction deadline expired")
-		s.sendError(w, http.StatusBadRequest, "Transaction deadline expired", "")
-		return
+	reorgWindow, err := getEnvDuration("REORG_WINDOW", 10*time.Minute)
+	if err != nil {
+		return Config{}, err
 	}
-	log.Println("✅ Deadline check passed")
 
-	// Check if user already minted
-	log.Println("🔍 Checking if user already minted...")
-	hasMinted, err := s.checkAlreadyMinted(userAddress)
+	reorgCheckInterval, err := getEnvDuration("REORG_CHECK_INTERVAL", 30*time.Second)
 	if err != nil {
-		log.Printf("❌ Error checking minted status: %v\n", err)
-		s.sendError(w, http.StatusInternalServerError, "Failed to verify minting status", err.Error())
-		return
+		return Config{}, err
 	}
 
-	if hasMinted {
-		log.Printf("❌ User already minted: %s\n", userAddress.Hex())
-		s.sendError(w, http.StatusBadRequest, "You already minted an NFT", "")
-		return
+	gasFallbackLimit, err := getEnvInt("GAS_FALLBACK_LIMIT", 500000)
+	if err != nil {
+		return Config{}, err
+	}
+	if gasFallbackLimit <= 0 {
+		return Config{}, fmt.Errorf("GAS_FALLBACK_LIMIT must be positive")
 	}
-	log.Println("✅ User has not minted yet")
 
-	// Check gas price
-	log.Println("🔍 Checking gas price...")
-	gasPrice, err := s.client.SuggestGasPrice(context.Background())
+	gasAverageWindow, err := getEnvInt("GAS_AVERAGE_WINDOW", 50)
 	if err != nil {
-		log.Printf("⚠️  Error getting gas price: %v\n", err)
-	} else {
-		gasPriceGwei := new(big.Int).Div(gasPrice, big.NewInt(1e9))
-		maxGasPriceGwei := new(big.Int).Div(s.config.MaxGasPrice, big.NewInt(1e9))
-		log.Printf("   Current gas price: %s gwei\n", gasPriceGwei.String())
-		log.Printf("   Max gas price: %s gwei\n", maxGasPriceGwei.String())
+		return Config{}, err
+	}
+	if gasAverageWindow < 1 {
+		return Config{}, fmt.Errorf("GAS_AVERAGE_WINDOW must be at least 1")
+	}
 
-		if gasPrice.Cmp(s.config.MaxGasPrice) > 0 {
-			log.Printf("❌ Gas price too high: %s gwei\n", gasPriceGwei.String())
-			s.sendError(w, http.StatusServiceUnavailable, "Network gas prices too high. Please try again later.", "")
-			return
-		}
+	gasAverageMinSamples, err := getEnvInt("GAS_AVERAGE_MIN_SAMPLES", 5)
+	if err != nil {
+		return Config{}, err
+	}
+	if gasAverageMinSamples > gasAverageWindow {
+		return Config{}, fmt.Errorf("GAS_AVERAGE_MIN_SAMPLES must not exceed GAS_AVERAGE_WINDOW")
 	}
-	log.Println("✅ Gas price check passed")
 
-	log.Println("✅ All validations passed. Executing meta-transaction...")
+	// Percentage applied to the gasUsed average when estimation fails
+	gasFallbackBuffer, err := getEnvInt("GAS_FALLBACK_BUFFER", 150)
+	if err != nil {
+		return Config{}, err
+	}
+	if gasFallbackBuffer < 100 {
+		return Config{}, fmt.Errorf("GAS_FALLBACK_BUFFER must be at least 100 (percent)")
+	}
 
-	// Execute transaction
-	txHash, blockNumber, gasUsed, err := s.executeMetaTransaction(req)
+	trustedProxyHops, err := getEnvInt("TRUSTED_PROXY_HOPS", 1)
 	if err != nil {
-		log.Printf("❌ Error executing transaction: %v\n", err)
-		s.sendError(w, http.StatusInternalServerError, s.parseError(err), err.Error())
-		return
+		return Config{}, err
+	}
+	if trustedProxyHops < 1 {
+		return Config{}, fmt.Errorf("TRUSTED_PROXY_HOPS must be at least 1")
 	}
 
-	// Mark as processed
-	s.markProcessed(requestID)
+	dailyCap, err := getEnvInt("DAILY_CAP_PER_ADDRESS", 0)
+	if err != nil {
+		return Config{}, err
+	}
 
-	log.Printf("✅ Transaction confirmed in block: %d\n", blockNumber)
-	log.Printf("⛽ Gas used: %s\n", gasUsed.String())
+	// How long a minted() == false lookup is reused; true results never expire
+	mintedFalseTTL, err := getEnvDuration("MINTED_FALSE_TTL", 10*time.Second)
+	if err != nil {
+		return Config{}, err
+	}
+	// Per-contract overrides, e.g. a shorter TTL for a fast-selling drop
+	mintedFalseTTLs, err := parseMintedFalseTTLs(splitList(os.Getenv("MINTED_FALSE_TTLS")))
+	if err != nil {
+		return Config{}, err
+	}
 
-	// Send success response
-	response := RelayResponse{
-		Success:         true,
-		TxHash:          txHash,
-		TransactionHash: txHash,
-		BlockNumber:     blockNumber,
-		GasUsed:         gasUsed.String(),
+	maxBodyBytes, err := getEnvInt("MAX_BODY_BYTES", 64*1024)
+	if err != nil {
+		return Config{}, err
+	}
+	if maxBodyBytes < 1 {
+		return Config{}, fmt.Errorf("MAX_BODY_BYTES must be positive")
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
-}
+	tlsCertFile := os.Getenv("TLS_CERT_FILE")
+	tlsKeyFile := os.Getenv("TLS_KEY_FILE")
+	if (tlsCertFile == "") != (tlsKeyFile == "") {
+		return Config{}, fmt.Errorf("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
 
-// executeMetaTransaction executes the meta-transaction through the hub
-func (s *Server) executeMetaTransaction(req RelayRequest) (string, uint64, *big.Int, error) {
-	log.Println("📝 Preparing transaction data...")
+	selfTest := getEnvBool("SELFTEST", false)
+	selfTestFixture := os.Getenv("SELFTEST_FIXTURE")
+	if selfTest && selfTestFixture == "" {
+		return Config{}, fmt.Errorf("SELFTEST_FIXTURE is required with SELFTEST")
+	}
 
-	// Parse Hub ABI
-	parsedABI, err := abi.JSON(strings.NewReader(hubABI))
+	feeMode := getEnvBool("FEE_MODE", false)
+	feeTokens, err := parseFeeTokens(splitList(os.Getenv("FEE_TOKENS")))
 	if err != nil {
-		return "", 0, nil, fmt.Errorf("failed to parse Hub ABI: %v", err)
+		return Config{}, err
+	}
+	if feeMode && len(feeTokens) == 0 {
+		return Config{}, fmt.Errorf("FEE_TOKENS is required with FEE_MODE")
 	}
 
-	// Parse signature
-	sigBytes, err := hex.DecodeString(strings.TrimPrefix(req.Signature, "0x"))
+	compressMinBytes, err := getEnvInt("COMPRESS_MIN_BYTES", 1024)
 	if err != nil {
-		return "", 0, nil, fmt.Errorf("invalid signature format: %v", err)
+		return Config{}, err
 	}
-	log.Printf("   Signature length: %d bytes\n", len(sigBytes))
 
-	// Parse callData
-	callDataBytes, err := hex.DecodeString(strings.TrimPrefix(req.CallData, "0x"))
+	maxGlobalInflight, err := getEnvInt("MAX_GLOBAL_INFLIGHT", 0)
 	if err != nil {
-		return "", 0, nil, fmt.Errorf("invalid callData format: %v", err)
+		return Config{}, err
 	}
-	log.Printf("   CallData length: %d bytes\n", len(callDataBytes))
 
-	// Prepare the Forward tuple struct for ABI encoding
-	// The order MUST match the Hub contract's Forward struct
-	forwardTuple := struct {
-		From     common.Address
-		To       common.Address
-		Value    *big.Int
-		Space    uint32
-		Nonce    *big.Int
-		Deadline *big.Int
-		DataHash [32]byte
-		Caller   common.Address
-	}{
-		From:     req.Forward.From,
-		To:       req.Forward.To,
-		Value:    req.Forward.Value,
-		Space:    req.Forward.Space,
-		Nonce:    req.Forward.Nonce,
-		Deadline: req.Forward.Deadline,
-		DataHash: req.Forward.DataHash,
-		Caller:   req.Forward.Caller,
+	// REQUIRE_FROM_BALANCE is a minimum balance in ether for forward.from
+	var requireFromBalance *big.Int
+	if v := os.Getenv("REQUIRE_FROM_BALANCE"); v != "" {
+		requireFromBalance, err = parseEther(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid REQUIRE_FROM_BALANCE: %v", err)
+		}
 	}
 
-	log.Println("📦 Forward tuple prepared:")
-	log.Printf("   From: %s\n", forwardTuple.From.Hex())
-	log.Printf("   To: %s\n", forwardTuple.To.Hex())
-	log.Printf("   Value: %s\n", forwardTuple.Value.String())
-	log.Printf("   Space: %d\n", forwardTuple.Space)
-	log.Printf("   Nonce: %s\n", forwardTuple.Nonce.String())
-	log.Printf("   Deadline: %s\n", forwardTuple.Deadline.String())
-	log.Printf("   DataHash: 0x%s\n", hex.EncodeToString(forwardTuple.DataHash[:]))
-	log.Printf("   Caller: %s\n", forwardTuple.Caller.Hex())
-
-	// Pack the execute function call
-	data, err := parsedABI.Pack("execute", forwardTuple, callDataBytes, sigBytes)
+	activityCacheTTL, err := getEnvDuration("ACTIVITY_CACHE_TTL", 1*time.Minute)
 	if err != nil {
-		return "", 0, nil, fmt.Errorf("failed to pack execute: %v", err)
+		return Config{}, err
 	}
 
-	log.Printf("✅ Transaction data packed: %d bytes\n", len(data))
-	log.Printf("   Data (first 100 chars): 0x%s...\n", hex.EncodeToString(data[:min(50, len(data))]))
-
-	// Get nonce for relayer
-	nonce, err := s.client.PendingNonceAt(context.Background(), s.relayerAddress)
+	txRetention, err := getEnvDuration("TX_RETENTION", 1*time.Hour)
 	if err != nil {
-		return "", 0, nil, fmt.Errorf("failed to get nonce: %v", err)
+		return Config{}, err
 	}
-	log.Printf("   Relayer nonce: %d\n", nonce)
 
-	// Get gas price
-	gasPrice, err := s.client.SuggestGasPrice(context.Background())
+	readTimeout, err := getEnvDuration("READ_TIMEOUT", 15*time.Second)
 	if err != nil {
-		return "", 0, nil, fmt.Errorf("failed to get gas price: %v", err)
+		return Config{}, err
 	}
-	log.Printf("   Gas price: %s gwei\n", new(big.Int).Div(gasPrice, big.NewInt(1e9)).String())
 
-	// Estimate gas
-	estimatedGas, err := s.client.EstimateGas(context.Background(), ethereum.CallMsg{
-		From:     s.relayerAddress,
-		To:       &s.config.HubAddress,
-		Value:    big.NewInt(0),
-		Data:     data,
-		GasPrice: gasPrice,
-	})
+	readHeaderTimeout, err := getEnvDuration("READ_HEADER_TIMEOUT", 5*time.Second)
 	if err != nil {
-		log.Printf("⚠️  Failed to estimate gas: %v\n", err)
-		log.Println("   Using default gas limit: 500000")
-		estimatedGas = 500000
-	} else {
-		// Add 20% buffer to estimated gas
-		estimatedGas = estimatedGas * 120 / 100
-		log.Printf("   Estimated gas (with 20%% buffer): %d\n", estimatedGas)
+		return Config{}, err
 	}
 
-	// Create transaction
-	tx := types.NewTransaction(
-		nonce,
-		s.config.HubAddress,
-		big.NewInt(0),
-		estimatedGas,
-		gasPrice,
-		data,
-	)
-
-	log.Println("🔐 Signing transaction...")
-	// Sign transaction
-	signedTx, err := types.SignTx(tx, types.NewEIP155Signer(s.config.ChainID), s.relayerKey)
+	// /relay holds the response open until the receipt arrives, across every
+	// retry attempt, so WRITE_TIMEOUT must outlast the receipt wait or the
+	// client sees a dropped connection for a transaction that still mines
+	writeTimeout, err := getEnvDuration("WRITE_TIMEOUT", 0)
 	if err != nil {
-		return "", 0, nil, fmt.Errorf("failed to sign transaction: %v", err)
+		return Config{}, err
+	}
+	if writeTimeout == 0 {
+		writeTimeout = time.Duration(retry.MaxAttempts)*receiptTimeout + 30*time.Second
+	}
+	if writeTimeout <= receiptTimeout {
+		log.Printf("⚠️  WRITE_TIMEOUT (%s) does not exceed the receipt wait (%s); slow relays will be cut off\n", writeTimeout, receiptTimeout)
 	}
 
-	log.Println("📤 Sending transaction to network...")
-	// Send transaction
-	err = s.client.SendTransaction(context.Background(), signedTx)
+	idleTimeout, err := getEnvDuration("IDLE_TIMEOUT", 60*time.Second)
 	if err != nil {
-		return "", 0, nil, fmt.Errorf("failed to send transaction: %v", err)
+		return Config{}, err
 	}
 
-	log.Printf("📡 Transaction sent: %s\n", signedTx.Hash().Hex())
-	log.Println("⏳ Waiting for confirmation...")
-
-	// Wait for receipt
-	receipt, err := s.waitForReceipt(signedTx.Hash())
+	supplyCacheTTL, err := getEnvDuration("SUPPLY_CACHE_TTL", 5*time.Second)
 	if err != nil {
-		return "", 0, nil, fmt.Errorf("failed to get receipt: %v", err)
+		return Config{}, err
 	}
 
-	// Check if transaction was successful
-	if receipt.Status == 0 {
-		log.Printf("❌ Transaction reverted! Receipt status: %d\n", receipt.Status)
-		return "", 0, nil, fmt.Errorf("transaction reverted by contract")
+	// Bound the rate limiter's memory under a sybil flood. Once every tracked
+	// address is still inside its window, new addresses are either refused
+	// ("closed", the default) or let through unlimited ("open"). Closed keeps
+	// the per-address limit meaningful but lets a flood lock out new users
+	// until the window passes; open keeps the relayer available but lets the
+	// flood's addresses relay without any per-address limit.
+	maxRateLimitEntries, err := getEnvInt("MAX_RATE_LIMIT_ENTRIES", 100000)
+	if err != nil {
+		return Config{}, err
 	}
-
-	log.Printf("✅ Transaction successful! Status: %d\n", receipt.Status)
-
-	return signedTx.Hash().Hex(), receipt.BlockNumber.Uint64(), new(big.Int).SetUint64(receipt.GasUsed), nil
-}
-
-// min returns the minimum of two integers
-func min(a, b int) int {
-	if a < b {
-		return a
+	rateLimitFullPolicy := getEnv("RATE_LIMIT_FULL_POLICY", rateLimitFailClosed)
+	if rateLimitFullPolicy != rateLimitFailClosed && rateLimitFullPolicy != rateLimitFailOpen {
+		return Config{}, fmt.Errorf("RATE_LIMIT_FULL_POLICY must be %q or %q", rateLimitFailClosed, rateLimitFailOpen)
 	}
-	return b
-}
 
-// checkAlreadyMinted checks if user has already minted
-func (s *Server) checkAlreadyMinted(address common.Address) (bool, error) {
-	log.Printf("🔍 Checking minted status for: %s\n", address.Hex())
+	// DYNAMIC_FEES prices transactions as baseFee * BASE_FEE_MULTIPLIER +
+	// PRIORITY_FEE_GWEI, clamped by the max gas price
+	dynamicFees := getEnvBool("DYNAMIC_FEES", false)
+	useAccessList := getEnvBool("USE_ACCESS_LIST", false)
+	baseFeeMultiplier, err := strconv.ParseFloat(getEnv("BASE_FEE_MULTIPLIER", "2"), 64)
+	if err != nil || baseFeeMultiplier < 1 {
+		return Config{}, fmt.Errorf("invalid BASE_FEE_MULTIPLIER: must be a number >= 1")
+	}
+	priorityFee, err := parseGwei(getEnv("PRIORITY_FEE_GWEI", "1.5"))
+	if err != nil {
+		return Config{}, fmt.Errorf("invalid PRIORITY_FEE_GWEI: %v", err)
+	}
 
-	parsedABI, err := abi.JSON(strings.NewReader(nftABI))
+	// ALLOW_DEPLOY accepts forwards to the zero address (or DEPLOY_FACTORY)
+	// as contract deployments, bounded by their own size and gas limits
+	var deployFactory common.Address
+	if factory := os.Getenv("DEPLOY_FACTORY"); factory != "" {
+		if !common.IsHexAddress(factory) {
+			return Config{}, fmt.Errorf("invalid DEPLOY_FACTORY address")
+		}
+		deployFactory = common.HexToAddress(factory)
+	}
+	deployMaxBytes, err := getEnvInt("DEPLOY_MAX_BYTES", 49152)
+	if err != nil {
+		return Config{}, err
+	}
+	deployMaxGas, err := getEnvInt("DEPLOY_MAX_GAS", 5000000)
 	if err != nil {
-		log.Printf("❌ Error parsing ABI: %v\n", err)
-		return false, err
+		return Config{}, err
 	}
 
-	data, err := parsedABI.Pack("minted", address)
+	// After SOFT_TIMEOUT a broadcast relay answers 202 pending and the
+	// receipt is tracked in the background for /tx lookups (0 = wait)
+	softTimeout, err := getEnvDuration("SOFT_TIMEOUT", 0)
 	if err != nil {
-		log.Printf("❌ Error packing ABI data: %v\n", err)
-		return false, err
+		return Config{}, err
 	}
 
-	log.Printf("   Calling NFT contract at: %s\n", s.config.NFTContract.Hex())
-	log.Printf("   Call data: 0x%s\n", hex.EncodeToString(data))
+	// MAX_NONCE bounds forward.nonce; unset disables the check
+	var maxNonce *big.Int
+	if v := os.Getenv("MAX_NONCE"); v != "" {
+		n, ok := new(big.Int).SetString(v, 10)
+		if !ok || n.Sign() < 0 {
+			return Config{}, fmt.Errorf("invalid MAX_NONCE")
+		}
+		maxNonce = n
+	}
 
-	msg := ethereum.CallMsg{
-		To:   &s.config.NFTContract,
-		Data: data,
+	// EIP712_DOMAIN_SALT is only part of the domain when set
+	var domainSalt *common.Hash
+	if v := os.Getenv("EIP712_DOMAIN_SALT"); v != "" {
+		raw, err := decodeHex(v)
+		if err != nil || len(raw) != 32 {
+			return Config{}, fmt.Errorf("invalid EIP712_DOMAIN_SALT: must be 32 bytes of hex")
+		}
+		salt := common.BytesToHash(raw)
+		domainSalt = &salt
 	}
 
-	result, err := s.client.CallContract(context.Background(), msg, nil)
+	// MAX_RELAY_DURATION is a hard cap on how long a relay holds its slot
+	// before the handler gives up on it (0 = no cap)
+	maxRelayDuration, err := getEnvDuration("MAX_RELAY_DURATION", 0)
 	if err != nil {
-		log.Printf("❌ Error calling contract: %v\n", err)
-		return false, err
+		return Config{}, err
 	}
 
-	log.Printf("   Contract response: 0x%s\n", hex.EncodeToString(result))
+	spaceAssignment := os.Getenv("SPACE_ASSIGNMENT")
+	if spaceAssignment != "" && spaceAssignment != spaceRoundRobin && spaceAssignment != spaceLeastLoaded {
+		return Config{}, fmt.Errorf("SPACE_ASSIGNMENT must be %q or %q", spaceRoundRobin, spaceLeastLoaded)
+	}
+
+	// SIGNER_TYPE=homestead signs pre-EIP-155 for old local devnets. Such
+	// transactions carry no chain ID and can be replayed on any chain.
+	signerType := getEnv("SIGNER_TYPE", signerEIP155)
+	switch signerType {
+	case signerEIP155:
+	case signerHomestead:
+		if remoteSignerURL != "" {
+			return Config{}, fmt.Errorf("SIGNER_TYPE=homestead requires a local private key")
+		}
+		if useAccessList || dynamicFees {
+			return Config{}, fmt.Errorf("SIGNER_TYPE=homestead supports legacy transactions only; disable USE_ACCESS_LIST and DYNAMIC_FEES")
+		}
+		if chainID.Cmp(big.NewInt(1)) == 0 {
+			return Config{}, fmt.Errorf("SIGNER_TYPE=homestead is not allowed on mainnet")
+		}
+		log.Printf("⚠️  SIGNER_TYPE=homestead: transactions lack replay protection and are valid on any chain. Use only on local devnets.\n")
+	default:
+		return Config{}, fmt.Errorf("SIGNER_TYPE must be %q or %q", signerEIP155, signerHomestead)
+	}
+
+	// FIRST_REQUEST_BONUS extra requests are allowed in the window opened by
+	// an address the rate limiter isn't currently tracking, so a retry storm
+	// on a first mint doesn't trip the limit (0 = standard limit only)
+	firstRequestBonus, err := getEnvInt("FIRST_REQUEST_BONUS", 0)
+	if err != nil {
+		return Config{}, err
+	}
+
+	// PERSIST_MAINTENANCE keeps the maintenance toggle across restarts
+	var maintenanceFile string
+	if getEnvBool("PERSIST_MAINTENANCE", false) {
+		maintenanceFile = getEnv("MAINTENANCE_FILE", "maintenance.json")
+	}
+
+	// MULTICALL_ADDRESS batches validation reads through a Multicall3 contract
+	var multicallAddress common.Address
+	if v := os.Getenv("MULTICALL_ADDRESS"); v != "" {
+		if !common.IsHexAddress(v) {
+			return Config{}, fmt.Errorf("invalid MULTICALL_ADDRESS")
+		}
+		multicallAddress = common.HexToAddress(v)
+	}
+
+	// ALLOW_VALUE lets forwards carry a non-zero value, paid by the relayer;
+	// ALLOWED_VALUES restricts it to exact amounts such as the mint price
+	allowValue := getEnvBool("ALLOW_VALUE", false)
+	allowedValues, err := parseAllowedValues(splitList(os.Getenv("ALLOWED_VALUES")))
+	if err != nil {
+		return Config{}, err
+	}
+
+	// SHUTDOWN_TIMEOUT bounds the whole shutdown, including waiting for
+	// in-flight relays
+	shutdownTimeout, err := getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second)
+	if err != nil {
+		return Config{}, err
+	}
+
+	// MAX_RECEIPT_WATCHERS bounds how many transactions are watched for a
+	// receipt at once; excess ones queue before their receipt timeout
+	// starts. 0 disables the limit.
+	maxReceiptWatchers, err := getEnvInt("MAX_RECEIPT_WATCHERS", 256)
+	if err != nil {
+		return Config{}, err
+	}
+	if maxReceiptWatchers < 0 {
+		return Config{}, fmt.Errorf("MAX_RECEIPT_WATCHERS must not be negative")
+	}
+
+	// IP_BLOCKLIST rejects relays from these CIDR ranges
+	ipBlocklist, err := parseCIDRs(splitList(os.Getenv("IP_BLOCKLIST")))
+	if err != nil {
+		return Config{}, err
+	}
+
+	// SERVER_CONSTRUCTS_CALLDATA fills in mint(DEFAULT_TOKEN_URI) when a
+	// request omits callData, for drops where every token shares metadata
+	serverCallData := getEnvBool("SERVER_CONSTRUCTS_CALLDATA", false)
+	defaultTokenURI := os.Getenv("DEFAULT_TOKEN_URI")
+	if serverCallData && defaultTokenURI == "" {
+		return Config{}, fmt.Errorf("DEFAULT_TOKEN_URI is required with SERVER_CONSTRUCTS_CALLDATA")
+	}
+
+	// ALLOW_1271 accepts EIP-1271 contract wallet signatures. Only
+	// VERIFY_SIGNATURE checks signatures, so it requires it.
+	verifySignature := getEnvBool("VERIFY_SIGNATURE", false)
+	allow1271 := getEnvBool("ALLOW_1271", false)
+	if allow1271 && !verifySignature {
+		return Config{}, fmt.Errorf("ALLOW_1271 requires VERIFY_SIGNATURE")
+	}
+
+	// DAILY_GAS_BUDGET_WEI caps each relayer key's gas spend per rolling
+	// 24h; unset disables the cap
+	var dailyGasBudget *big.Int
+	if v := os.Getenv("DAILY_GAS_BUDGET_WEI"); v != "" {
+		n, ok := new(big.Int).SetString(v, 10)
+		if !ok || n.Sign() <= 0 {
+			return Config{}, fmt.Errorf("invalid DAILY_GAS_BUDGET_WEI")
+		}
+		dailyGasBudget = n
+	}
+
+	// CHAIN_ID_CHECK_INTERVAL is how often the RPC endpoint's chain ID is
+	// compared with CHAIN_ID; 0 disables the background check
+	chainIDCheckInterval, err := getEnvDuration("CHAIN_ID_CHECK_INTERVAL", time.Minute)
+	if err != nil {
+		return Config{}, err
+	}
+
+	// MAX_REQUEST_COST_WEI caps the estimated cost of a single relay; unset
+	// disables the cap
+	var maxRequestCost *big.Int
+	if v := os.Getenv("MAX_REQUEST_COST_WEI"); v != "" {
+		n, ok := new(big.Int).SetString(v, 10)
+		if !ok || n.Sign() <= 0 {
+			return Config{}, fmt.Errorf("invalid MAX_REQUEST_COST_WEI")
+		}
+		maxRequestCost = n
+	}
+
+	// EXPECTED_CALLER is the forward.caller to accept when an intermediate
+	// contract, not the relayer key, calls the hub; unset means the relayer.
+	// Relay transactions are then sent to the intermediate, which forwards
+	// them to one hub, so it can't serve several.
+	var expectedCaller common.Address
+	if v := os.Getenv("EXPECTED_CALLER"); v != "" {
+		if !common.IsHexAddress(v) {
+			return Config{}, fmt.Errorf("invalid EXPECTED_CALLER")
+		}
+		if len(hubs) > 1 {
+			return Config{}, fmt.Errorf("EXPECTED_CALLER forwards to a single hub but HUB_ADDRESS lists %d", len(hubs))
+		}
+		expectedCaller = common.HexToAddress(v)
+	}
+
+	// CLEANUP_JITTER adds up to this much random delay to each cleanup run
+	cleanupJitter, err := getEnvDuration("CLEANUP_JITTER", 0)
+	if err != nil {
+		return Config{}, err
+	}
+
+	// Responses are signed with the relayer key, which a remote signer
+	// doesn't expose for arbitrary messages
+	signResponses := getEnvBool("SIGN_RESPONSES", false)
+	if signResponses && remoteSignerURL != "" {
+		return Config{}, fmt.Errorf("SIGN_RESPONSES requires RELAYER_PRIVATE_KEY, not a remote signer")
+	}
+
+	// Above GAS_SOFT_LIMIT_GWEI a growing share of relays is shed, reaching
+	// all of them at the max gas price; GAS_SHED_EXPONENT shapes the curve
+	var gasSoftLimit *big.Int
+	if v := os.Getenv("GAS_SOFT_LIMIT_GWEI"); v != "" {
+		gasSoftLimit, err = parseGwei(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid GAS_SOFT_LIMIT_GWEI: %v", err)
+		}
+		if gasSoftLimit.Cmp(maxGasPrice) >= 0 {
+			return Config{}, fmt.Errorf("GAS_SOFT_LIMIT_GWEI must be below the max gas price")
+		}
+	}
+	gasShedExponent, err := strconv.ParseFloat(getEnv("GAS_SHED_EXPONENT", "1"), 64)
+	if err != nil || gasShedExponent <= 0 {
+		return Config{}, fmt.Errorf("invalid GAS_SHED_EXPONENT: must be a number > 0")
+	}
+
+	merkleBatch := getEnvBool("MERKLE_BATCH", false)
+	merkleMethod := getEnv("MERKLE_EXECUTE_METHOD", "executeWithProof")
+	if merkleBatch {
+		if err := checkMerkleSupport(hubs, merkleMethod); err != nil {
+			return Config{}, err
+		}
+	}
+
+	return Config{
+		Port:                  port,
+		RPCURL:                rpcURL,
+		RelayerPrivateKey:     relayerKey,
+		RemoteSignerURL:       remoteSignerURL,
+		RemoteSignerType:      getEnv("REMOTE_SIGNER_TYPE", "web3signer"),
+		RemoteSignerAddr:      common.HexToAddress(remoteSignerAddr),
+		HubAddress:            hubs[0].Address,
+		Hubs:                  hubs,
+		NFTContract:           common.HexToAddress(nftAddr),
+		ChainID:               chainID,
+		MaxGasPrice:           maxGasPrice,
+		MinGasPrice:           minGasPrice,
+		NonceSpaces:           nonceSpaces,
+		AdminAPIKey:           os.Getenv("ADMIN_API_KEY"),
+		RevertThreshold:       revertThreshold,
+		RevertWindow:          revertWindow,
+		RevertBlockTime:       revertBlockTime,
+		UseAccessList:         useAccessList,
+		SuccessEventTopic:     successEventTopic,
+		Retry:                 retry,
+		ReceiptBatching:       getEnvBool("RECEIPT_BATCHING", false),
+		ReceiptPoll:           receiptPoll,
+		CORSOrigins:           corsOrigins,
+		AdminCORSOrigins:      adminCORSOrigins,
+		CORSMaxAge:            corsMaxAge,
+		BalanceCacheTTL:       balanceCacheTTL,
+		DeadlineClock:         deadlineClock,
+		BlockTimeCacheTTL:     blockTimeCacheTTL,
+		CallAllowedMethods:    splitList(getEnv("CALL_ALLOWED_METHODS", "minted,isNonceUsed,isCallerAllowed")),
+		GasRetryAfter:         gasRetryAfter,
+		WebhookURL:            os.Getenv("WEBHOOK_URL"),
+		WebhookSecret:         os.Getenv("WEBHOOK_SECRET"),
+		WebhookTimeout:        webhookTimeout,
+		TestMode:              getEnvBool("TEST_MODE", false),
+		LogGasEconomics:       getEnvBool("LOG_GAS_ECONOMICS", true),
+		PriceFeedURL:          os.Getenv("PRICE_FEED_URL"),
+		PriceFeedField:        getEnv("PRICE_FEED_FIELD", "usd"),
+		BreakerThreshold:      breakerThreshold,
+		BreakerWindow:         breakerWindow,
+		BreakerCooldown:       breakerCooldown,
+		MerkleBatch:           merkleBatch,
+		MerkleExecuteMethod:   merkleMethod,
+		MaxBatchSize:          maxBatchSize,
+		ReorgMonitor:          getEnvBool("REORG_MONITOR", false),
+		ReorgWindow:           reorgWindow,
+		ReorgCheckInterval:    reorgCheckInterval,
+		AllowAnyCaller:        getEnvBool("ALLOW_ANY_CALLER", false),
+		GasFallbackLimit:      uint64(gasFallbackLimit),
+		GasAverageWindow:      gasAverageWindow,
+		GasAverageMinSamples:  gasAverageMinSamples,
+		GasFallbackBuffer:     gasFallbackBuffer,
+		TrustProxy:            getEnvBool("TRUST_PROXY", false),
+		TrustedProxyHops:      trustedProxyHops,
+		DailyCapPerAddress:    dailyCap,
+		NetworkName:           getEnv("NETWORK_NAME", "default"),
+		Networks:              splitList(os.Getenv("NETWORKS")),
+		MintedFalseTTL:        mintedFalseTTL,
+		MintedFalseTTLs:       mintedFalseTTLs,
+		MaxBodyBytes:          int64(maxBodyBytes),
+		RequireHTTPS:          getEnvBool("REQUIRE_HTTPS", false),
+		HTTPSRedirect:         getEnvBool("HTTPS_REDIRECT", false),
+		TLSCertFile:           tlsCertFile,
+		TLSKeyFile:            tlsKeyFile,
+		DedupeDataHash:        getEnvBool("DEDUPE_DATAHASH", false),
+		SelfTest:              selfTest,
+		SelfTestFixture:       selfTestFixture,
+		SelfTestStrict:        getEnvBool("SELFTEST_STRICT", false),
+		FeeMode:               feeMode,
+		FeeTokens:             feeTokens,
+		FeeExecuteMethod:      getEnv("FEE_EXECUTE_METHOD", "executeWithFee"),
+		FeeRequired:           getEnvBool("FEE_REQUIRED", false),
+		CompressResponses:     getEnvBool("RESPONSE_COMPRESSION", false),
+		CompressMinBytes:      compressMinBytes,
+		KeystoreDir:           os.Getenv("KEYSTORE_DIR"),
+		MaxGlobalInflight:     maxGlobalInflight,
+		RequireFromNonce:      getEnvBool("REQUIRE_FROM_NONCE", false),
+		RequireFromBalance:    requireFromBalance,
+		ActivityCacheTTL:      activityCacheTTL,
+		TxStoreFile:           os.Getenv("TX_STORE_FILE"),
+		MintHistoryFile:       os.Getenv("MINT_HISTORY_FILE"),
+		TxRetention:           txRetention,
+		EnablePprof:           getEnvBool("ENABLE_PPROF", false),
+		ReadTimeout:           readTimeout,
+		ReadHeaderTimeout:     readHeaderTimeout,
+		WriteTimeout:          writeTimeout,
+		IdleTimeout:           idleTimeout,
+		EnforceSupplyCap:      getEnvBool("ENFORCE_SUPPLY_CAP", false),
+		SupplyTotalMethod:     getEnv("SUPPLY_TOTAL_METHOD", "totalSupply"),
+		SupplyMaxMethod:       getEnv("SUPPLY_MAX_METHOD", "maxSupply"),
+		SupplyCacheTTL:        supplyCacheTTL,
+		AllowedURIHosts:       splitList(os.Getenv("ALLOWED_URI_HOSTS")),
+		MaxRateLimitEntries:   maxRateLimitEntries,
+		RateLimitFullPolicy:   rateLimitFullPolicy,
+		DynamicFees:           dynamicFees,
+		BaseFeeMultiplier:     baseFeeMultiplier,
+		PriorityFee:           priorityFee,
+		AllowDeploy:           getEnvBool("ALLOW_DEPLOY", false),
+		DeployFactory:         deployFactory,
+		DeployMaxBytes:        deployMaxBytes,
+		DeployMaxGas:          uint64(deployMaxGas),
+		SoftTimeout:           softTimeout,
+		MaxNonce:              maxNonce,
+		MaxRelayDuration:      maxRelayDuration,
+		SpaceAssignment:       spaceAssignment,
+		SignerType:            signerType,
+		StrictContentType:     getEnvBool("STRICT_CONTENT_TYPE", false),
+		FirstRequestBonus:     firstRequestBonus,
+		MaintenanceMessage:    getEnv("MAINTENANCE_MESSAGE", "The relayer is down for maintenance. Please try again later."),
+		MaintenanceFile:       maintenanceFile,
+		MulticallAddress:      multicallAddress,
+		AllowValue:            allowValue,
+		AllowedValues:         allowedValues,
+		ProcessedFile:         os.Getenv("PROCESSED_FILE"),
+		MetricsFile:           os.Getenv("METRICS_FILE"),
+		ShutdownTimeout:       shutdownTimeout,
+		MaxReceiptWatchers:    maxReceiptWatchers,
+		IPBlocklist:           ipBlocklist,
+		ServerCallData:        serverCallData,
+		DefaultTokenURI:       defaultTokenURI,
+		Allow1271:             allow1271,
+		DailyGasBudget:        dailyGasBudget,
+		ChainIDCheckInterval:  chainIDCheckInterval,
+		WebhookConfirmations:  uint64(webhookConfirmations),
+		MaxRequestCost:        maxRequestCost,
+		ExpectedCaller:        expectedCaller,
+		HubSelectorStrict:     getEnvBool("HUB_SELECTOR_STRICT", false),
+		CleanupJitter:         cleanupJitter,
+		DecodeCallDataMethods: splitList(getEnv("DECODE_CALLDATA_METHODS", "mint")),
+		SignResponses:         signResponses,
+		BroadcastRPCs:         splitList(os.Getenv("BROADCAST_RPCS")),
+		GasSoftLimit:          gasSoftLimit,
+		GasShedExponent:       gasShedExponent,
+		VerifySignature:       verifySignature,
+		DomainName:            getEnv("EIP712_DOMAIN_NAME", "PermissionedMetaTxHub"),
+		DomainVersion:         getEnv("EIP712_DOMAIN_VERSION", "1"),
+		DomainSalt:            domainSalt,
+	}, nil
+}
+
+// corsHandler applies the public CORS policy to all routes except /admin/,
+// which gets its own (typically stricter) origin list. Preflight responses
+// are cached by browsers for CORS_MAX_AGE.
+func corsHandler(config Config, next http.Handler) http.Handler {
+	maxAge := int(config.CORSMaxAge.Seconds())
+
+	public := cors.New(cors.Options{
+		AllowedOrigins: config.CORSOrigins,
+		AllowedMethods: []string{"GET", "POST", "OPTIONS"},
+		AllowedHeaders: []string{"*"},
+		MaxAge:         maxAge,
+	}).Handler(next)
+
+	admin := cors.New(cors.Options{
+		AllowedOrigins: config.AdminCORSOrigins,
+		AllowedMethods: []string{"GET", "POST", "DELETE", "OPTIONS"},
+		AllowedHeaders: []string{"*"},
+		MaxAge:         maxAge,
+	}).Handler(next)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/admin/") {
+			admin.ServeHTTP(w, r)
+			return
+		}
+		public.ServeHTTP(w, r)
+	})
+}
+
+// loadHubs parses the comma-separated HUB_ADDRESS list. HUB_VERSIONS names each
+// hub (defaulting to v1, v2, ...) and HUB_ABI_FILES optionally points each hub at
+// its own ABI JSON file; empty entries use the built-in Hub ABI.
+func loadHubs(hubAddrs, hubVersions, hubABIFiles string) ([]HubConfig, error) {
+	addrs := strings.Split(hubAddrs, ",")
+
+	var versions, abiFiles []string
+	if hubVersions != "" {
+		versions = strings.Split(hubVersions, ",")
+		if len(versions) != len(addrs) {
+			return nil, fmt.Errorf("HUB_VERSIONS must have one entry per HUB_ADDRESS")
+		}
+	}
+	if hubABIFiles != "" {
+		abiFiles = strings.Split(hubABIFiles, ",")
+		if len(abiFiles) != len(addrs) {
+			return nil, fmt.Errorf("HUB_ABI_FILES must have one entry per HUB_ADDRESS")
+		}
+	}
+
+	defaultABI, err := abi.JSON(strings.NewReader(hubABI))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Hub ABI: %v", err)
+	}
+
+	hubs := make([]HubConfig, 0, len(addrs))
+	seen := make(map[string]bool)
+	for i, addr := range addrs {
+		addr = strings.TrimSpace(addr)
+		if !common.IsHexAddress(addr) {
+			return nil, fmt.Errorf("invalid HUB_ADDRESS entry %q", addr)
+		}
+
+		version := fmt.Sprintf("v%d", i+1)
+		if versions != nil {
+			version = strings.TrimSpace(versions[i])
+		}
+		if seen[version] {
+			return nil, fmt.Errorf("duplicate hub version %q", version)
+		}
+		seen[version] = true
+
+		hubABIParsed := defaultABI
+		if abiFiles != nil && strings.TrimSpace(abiFiles[i]) != "" {
+			raw, err := os.ReadFile(strings.TrimSpace(abiFiles[i]))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read ABI for hub %s: %v", version, err)
+			}
+			hubABIParsed, err = abi.JSON(strings.NewReader(string(raw)))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse ABI for hub %s: %v", version, err)
+			}
+			if _, ok := hubABIParsed.Methods["execute"]; !ok {
+				return nil, fmt.Errorf("ABI for hub %s has no execute method", version)
+			}
+		}
+
+		hubs = append(hubs, HubConfig{
+			Version: version,
+			Address: common.HexToAddress(addr),
+			ABI:     hubABIParsed,
+		})
+	}
+
+	return hubs, nil
+}
+
+// resolveHub returns the hub for a requested version, defaulting to the first
+func (s *Server) resolveHub(version string) (HubConfig, bool) {
+	if version == "" {
+		return s.config.Hubs[0], true
+	}
+	for _, hub := range s.config.Hubs {
+		if hub.Version == version {
+			return hub, true
+		}
+	}
+	return HubConfig{}, false
+}
+
+// NewServer creates a new relayer server recording into metrics. A nil
+// metrics creates a fresh registry, restored from METRICS_FILE when set.
+func NewServer(config Config, metrics *Metrics) (*Server, error) {
+	// Connect to Ethereum client
+	client, err := ethclient.Dial(config.RPCURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Ethereum client: %v", err)
+	}
+
+	// Load the transaction signer: a local private key by default, or a
+	// clef/web3signer endpoint when REMOTE_SIGNER_URL is set
+	var signer TxSigner
+	if config.RemoteSignerURL != "" {
+		signer, err = newRemoteSigner(config.RemoteSignerURL, config.RemoteSignerType, config.RemoteSignerAddr)
+		log.Printf("🔏 Using remote %s signer at %s\n", config.RemoteSignerType, config.RemoteSignerURL)
+	} else {
+		var local *localSigner
+		local, err = newLocalSigner(config.RelayerPrivateKey)
+		if err == nil {
+			local.homestead = config.SignerType == signerHomestead
+			signer = local
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	relayerAddress := signer.Address()
+
+	log.Println("🚀 Starting Relayer Server...")
+	log.Printf("📍 Relayer Address: %s\n", relayerAddress.Hex())
+	log.Printf("🌐 Network: %s\n", config.ChainID.String())
+	for _, hub := range config.Hubs {
+		log.Printf("📜 Hub Contract (%s): %s\n", hub.Version, hub.Address.Hex())
+	}
+	log.Printf("🎃 NFT Contract: %s\n", config.NFTContract.Hex())
+	if config.TestMode {
+		log.Println("🧪 TEST_MODE enabled: transactions are simulated, nothing is broadcast")
+	}
+
+	txStore, err := NewTxStore(config.TxStoreFile)
+	if err != nil {
+		return nil, err
+	}
+	mintHistory, err := NewMintHistory(config.MintHistoryFile)
+	if err != nil {
+		return nil, err
+	}
+
+	allowlist, err := buildCallAllowlist(config)
+	if err != nil {
+		return nil, err
+	}
+
+	maintenance, err := NewMaintenance(config.MaintenanceFile)
+	if err != nil {
+		return nil, err
+	}
+
+	supply, err := NewSupplyCache(config.SupplyTotalMethod, config.SupplyMaxMethod, config.SupplyCacheTTL)
+	if err != nil {
+		return nil, err
+	}
+	if config.EnforceSupplyCap {
+		log.Printf("🎟️  Supply cap enforced via %s()/%s()\n", config.SupplyTotalMethod, config.SupplyMaxMethod)
+	}
+
+	processed, err := loadProcessed(config.ProcessedFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if metrics == nil {
+		metrics = NewMetrics()
+		if config.MetricsFile != "" {
+			if err := metrics.Restore(config.MetricsFile); err != nil {
+				return nil, err
+			}
+		}
+	}
+	var backend EthBackend = newInstrumentedBackend(client, metrics)
+	if len(config.BroadcastRPCs) > 0 {
+		backend, err = newBroadcastBackend(backend, config.BroadcastRPCs, config.ChainID, metrics)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	receipts := NewPollingReceiptWatcher(backend, config.ReceiptPoll)
+	if config.ReceiptBatching {
+		receipts = NewReceiptWatcher(receiptClients(backend), config.ReceiptPoll, metrics)
+		log.Printf("🧾 Batched receipt watcher enabled (every %s)\n", config.ReceiptPoll)
+	}
+
+	var reorgs *ReorgMonitor
+	if config.ReorgMonitor {
+		reorgs = NewReorgMonitor(backend, config.ReorgWindow, config.ReorgCheckInterval)
+		log.Printf("🔁 Reorg monitor enabled (window %s, every %s)\n", config.ReorgWindow, config.ReorgCheckInterval)
+	}
+
+	return &Server{
+		config:            config,
+		client:            backend,
+		nonces:            NewNonceManager(backend),
+		watchers:          NewWatcherPool(config.MaxReceiptWatchers),
+		blocklist:         cidrBlocklist(config.IPBlocklist),
+		budget:            NewGasBudget(config.DailyGasBudget),
+		maintenance:       maintenance,
+		signer:            signer,
+		relayerAddress:    relayerAddress,
+		processedRequests: processed,
+		rateLimit:         newRateLimit(config.MaxRateLimitEntries, config.RateLimitFullPolicy, config.FirstRequestBonus),
+		inflight:          make(map[string]map[uint32]int),
+		reverts:           NewRevertTracker(config.RevertThreshold, config.RevertWindow, config.RevertBlockTime),
+		metrics:           metrics,
+		receipts:          receipts,
+		reorgs:            reorgs,
+		dailyCap:          NewDailyCap(config.DailyCapPerAddress),
+		mintedCache:       NewMintedCache(config.MintedFalseTTL, config.MintedFalseTTLs),
+		fees:              NewFeeLedger(),
+		activity:          NewActivityCache(config.ActivityCacheTTL),
+		txStore:           txStore,
+		mintHistory:       mintHistory,
+		supply:            supply,
+		gasAverage:        NewGasAverage(config.GasAverageWindow, config.GasAverageMinSamples, config.GasFallbackLimit),
+		callAllowlist:     allowlist,
+		economics:         NewGasEconomics(config.PriceFeedURL, config.PriceFeedField),
+		breaker:           NewCircuitBreaker(config.BreakerThreshold, config.BreakerWindow, config.BreakerCooldown),
+	}, nil
+}
+
+// healthHandler handles health check requests
+func (s *Server) healthHandler(w http.ResponseWriter, r *http.Request) {
+	status := "ok"
+	breakerState := s.breaker.State()
+	if breakerState != breakerClosed {
+		status = "degraded"
+	}
+
+	// Any failing sub-check degrades the rollup status for simple probes
+	checks := s.runHealthChecks()
+	for _, check := range checks {
+		if check.Status != "ok" {
+			status = "degraded"
+		}
+	}
+
+	if s.maintenance.State().Enabled {
+		status = "maintenance"
+	}
+
+	// A chain ID mismatch makes the relayer unusable, so it fails readiness
+	httpStatus := http.StatusOK
+	if s.chainMismatch.Load() != nil {
+		status = "chain_mismatch"
+		httpStatus = http.StatusServiceUnavailable
+	}
+
+	response := HealthResponse{
+		Status:    status,
+		Relayer:   s.relayer().Hex(),
+		Breaker:   breakerState,
+		Checks:    checks,
+		Timestamp: time.Now().Unix(),
+	}
+
+	writeJSON(w, httpStatus, response)
+}
+
+// spaceHandler suggests a nonce space with no in-flight relays for an address.
+// The suggestion is advisory only: it reflects this relayer's view at the time
+// of the request, and the Hub still enforces nonce ordering per space.
+func (s *Server) spaceHandler(w http.ResponseWriter, r *http.Request) {
+	addrStr := mux.Vars(r)["address"]
+	if !common.IsHexAddress(addrStr) {
+		s.sendError(w, http.StatusBadRequest, "Invalid address", "")
+		return
+	}
+	address := common.HexToAddress(addrStr)
+
+	space, inFlight := s.suggestSpace(address.Hex())
+	response := SpaceResponse{
+		Address:  address.Hex(),
+		Space:    space,
+		InFlight: inFlight,
+		Advisory: true,
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// relayHandler handles relay requests
+func (s *Server) relayHandler(w http.ResponseWriter, r *http.Request) {
+	if state := s.maintenance.State(); state.Enabled {
+		s.sendErrorCode(w, http.StatusServiceUnavailable, "MAINTENANCE", state.Message, "")
+		return
+	}
+
+	// Shed load before any expensive work
+	if !s.enterGlobal() {
+		s.sendRetryAfter(w, http.StatusServiceUnavailable, "Relayer is at capacity. Please try again later.", 1)
+		return
+	}
+	defer s.leaveGlobal()
+
+	log.Printf("\n=== 🔍 NEW RELAY REQUEST %s ===\n", requestID(r))
+	log.Printf("Method: %s\n", r.Method)
+	log.Printf("Content-Type: %s\n", r.Header.Get("Content-Type"))
+	log.Printf("Content-Length: %d\n", r.ContentLength)
+	clientIP := s.clientIP(r)
+	log.Printf("Client IP: %s\n", clientIP)
+
+	if s.ipBlocked(clientIP) {
+		log.Printf("❌ Client IP %s is blocklisted\n", clientIP)
+		s.metrics.Inc("relay_ip_blocked_total")
+		s.sendErrorCode(w, http.StatusForbidden, "IP_BLOCKED", "Requests from this address are not allowed", "")
+		return
+	}
+
+	// SSE clients get progress events, then the usual response as the last
+	// one. The stream's headers go out before the result, so it can't carry
+	// a response signature.
+	if wantsEventStream(r) {
+		if signsResponses(w) {
+			s.sendErrorCode(w, http.StatusNotAcceptable, "SSE_NOT_SIGNED", "Event streams are not available while responses are signed", "")
+			return
+		}
+		sw := &sseWriter{ResponseWriter: w}
+		defer sw.finish()
+		w = sw
+	}
+
+	if s.config.StrictContentType && !isJSONContentType(r) {
+		s.sendErrorCode(w, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE", "Content-Type must be application/json", r.Header.Get("Content-Type"))
+		return
+	}
+
+	var req RelayRequest
+	if err := s.decodeBody(w, r, &req); err != nil {
+		log.Printf("❌ JSON Decode Error: %v\n", err)
+		s.sendBodyError(w, err)
+		return
+	}
+
+	// Route to the requested network: the path prefix wins over the body field
+	networkName := mux.Vars(r)["network"]
+	if networkName == "" {
+		networkName = req.Network
+	}
+	target, ok := s.networkServer(networkName)
+	if !ok {
+		s.sendErrorCode(w, http.StatusBadRequest, "UNKNOWN_NETWORK", "Unknown network", networkName)
+		return
+	}
+	target.relay(w, r, req)
+}
+
+// relay validates and submits a decoded relay request on s's network
+func (s *Server) relay(w http.ResponseWriter, r *http.Request, req RelayRequest) {
+	// Never sign for a chain the RPC endpoint is no longer on
+	if chainID := s.chainMismatch.Load(); chainID != nil {
+		s.sendErrorCode(w, http.StatusServiceUnavailable, "CHAIN_ID_MISMATCH", "Relayer RPC is on the wrong chain", fmt.Sprintf("RPC reports chain %s, expected %s", chainID.String(), s.config.ChainID.String()))
+		return
+	}
+
+	log.Println("✅ Request body decoded successfully")
+	log.Printf("Signature present: %v (length: %d)\n", req.Signature != "", len(req.Signature))
+	log.Printf("SignatureRSV present: %v\n", req.SignatureRSV != nil)
+	log.Printf("CallData present: %v (length: %d)\n", req.CallData != "", len(req.CallData))
+
+	// Log the entire forward struct for debugging
+	log.Println("📦 Forward struct:")
+	log.Printf("  From: %s\n", req.Forward.From.Hex())
+	log.Printf("  To: %s\n", req.Forward.To.Hex())
+	log.Printf("  Value: %s\n", req.Forward.Value.String())
+	log.Printf("  Space: %d\n", req.Forward.Space)
+	log.Printf("  Nonce: %s\n", req.Forward.Nonce.String())
+	log.Printf("  Deadline: %s\n", req.Forward.Deadline.String())
+	log.Printf("  DataHash: 0x%s\n", hex.EncodeToString(req.Forward.DataHash[:]))
+	log.Printf("  Caller: %s\n", req.Forward.Caller.Hex())
+
+	v := s.newValidation(w, r)
+
+	// Validate required fields
+	if req.Signature != "" && req.SignatureRSV != nil {
+		log.Println("❌ Validation failed: Both signature and signatureRSV provided")
+		if v.reject(http.StatusBadRequest, "", "Provide either signature or signatureRSV, not both", "") {
+			return
+		}
+	}
+
+	// Assemble the flat signature from its r, s, v components
+	if req.SignatureRSV != nil {
+		req.Signature = req.flatSignature()
+		log.Printf("✅ Signature assembled from r, s, v: %s\n", req.Signature)
+	}
+
+	// The forward's dataHash must commit to this calldata, so a client
+	// signing for different calldata still fails the dataHash check below
+	if req.CallData == "" && s.config.ServerCallData {
+		callData, err := defaultMintCallData(s.config.DefaultTokenURI)
+		if err != nil {
+			v.fatal(http.StatusInternalServerError, "", "Failed to construct callData", err.Error())
+			return
+		}
+		req.CallData = "0x" + hex.EncodeToString(callData)
+		log.Printf("🧩 Constructed mint callData for %s\n", s.config.DefaultTokenURI)
+	}
+
+	if req.Signature == "" || req.CallData == "" {
+		log.Println("❌ Validation failed: Missing signature or callData")
+		v.fatal(http.StatusBadRequest, "", "Missing required fields: forward, signature (or signatureRSV), callData", "")
+		return
+	}
+
+	log.Println("✅ Required fields validation passed")
+
+	// Nonce, deadline, addresses and value
+	if s.checkForwardFields(v, req.Forward) {
+		return
+	}
+
+	// With SPACE_ASSIGNMENT, a sentinel space asks the relayer to pick one.
+	// The space is part of the signed forward, so the relayer can't submit
+	// it as-is: the client must re-sign with the assigned space and resend.
+	if s.config.SpaceAssignment != "" && req.Forward.Space == spaceSentinel {
+		space := s.assignSpace(req.Forward.From.Hex())
+		log.Printf("🛤️  Assigned space %d to %s, re-sign required\n", space, req.Forward.From.Hex())
+		writeJSON(w, http.StatusConflict, RelayResponse{
+			Success: false,
+			Code:    "RESIGN_REQUIRED",
+			Error:   "Space assigned; re-sign the forward with this space and resubmit",
+			Space:   &space,
+		})
+		return
+	}
+
+	userAddress := req.Forward.From
+	log.Printf("\n📨 Processing mint request from: %s\n", userAddress.Hex())
+	log.Printf("🔢 Nonce: %s\n", req.Forward.Nonce.String())
+	log.Printf("📦 Space: %d\n", req.Forward.Space)
+
+	// Reject addresses auto-blocked for repeated reverts
+	if s.reverts.IsBlocked(userAddress.Hex()) {
+		log.Printf("❌ Address is temporarily blocked: %s\n", userAddress.Hex())
+		s.sendError(w, http.StatusForbidden, "Address temporarily blocked due to repeated reverts", "")
+		return
+	}
+
+	// Stop accepting relays while the circuit breaker is open
+	if !s.breaker.Allow() {
+		log.Println("❌ Circuit breaker open, relaying paused")
+		s.sendRetryAfter(w, http.StatusServiceUnavailable, "Relaying is temporarily paused. Please try again later.", int(s.config.BreakerCooldown.Seconds()))
+		return
+	}
+
+	// Stop relaying once the relayer key has spent its daily gas budget
+	if ok, retryAfter := s.budget.Allow(s.relayer()); !ok {
+		log.Printf("❌ Daily gas budget of %s wei exhausted for %s\n", s.config.DailyGasBudget.String(), s.relayer().Hex())
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		s.sendErrorCode(w, http.StatusServiceUnavailable, "DAILY_BUDGET_EXHAUSTED", "Daily gas budget exhausted. Please try again later.", "")
+		return
+	}
+
+	// Rate limiting
+	log.Println("🔍 Checking rate limit...")
+	if ok, retryAfter := s.checkRateLimit(userAddress.Hex()); !ok {
+		log.Printf("❌ Rate limit exceeded for: %s\n", userAddress.Hex())
+		s.sendRetryAfter(w, http.StatusTooManyRequests, "Too many requests. Please try again later.", retryAfter)
+		return
+	}
+	log.Println("✅ Rate limit check passed")
+
+	// Daily cap
+	if ok, retryAfter := s.dailyCap.Allow(userAddress.Hex()); !ok {
+		log.Printf("❌ Daily cap of %d relays reached for: %s\n", s.config.DailyCapPerAddress, userAddress.Hex())
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		s.sendErrorCode(w, http.StatusTooManyRequests, "DAILY_CAP_EXCEEDED", "Daily relay limit reached for this address", "")
+		return
+	}
+
+	// Check for duplicate requests
+	sigBytes, err := decodeHex(req.Signature)
+	if err != nil {
+		v.fatal(http.StatusBadRequest, "", "Invalid signature format", err.Error())
+		return
+	}
+	// Contract wallet signatures come in any length
+	if len(sigBytes) != signatureLength && !s.config.Allow1271 {
+		log.Printf("❌ Invalid signature length: %d bytes\n", len(sigBytes))
+		v.fatal(http.StatusBadRequest, "INVALID_SIGNATURE_LENGTH", fmt.Sprintf("Invalid signature length: expected %d bytes, got %d", signatureLength, len(sigBytes)), "")
+		return
+	}
+	requestID := requestHash(req.Forward, sigBytes)
+	log.Printf("🔍 Checking for duplicate request: %s\n", requestID)
+	if s.isProcessed(requestID) {
+		log.Printf("❌ Duplicate request detected: %s\n", requestID)
+		if v.reject(http.StatusBadRequest, "", "This request has already been processed", "") {
+			return
+		}
+	} else {
+		log.Println("✅ Duplicate check passed")
+	}
+
+	// Optionally reject the same callData resubmitted under a new nonce
+	dataHashID := dataHashKey(req.Forward)
+	if s.config.DedupeDataHash && s.isProcessed(dataHashID) {
+		log.Printf("❌ Duplicate dataHash detected for: %s\n", userAddress.Hex())
+		if v.reject(http.StatusBadRequest, "DUPLICATE_DATAHASH", "This callData has already been relayed for this address", "") {
+			return
+		}
+	}
+
+	// Resolve the target hub
+	hub, ok := s.resolveHub(req.HubVersion)
+	if !ok {
+		log.Printf("❌ Unknown hub version: %s\n", req.HubVersion)
+		v.fatal(http.StatusBadRequest, "", "Unknown hub version", req.HubVersion)
+		return
+	}
+	log.Printf("📜 Target hub: %s (%s)\n", hub.Version, hub.Address.Hex())
+
+	// Validate the optional token fee authorization
+	if req.Fee != nil {
+		if !s.config.FeeMode {
+			if v.reject(http.StatusBadRequest, "FEE_NOT_ENABLED", "Fee authorizations are not accepted by this relayer", "") {
+				return
+			}
+		} else if err := s.validateFee(hub, req.Forward, req.Fee); err != nil {
+			log.Printf("❌ Invalid fee authorization: %v\n", err)
+			if v.reject(http.StatusBadRequest, "INVALID_FEE", "Invalid fee authorization", err.Error()) {
+				return
+			}
+		} else {
+			log.Printf("💰 Fee authorized: %s of token %s\n", req.Fee.Amount.String(), req.Fee.Token.Hex())
+		}
+	} else if s.config.FeeMode && s.config.FeeRequired {
+		if v.reject(http.StatusBadRequest, "FEE_REQUIRED", "A fee authorization is required", "") {
+			return
+		}
+	}
+
+	// Optionally verify the EIP-712 signature before spending any gas
+	if s.config.VerifySignature {
+		if err := s.verifySignature(hub.Address, req.Forward, sigBytes); err != nil {
+			log.Printf("❌ Signature verification failed: %v\n", err)
+			if v.reject(http.StatusBadRequest, "INVALID_SIGNATURE", "Signature does not match forward.from", err.Error()) {
+				return
+			}
+		} else {
+			log.Println("✅ Signature verified")
+		}
+	}
+
+	if _, stop := s.checkForward(v, hub, req.Forward, req.CallData); stop {
+		return
+	}
+
+	// With ?all_errors=true, everything above has been checked by now
+	if v.flush() {
+		return
+	}
+
+	// Check gas price
+	log.Println("🔍 Checking gas price...")
+	clientMax, err := parseClientGasCap(req.MaxGasPriceGwei)
+	if err != nil {
+		s.sendError(w, http.StatusBadRequest, "Invalid maxGasPriceGwei", err.Error())
+		return
+	}
+	if s.checkGasPrice(w, clientMax) {
+		return
+	}
+	log.Println("✅ Gas price check passed")
+
+	log.Println("✅ All validations passed. Executing meta-transaction...")
+	progress := relayProgress(w)
+	if progress != nil {
+		progress(stageValidated, "")
+	}
+
+	// Execute in the background so SOFT_TIMEOUT can answer before the
+	// receipt arrives while the outcome is still recorded
+	s.acquireSpace(userAddress.Hex(), req.Forward.Space)
+	releaseSpace := sync.OnceFunc(func() { s.releaseSpace(userAddress.Hex(), req.Forward.Space) })
+	tracker := newSoftTracker(progress)
+	outcome := make(chan relayOutcome, 1)
+	s.relays.Add(1)
+	go func() {
+		defer s.relays.Done()
+		defer releaseSpace()
+		start := time.Now()
+		result, err := s.executeMetaTransaction(req, hub, tracker.progress)
+		s.recordRelayMetrics(req, result, err, time.Since(start))
+		s.finishRelay(req, requestID, dataHashID, result, err)
+		outcome <- relayOutcome{result: result, err: err}
+	}()
+
+	done, pendingHash, abandoned := tracker.wait(outcome, s.config.SoftTimeout, s.config.MaxRelayDuration)
+	if abandoned {
+		// Free the slot for other relays; the background watcher still
+		// records the outcome if the transaction later confirms
+		releaseSpace()
+		log.Printf("⌛ Relay exceeded MAX_RELAY_DURATION, abandoning %s\n", pendingHash)
+		s.metrics.Inc("relay_abandoned_total")
+		if pendingHash != "" {
+			s.txStore.Update(pendingHash, func(rec *TxRecord) {
+				if rec.Status == txPending {
+					rec.Status = txAbandoned
+				}
+			})
+		}
+		s.sendErrorCode(w, http.StatusGatewayTimeout, "RELAY_ABANDONED", "Relay timed out; the transaction may still confirm", pendingHash)
+		return
+	}
+	if pendingHash != "" {
+		log.Printf("⏳ Soft timeout reached, answering pending for %s\n", pendingHash)
+		writeJSON(w, http.StatusAccepted, RelayResponse{
+			Success:         true,
+			Status:          "pending",
+			TxHash:          pendingHash,
+			TransactionHash: pendingHash,
+		})
+		return
+	}
+	result, err := done.result, done.err
+	if err != nil {
+		s.sendError(w, http.StatusInternalServerError, s.parseError(err), err.Error())
+		return
+	}
+	// Send success response
+	response := RelayResponse{
+		Success:         true,
+		TxHash:          result.TxHash,
+		TransactionHash: result.TxHash,
+		BlockNumber:     result.BlockNumber,
+		GasUsed:         result.GasUsed.String(),
+		Attempts:        result.Attempts,
+		Simulated:       result.Simulated,
+	}
+	if result.EffectiveGasPrice != nil {
+		response.EffectiveGasPrice = result.EffectiveGasPrice.String()
+		response.TxCostWei = result.CostWei.String()
+	}
+	if r.URL.Query().Get("include_raw") == "true" && len(result.RawTx) > 0 {
+		response.RawTransaction = "0x" + hex.EncodeToString(result.RawTx)
+	}
+	if s.isDeploy(req.Forward.To) {
+		if addr, err := deployedAddress(hub, result.Logs); err != nil {
+			log.Printf("⚠️  Could not determine deployed address: %v\n", err)
+		} else {
+			log.Printf("🏗️  Contract deployed at %s\n", addr.Hex())
+			response.DeployedAddress = addr.Hex()
+		}
+	}
+	if r.URL.Query().Get("include_fees") == "true" && result.GasFeeCap != nil {
+		response.MaxFeePerGas = result.GasFeeCap.String()
+		response.MaxPriorityFeePerGas = result.GasTipCap.String()
+	}
+	if r.URL.Query().Get("decode_calldata") == "true" {
+		if decoded, err := s.decodeCallData(req); err != nil {
+			log.Printf("⚠️  Could not decode callData: %v\n", err)
+		} else {
+			response.DecodedCallData = decoded
+		}
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// finishRelay records the outcome of an executed relay: bookkeeping,
+// breaker state and webhooks
+func (s *Server) finishRelay(req RelayRequest, requestID, dataHashID string, result *TxResult, err error) {
+	userAddress := req.Forward.From
+	if errors.Is(err, errSettledElsewhere) {
+		// Reconciliation recorded the outcome and sent the webhook
+		return
+	}
+	if err != nil {
+		log.Printf("❌ Error executing transaction: %v\n", err)
+		if errors.Is(err, errTxReverted) {
+			s.recordRevert(userAddress.Hex())
+		}
+		if s.breaker.RecordFailure() {
+			log.Printf("🚨 ALERT: circuit breaker tripped after %d consecutive failures\n", s.config.BreakerThreshold)
+			s.metrics.Inc("relay_breaker_trips_total")
+		}
+		s.metrics.Set("relay_breaker_open", boolToFloat(s.breaker.State() != breakerClosed))
+		s.sendWebhook(WebhookEvent{
+			Event: webhookRelayFailed,
+			From:  userAddress.Hex(),
+			Error: s.parseError(err),
+		})
+		return
+	}
+
+	log.Printf("✅ Transaction confirmed in block: %d\n", result.BlockNumber)
+	log.Printf("⛽ Gas used: %s\n", result.GasUsed.String())
+	s.breaker.RecordSuccess()
+	s.metrics.Set("relay_breaker_open", 0)
+	s.recordSuccess(userAddress, requestID, dataHashID, req.Fee, result)
+}
+
+// recordSuccess does the bookkeeping for a confirmed relay, whether its
+// request saw the receipt or reconciliation found it later
+func (s *Server) recordSuccess(from common.Address, requestID, dataHashID string, fee *FeeAuthorization, result *TxResult) {
+	if requestID != "" {
+		s.markProcessed(requestID)
+	}
+	if s.config.DedupeDataHash && dataHashID != "" {
+		s.markProcessed(dataHashID)
+	}
+	s.dailyCap.Record(from.Hex())
+	s.mintHistory.Record(from.Hex())
+	s.mintedCache.Invalidate(s.config.NFTContract, from)
+	if fee != nil {
+		s.fees.Record(fee.Token, fee.Amount)
+	}
+	s.recordGasEconomics(result)
+	s.watchForReorg(from, result)
+
+	s.sendSuccessWebhook(WebhookEvent{
+		Event:       webhookRelaySucceeded,
+		From:        from.Hex(),
+		TxHash:      result.TxHash,
+		BlockNumber: result.BlockNumber,
+		GasUsed:     result.GasUsed.String(),
+	}, result.BlockHash)
+}
+
+// checkGasPrice rejects a relay, writing the response, while the network gas
+// price is above the configured maximum, the soft limit's shedding share or the
+// client's own cap. It reports whether the caller must stop.
+func (s *Server) checkGasPrice(w http.ResponseWriter, clientMax *big.Int) bool {
+	gasPrice, err := s.client.SuggestGasPrice(context.Background())
+	if err != nil {
+		log.Printf("⚠️  Error getting gas price: %v\n", err)
+	} else {
+		gasPriceGwei := new(big.Int).Div(gasPrice, big.NewInt(1e9))
+		maxGasPriceGwei := new(big.Int).Div(s.config.MaxGasPrice, big.NewInt(1e9))
+		log.Printf("   Current gas price: %s gwei\n", gasPriceGwei.String())
+		log.Printf("   Max gas price: %s gwei\n", maxGasPriceGwei.String())
+
+		if gasPrice.Cmp(s.config.MaxGasPrice) > 0 {
+			log.Printf("❌ Gas price too high: %s gwei\n", gasPriceGwei.String())
+			s.sendRetryAfter(w, http.StatusServiceUnavailable, "Network gas prices too high. Please try again later.", int(s.config.GasRetryAfter.Seconds()))
+			return true
+		}
+		if shed, retryAfter := s.shedForGas(gasPrice); shed {
+			log.Printf("❌ Shedding relay at %s gwei, above the soft limit\n", gasPriceGwei.String())
+			s.metrics.Inc("relay_gas_shed_total")
+			s.sendRetryAfter(w, http.StatusServiceUnavailable, "Network gas prices are elevated. Please try again later.", int(retryAfter.Seconds()))
+			return true
+		}
+		if clientMax != nil && gasPrice.Cmp(clientMax) > 0 {
+			log.Printf("❌ Gas price %s gwei exceeds the client's cap of %s gwei\n", gasPriceGwei.String(), new(big.Int).Div(clientMax, big.NewInt(1e9)).String())
+			w.Header().Set("Retry-After", strconv.Itoa(int(s.config.GasRetryAfter.Seconds())))
+			s.sendErrorCode(w, http.StatusServiceUnavailable, "GAS_PRICE_ABOVE_CLIENT_MAX", "Network gas price exceeds your maxGasPriceGwei", "")
+			return true
+		}
+	}
+	return false
+}
+
+// packExecute packs the hub call for a relay, passing the fee through when
+// the hub has a fee-aware entrypoint
+func (s *Server) packExecute(req RelayRequest, hub HubConfig, sigBytes, callDataBytes []byte) ([]byte, error) {
+	if _, ok := hub.ABI.Methods[s.config.FeeExecuteMethod]; ok && req.Fee != nil {
+		feeSig, err := decodeHex(req.Fee.Signature)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fee signature format: %v", err)
+		}
+		data, err := hub.ABI.Pack(s.config.FeeExecuteMethod, req.Forward.Tuple(), callDataBytes, sigBytes, req.Fee.Token, req.Fee.Amount, feeSig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to pack %s: %v", s.config.FeeExecuteMethod, err)
+		}
+		return data, nil
+	}
+
+	data, err := hub.ABI.Pack("execute", req.Forward.Tuple(), callDataBytes, sigBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack execute: %v", err)
+	}
+	return data, nil
+}
+
+// executeMetaTransaction executes the meta-transaction through the hub,
+// reporting each stage to progress when it's non-nil
+func (s *Server) executeMetaTransaction(req RelayRequest, hub HubConfig, progress func(stage, txHash string)) (*TxResult, error) {
+	log.Println("📝 Preparing transaction data...")
+
+	// Parse signature
+	sigBytes, err := decodeHex(req.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature format: %v", err)
+	}
+	log.Printf("   Signature length: %d bytes\n", len(sigBytes))
+
+	// Parse callData
+	callDataBytes, err := decodeHex(req.CallData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid callData format: %v", err)
+	}
+	log.Printf("   CallData length: %d bytes\n", len(callDataBytes))
+
+	// Prepare the Forward tuple struct for ABI encoding
+	forwardTuple := req.Forward.Tuple()
+
+	log.Println("📦 Forward tuple prepared:")
+	log.Printf("   From: %s\n", forwardTuple.From.Hex())
+	log.Printf("   To: %s\n", forwardTuple.To.Hex())
+	log.Printf("   Value: %s\n", forwardTuple.Value.String())
+	log.Printf("   Space: %d\n", forwardTuple.Space)
+	log.Printf("   Nonce: %s\n", forwardTuple.Nonce.String())
+	log.Printf("   Deadline: %s\n", forwardTuple.Deadline.String())
+	log.Printf("   DataHash: 0x%s\n", hex.EncodeToString(forwardTuple.DataHash[:]))
+	log.Printf("   Caller: %s\n", forwardTuple.Caller.Hex())
+
+	data, err := s.packExecute(req, hub, sigBytes, callDataBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("✅ Transaction data packed: %d bytes\n", len(data))
+	log.Printf("   Data (first 100 chars): 0x%s...\n", hex.EncodeToString(data[:min(50, len(data))]))
+
+	// The client may lower, but never raise, the relayer's gas price ceiling
+	maxGasPrice := s.config.MaxGasPrice
+	if clientMax, err := parseClientGasCap(req.MaxGasPriceGwei); err == nil && clientMax != nil {
+		maxGasPrice = minBigInt(maxGasPrice, clientMax)
+	}
+
+	// Deployments are expensive, so they get their own gas limit
+	deploy := s.isDeploy(req.Forward.To)
+	var maxGas uint64
+	if deploy {
+		maxGas = s.config.DeployMaxGas
+	}
+
+	return s.submitWithRetry(data, hub, req.Forward.To, &submission{
+		from:        req.Forward.From,
+		requestID:   requestHash(req.Forward, sigBytes),
+		dataHashID:  dataHashKey(req.Forward),
+		fee:         req.Fee,
+		maxGasPrice: maxGasPrice,
+		progress:    progress,
+		deploy:      deploy,
+		maxGas:      maxGas,
+		value:       bigOrZero(req.Forward.Value),
+	})
+}
+
+// submitWithRetry submits packed hub calldata, retrying per the configured
+// policy, and waits for the receipt
+func (s *Server) submitWithRetry(data []byte, hub HubConfig, target common.Address, sub *submission) (*TxResult, error) {
+	// In TEST_MODE nothing is broadcast: return a deterministic fake result
+	if s.config.TestMode {
+		fakeHash := crypto.Keccak256Hash(hub.Address.Bytes(), data)
+		log.Printf("🧪 TEST_MODE: skipping submission, simulated tx %s\n", fakeHash.Hex())
+		return &TxResult{
+			TxHash:      fakeHash.Hex(),
+			BlockNumber: simulatedBlockNumber,
+			GasUsed:     big.NewInt(0),
+			Attempts:    1,
+			Simulated:   true,
+		}, nil
+	}
+
+	// Hold off key rotation until this submission is confirmed
+	s.submitMutex.RLock()
+	defer s.submitMutex.RUnlock()
+
+	// Every broadcast stays held until the relay stops waiting on them
+	defer func() {
+		for _, tx := range sub.sent {
+			s.txStore.Release(tx.Hash().Hex())
+		}
+	}()
+
+	// Submit with the configured retry policy. Once a transaction has been
+	// broadcast its nonce is pinned so that resubmissions replace it.
+	policy := s.config.Retry
+	for attempt := 1; ; attempt++ {
+		final := attempt >= policy.MaxAttempts
+		result, err := s.submitTransaction(data, hub.Address, target, sub, final)
+		if err == nil {
+			result.Attempts = attempt
+			s.metrics.Add("relay_attempts_total", float64(attempt))
+			return result, nil
+		}
+
+		if final || !policy.Retryable(err) {
+			s.metrics.Add("relay_attempts_total", float64(attempt))
+			return nil, err
+		}
+
+		delay := policy.Delay(attempt)
+		log.Printf("🔁 Attempt %d/%d failed (%s): %v. Retrying in %s\n", attempt, policy.MaxAttempts, retryClass(err), err, delay)
+		s.metrics.Inc("relay_retries_total", "class", retryClass(err))
+		time.Sleep(delay)
+	}
+}
+
+// submission carries state across submission attempts of one relay
+type submission struct {
+	nonce       *uint64
+	gasPrice    *big.Int
+	from        common.Address
+	requestID   string
+	dataHashID  string
+	fee         *FeeAuthorization
+	maxGasPrice *big.Int
+	progress    func(stage, txHash string)
+	tip         *big.Int
+	deploy      bool
+	maxGas      uint64
+	value       *big.Int
+	sent        []*types.Transaction
+}
+
+// report passes a pipeline stage to the relay's progress reporter, if any
+func (sub *submission) report(stage string, txHash common.Hash) {
+	if sub.progress != nil {
+		sub.progress(stage, txHash.Hex())
+	}
+}
+
+// gasCeiling is the highest gas price this relay may pay
+func (sub *submission) gasCeiling(config Config) *big.Int {
+	if sub.maxGasPrice != nil {
+		return sub.maxGasPrice
+	}
+	return config.MaxGasPrice
+}
+
+// submitTransaction performs a single build, sign, send and wait attempt.
+// final is true on the last allowed attempt, in which case a transient gas
+// estimation failure falls back to the default gas limit instead of retrying.
+func (s *Server) submitTransaction(data []byte, hubAddress, target common.Address, sub *submission, final bool) (*TxResult, error) {
+	// Get nonce for relayer, reusing the broadcast nonce on resubmission
+	var nonce uint64
+	if sub.nonce != nil {
+		nonce = *sub.nonce
+		log.Printf("   Resubmitting with relayer nonce: %d\n", nonce)
+
+		// A replacement at an already confirmed nonce can only fail with
+		// "nonce too low": pick up the transaction that got mined instead
+		if result, mined, err := s.checkNonceMined(nonce, target, sub); mined {
+			return result, err
+		}
+	} else {
+		next, err := s.nonces.Next(context.Background(), s.relayer())
+		if err != nil {
+			return nil, retryable(retryClassSend, err)
+		}
+		nonce = next
+		log.Printf("   Relayer nonce: %d\n", nonce)
+
+		// Hand the nonce back if this attempt never broadcasts
+		defer func() {
+			if sub.nonce == nil {
+				s.nonces.Release(nonce)
+			}
+		}()
+	}
+
+	// Get gas price
+	gasPrice, err := s.client.SuggestGasPrice(context.Background())
+	if err != nil {
+		return nil, retryable(retryClassSend, fmt.Errorf("failed to get gas price: %v", err))
+	}
+	log.Printf("   Suggested gas price: %s gwei\n", new(big.Int).Div(gasPrice, big.NewInt(1e9)).String())
+
+	// A replacement must outbid the previous broadcast by at least 10%
+	if sub.gasPrice != nil {
+		bumped := bumpGasPrice(sub.gasPrice)
+		if gasPrice.Cmp(bumped) < 0 {
+			gasPrice = bumped
+		}
+	}
+
+	// Keep the gas price inside the mineable band [floor, ceiling]
+	gasPrice = clampGasPrice(gasPrice, s.config.MinGasPrice, sub.gasCeiling(s.config))
+	log.Printf("   Gas price: %s gwei\n", new(big.Int).Div(gasPrice, big.NewInt(1e9)).String())
+
+	// With DYNAMIC_FEES, gasPrice becomes the 1559 fee cap and tip is set
+	var tip *big.Int
+	if s.config.DynamicFees {
+		feeCap, feeTip, err := s.dynamicFees(sub)
+		if err != nil {
+			return nil, retryable(retryClassSend, err)
+		}
+		if feeCap != nil {
+			gasPrice, tip = feeCap, feeTip
+		}
+	}
+
+	// Nodes reject a replacement that doesn't outbid the pending transaction
+	// by 10%, so once the ceiling clamps the bump there's nothing to resend
+	if sub.gasPrice != nil && (gasPrice.Cmp(bumpGasPrice(sub.gasPrice)) < 0 ||
+		(tip != nil && sub.tip != nil && tip.Cmp(bumpGasPrice(sub.tip)) < 0)) {
+		log.Printf("❌ Replacement gas price %s gwei is capped below the required bump\n", new(big.Int).Div(gasPrice, big.NewInt(1e9)).String())
+		s.metrics.Inc("relay_replacement_capped_total")
+		return nil, errReplacementCapped
+	}
 
-	var minted bool
-	err = parsedABI.UnpackIntoInterface(&minted, "minted", result)
+	// Estimate gas
+	to := s.executeTarget(hubAddress)
+	callMsg := ethereum.CallMsg{
+		From:     s.relayer(),
+		To:       &to,
+		Value:    bigOrZero(sub.value),
+		Data:     data,
+		GasPrice: gasPrice,
+	}
+	if tip != nil {
+		callMsg.GasPrice = nil
+		callMsg.GasFeeCap = gasPrice
+		callMsg.GasTipCap = tip
+	}
+	estimatedGas, err := s.client.EstimateGas(context.Background(), callMsg)
+	estimateOK := err == nil
 	if err != nil {
-		log.Printf("❌ Error unpacking result: %v\n", err)
-		return false, err
+		log.Printf("⚠️  Failed to estimate gas: %v\n", err)
+		if !final && !isExecutionError(err) {
+			return nil, retryable(retryClassEstimate, fmt.Errorf("failed to estimate gas: %v", err))
+		}
+		estimatedGas = s.gasAverage.Fallback(s.config.GasFallbackBuffer)
+		log.Printf("   Using fallback gas limit: %d\n", estimatedGas)
 	}
 
-	log.Printf("   Minted status: %v\n", minted)
-	return minted, nil
-}
+	// Optionally compute an access list and keep it when it lowers gas usage
+	var accessList types.AccessList
+	if s.config.UseAccessList {
+		list, listGas, err := s.createAccessList(callMsg)
+		if err != nil {
+			log.Printf("⚠️  Access list unavailable, continuing without: %v\n", err)
+		} else if estimateOK && listGas >= estimatedGas {
+			log.Printf("   Access list does not save gas (%d vs %d), skipping\n", listGas, estimatedGas)
+		} else {
+			if estimateOK {
+				log.Printf("   Access list saves %d gas (%d -> %d)\n", estimatedGas-listGas, estimatedGas, listGas)
+			}
+			accessList = list
+			estimatedGas = listGas
+			estimateOK = true
+		}
+	}
 
-// waitForReceipt waits for transaction receipt
-func (s *Server) waitForReceipt(txHash common.Hash) (*types.Receipt, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
-	defer cancel()
+	if estimateOK {
+		// Add 20% buffer to estimated gas
+		estimatedGas = estimatedGas * 120 / 100
+		log.Printf("   Estimated gas (with 20%% buffer): %d\n", estimatedGas)
+	}
+
+	if sub.maxGas > 0 && estimatedGas > sub.maxGas {
+		if estimateOK {
+			return nil, fmt.Errorf("estimated gas %d exceeds the limit of %d", estimatedGas, sub.maxGas)
+		}
+		estimatedGas = sub.maxGas
+	}
+
+	// Reject before signing if the relayer can't pay for the worst case
+	worstCaseCost, err := s.checkWorstCaseCost(sub, estimatedGas, gasPrice, estimateOK)
+	if err != nil {
+		return nil, err
+	}
 
-	for {
-		receipt, err := s.client.TransactionReceipt(ctx, txHash)
+	var signedTx *types.Transaction
+	for bumps := 0; ; bumps++ {
+		// Create transaction
+		tx := s.newRelayTx(nonce, to, estimatedGas, gasPrice, tip, bigOrZero(sub.value), data, accessList)
+
+		log.Println("🔐 Signing transaction...")
+		// Sign transaction
+		signedTx, err = s.currentSigner().SignTx(context.Background(), tx, s.config.ChainID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign transaction: %v", err)
+		}
+
+		log.Println("📤 Sending transaction to network...")
+		// Send transaction
+		err = s.client.SendTransaction(context.Background(), signedTx)
 		if err == nil {
-			return receipt, nil
+			break
+		}
+
+		// The node already has this exact transaction: wait on it as usual
+		if isAlreadyKnown(err) {
+			log.Printf("ℹ️  Transaction already known to the node, waiting on %s\n", signedTx.Hash().Hex())
+			break
+		}
+
+		// Another transaction holds this nonce. Only our own earlier broadcast
+		// is worth outbidding: a fresh nonce held by anything else, e.g. a
+		// transaction sent before a restart, is skipped by re-syncing with
+		// the node instead.
+		if isReplacementUnderpriced(err) && bumps < maxUnderpricedBumps && sub.nonce == nil {
+			log.Printf("⚠️  Nonce %d is held by another pending transaction, re-syncing nonce\n", nonce)
+			s.nonces.Commit(nonce)
+			s.nonces.Reset()
+			next, err := s.nonces.Next(context.Background(), s.relayer())
+			if err != nil {
+				return nil, retryable(retryClassSend, err)
+			}
+			nonce = next
+			log.Printf("   Relayer nonce: %d\n", nonce)
+			continue
+		}
+		if isReplacementUnderpriced(err) && bumps < maxUnderpricedBumps {
+			bumped := clampGasPrice(bumpGasPrice(gasPrice), nil, sub.gasCeiling(s.config))
+			if bumped.Cmp(gasPrice) <= 0 {
+				return nil, fmt.Errorf("failed to send transaction: %v (gas price already at the maximum)", err)
+			}
+			log.Printf("⚠️  Replacement underpriced, bumping gas price to %s gwei\n", new(big.Int).Div(bumped, big.NewInt(1e9)).String())
+			gasPrice = bumped
+			if tip != nil {
+				tip = capTip(bumpGasPrice(tip), gasPrice)
+			}
+			// The bump raises what the relayer may pay, so re-check it
+			if worstCaseCost, err = s.checkWorstCaseCost(sub, estimatedGas, gasPrice, estimateOK); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		// Only transport problems are worth another attempt: a node rejecting
+		// the transaction itself will reject it again
+		err = fmt.Errorf("failed to send transaction: %w", err)
+		if !isTransientSendError(err) {
+			return nil, err
+		}
+		return nil, retryable(retryClassSend, err)
+	}
+	if sub.nonce == nil {
+		s.nonces.Commit(nonce)
+	}
+	sub.nonce = &nonce
+	sub.gasPrice = gasPrice
+	sub.tip = tip
+	sub.sent = append(sub.sent, signedTx)
+	s.debitBalance(worstCaseCost)
+
+	log.Printf("📡 Transaction sent: %s\n", signedTx.Hash().Hex())
+	log.Println("⏳ Waiting for confirmation...")
+	sub.report(stageSubmitted, signedTx.Hash())
+
+	// Track the broadcast so its outcome survives a restart. It stays held
+	// until this relay stops waiting on it.
+	s.txStore.Put(TxRecord{
+		TxHash:      signedTx.Hash().Hex(),
+		RequestID:   sub.requestID,
+		DataHashID:  sub.dataHashID,
+		From:        sub.from.Hex(),
+		Sender:      s.relayer().Hex(),
+		Hub:         hubAddress.Hex(),
+		Fee:         sub.fee,
+		Status:      txPending,
+		SubmittedAt: time.Now().Unix(),
+	})
+	s.txStore.Hold(signedTx.Hash().Hex())
+
+	// Wait for receipt
+	receipt, err := s.waitForReceipt(signedTx.Hash())
+	if err != nil {
+		s.dropTx(signedTx.Hash(), "no receipt before timeout")
+		if errors.Is(err, errReceiptTimeout) {
+			return nil, retryable(retryClassTimeout, fmt.Errorf("failed to get receipt: %w", err))
 		}
+		return nil, fmt.Errorf("failed to get receipt: %v", err)
+	}
+
+	return s.receiptResult(signedTx, receipt, target, sub)
+}
+
+// receiptResult turns the receipt of a mined relay transaction into its
+// outcome, recording it in the tx store
+func (s *Server) receiptResult(signedTx *types.Transaction, receipt *types.Receipt, target common.Address, sub *submission) (*TxResult, error) {
+	sub.report(stageConfirming, signedTx.Hash())
+	s.supersedeTxs(sub, signedTx.Hash())
+
+	// Check if transaction was successful. Some contracts signal failure
+	// through events instead of reverting.
+	var failure error
+	if receipt.Status == 0 {
+		log.Printf("❌ Transaction reverted! Receipt status: %d\n", receipt.Status)
+		failure = errTxReverted
+	} else if s.config.SuccessEventTopic != (common.Hash{}) && !sub.deploy && !hasEvent(receipt, target, s.config.SuccessEventTopic) {
+		log.Printf("❌ Success event %s not found in receipt logs\n", s.config.SuccessEventTopic.Hex())
+		failure = errSoftFailure
+	}
+	if !s.settleTx(signedTx.Hash(), receipt, signedTx.GasPrice(), failure) {
+		log.Printf("ℹ️  Outcome of %s was already recorded by reconciliation\n", signedTx.Hash().Hex())
+		return nil, errSettledElsewhere
+	}
+	if failure != nil {
+		return nil, failure
+	}
+
+	log.Printf("✅ Transaction successful! Status: %d\n", receipt.Status)
+
+	// Prefer the receipt's effective price, which differs from the submitted
+	// price for dynamic-fee transactions
+	effectiveGasPrice := receipt.EffectiveGasPrice
+	if effectiveGasPrice == nil {
+		effectiveGasPrice = signedTx.GasPrice()
+	}
+	gasUsed := new(big.Int).SetUint64(receipt.GasUsed)
+	s.gasAverage.Record(receipt.GasUsed)
+
+	// Keep the signed RLP so integrators can audit what was broadcast
+	rawTx, err := signedTx.MarshalBinary()
+	if err != nil {
+		log.Printf("⚠️  Failed to encode raw transaction: %v\n", err)
+	}
+
+	result := &TxResult{
+		TxHash:            signedTx.Hash().Hex(),
+		BlockNumber:       receipt.BlockNumber.Uint64(),
+		BlockHash:         receipt.BlockHash,
+		RawTx:             rawTx,
+		GasUsed:           gasUsed,
+		EffectiveGasPrice: effectiveGasPrice,
+		CostWei:           new(big.Int).Mul(gasUsed, effectiveGasPrice),
+		Logs:              receipt.Logs,
+	}
+	if signedTx.Type() == types.DynamicFeeTxType {
+		result.GasFeeCap = signedTx.GasFeeCap()
+		result.GasTipCap = signedTx.GasTipCap()
+	}
+	return result, nil
+}
+
+// checkWorstCaseCost returns the most a transaction of gas at gasPrice, plus
+// the submission's value, can cost the relayer, rejecting it when that
+// exceeds the per-request budget or the relayer's balance. The budget only
+// applies to real estimates.
+func (s *Server) checkWorstCaseCost(sub *submission, gas uint64, gasPrice *big.Int, estimateOK bool) (*big.Int, error) {
+	worstCaseCost := new(big.Int).Mul(new(big.Int).SetUint64(gas), gasPrice)
+	worstCaseCost.Add(worstCaseCost, bigOrZero(sub.value))
+
+	// A contract can inflate its gas estimate to make the relayer burn gas,
+	// so cap what a single request may cost even below the gas limit
+	if estimateOK && s.config.MaxRequestCost != nil && worstCaseCost.Cmp(s.config.MaxRequestCost) > 0 {
+		log.Printf("🚩 Suspicious gas estimate from %s: %d gas plus %s wei value costs %s wei, over the %s wei budget\n",
+			sub.from.Hex(), gas, bigOrZero(sub.value).String(), worstCaseCost.String(), s.config.MaxRequestCost.String())
+		s.metrics.Inc("relay_gas_estimate_rejected_total")
+		return nil, errRequestTooExpensive
+	}
+
+	balance, err := s.relayerBalance()
+	if err != nil {
+		log.Printf("⚠️  Could not check relayer balance: %v\n", err)
+		return worstCaseCost, nil
+	}
+	log.Printf("   Worst-case cost: %s wei (balance: %s wei)\n", worstCaseCost.String(), balance.String())
+	if balance.Cmp(worstCaseCost) < 0 {
+		log.Println("❌ Relayer balance can't cover worst-case gas and value")
+		return nil, errInsufficientRelayerFunds
+	}
+	return worstCaseCost, nil
+}
+
+// createAccessList asks the node for an access list via eth_createAccessList
+// and returns it along with the gas used when the list is applied
+func (s *Server) createAccessList(msg ethereum.CallMsg) (types.AccessList, uint64, error) {
+	arg := map[string]interface{}{
+		"from":  msg.From,
+		"to":    msg.To,
+		"data":  hexutil.Bytes(msg.Data),
+		"value": (*hexutil.Big)(msg.Value),
+	}
+	if msg.GasPrice != nil {
+		arg["gasPrice"] = (*hexutil.Big)(msg.GasPrice)
+	}
+
+	var result struct {
+		AccessList *types.AccessList `json:"accessList"`
+		GasUsed    hexutil.Uint64    `json:"gasUsed"`
+		Error      string            `json:"error,omitempty"`
+	}
+	if err := s.client.Client().CallContext(context.Background(), &result, "eth_createAccessList", arg, "pending"); err != nil {
+		return nil, 0, err
+	}
+	if result.Error != "" {
+		return nil, 0, fmt.Errorf("execution error: %s", result.Error)
+	}
+	if result.AccessList == nil {
+		return nil, 0, fmt.Errorf("empty access list response")
+	}
+
+	log.Printf("   Access list: %d entries, gas used %d\n", len(*result.AccessList), uint64(result.GasUsed))
+	return *result.AccessList, uint64(result.GasUsed), nil
+}
+
+// clampGasPrice returns max(suggested, floor) capped at ceiling. A nil or zero
+// floor/ceiling is ignored.
+func clampGasPrice(suggested, floor, ceiling *big.Int) *big.Int {
+	price := new(big.Int).Set(suggested)
+	if floor != nil && floor.Sign() > 0 && price.Cmp(floor) < 0 {
+		price.Set(floor)
+	}
+	if ceiling != nil && ceiling.Sign() > 0 {
+		price.Set(minBigInt(price, ceiling))
+	}
+	return price
+}
+
+// latestBlockTime returns the latest block timestamp, cached for
+// BLOCK_TIME_CACHE_TTL
+func (s *Server) latestBlockTime() (int64, error) {
+	s.blockTimeMutex.Lock()
+	defer s.blockTimeMutex.Unlock()
+
+	if !s.blockTimeAt.IsZero() && time.Since(s.blockTimeAt) < s.config.BlockTimeCacheTTL {
+		return s.blockTime, nil
+	}
+
+	header, err := s.client.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		return 0, err
+	}
+	s.blockTime = int64(header.Time)
+	s.blockTimeAt = time.Now()
+	return s.blockTime, nil
+}
+
+// newRelayTx builds the hub transaction, using an EIP-2930 transaction when
+// an access list is attached and a legacy transaction otherwise
+func (s *Server) newRelayTx(nonce uint64, to common.Address, gas uint64, gasPrice, tip, value *big.Int, data []byte, accessList types.AccessList) *types.Transaction {
+	if tip != nil {
+		return types.NewTx(&types.DynamicFeeTx{
+			ChainID:    s.config.ChainID,
+			Nonce:      nonce,
+			GasTipCap:  tip,
+			GasFeeCap:  gasPrice,
+			Gas:        gas,
+			To:         &to,
+			Value:      value,
+			Data:       data,
+			AccessList: accessList,
+		})
+	}
+	if accessList != nil {
+		return types.NewTx(&types.AccessListTx{
+			ChainID:    s.config.ChainID,
+			Nonce:      nonce,
+			GasPrice:   gasPrice,
+			Gas:        gas,
+			To:         &to,
+			Value:      value,
+			Data:       data,
+			AccessList: accessList,
+		})
+	}
+	return types.NewTransaction(nonce, to, value, gas, gasPrice, data)
+}
+
+// bumpGasPrice returns price raised by the 10% nodes require for a replacement
+func bumpGasPrice(price *big.Int) *big.Int {
+	bumped := new(big.Int).Mul(price, big.NewInt(110))
+	bumped.Div(bumped, big.NewInt(100))
+	if bumped.Cmp(price) <= 0 {
+		bumped.Add(price, big.NewInt(1))
+	}
+	return bumped
+}
+
+// relayerBalance returns the relayer balance, refreshing the cached value
+// when it is older than BALANCE_CACHE_TTL
+func (s *Server) relayerBalance() (*big.Int, error) {
+	s.balanceMutex.Lock()
+	defer s.balanceMutex.Unlock()
+
+	if s.balance != nil && time.Since(s.balanceFetchedAt) < s.config.BalanceCacheTTL {
+		return new(big.Int).Set(s.balance), nil
+	}
+
+	balance, err := s.client.BalanceAt(context.Background(), s.relayer(), nil)
+	if err != nil {
+		return nil, err
+	}
+	s.balance = balance
+	s.balanceFetchedAt = time.Now()
+	return new(big.Int).Set(balance), nil
+}
 
-		select {
-		case <-ctx.Done():
-			return nil, fmt.Errorf("timeout waiting for transaction receipt")
-		case <-time.After(2 * time.Second):
-			// Continue polling
+// debitBalance subtracts a broadcast transaction's worst-case cost from the
+// cached balance so concurrent relays don't overcommit between refreshes
+func (s *Server) debitBalance(cost *big.Int) {
+	s.balanceMutex.Lock()
+	defer s.balanceMutex.Unlock()
+
+	if s.balance == nil {
+		return
+	}
+	s.balance = new(big.Int).Sub(s.balance, cost)
+	if s.balance.Sign() < 0 {
+		s.balance.SetInt64(0)
+	}
+}
+
+// hasEvent reports whether the receipt contains a log emitted by contract
+// with the given topic0
+func hasEvent(receipt *types.Receipt, contract common.Address, topic common.Hash) bool {
+	for _, l := range receipt.Logs {
+		if l.Address == contract && len(l.Topics) > 0 && l.Topics[0] == topic {
+			return true
 		}
 	}
+	return false
+}
+
+// callerAllowed reports whether this relayer may submit a forward naming
+// caller. The zero address is accepted only when ALLOW_ANY_CALLER is set.
+func (s *Server) callerAllowed(caller common.Address) bool {
+	if s.config.AllowAnyCaller && caller == (common.Address{}) {
+		return true
+	}
+	return bytes32Equal(caller, s.expectedCaller())
+}
+
+// expectedCaller is the forward.caller relays must name: EXPECTED_CALLER
+// when set, otherwise the relayer key
+func (s *Server) expectedCaller() common.Address {
+	if s.config.ExpectedCaller != (common.Address{}) {
+		return s.config.ExpectedCaller
+	}
+	return s.relayer()
+}
+
+// executeTarget is where relay transactions for hub are sent. With
+// EXPECTED_CALLER the intermediate is a pass-through contract that forwards
+// the execute call data unchanged to its single hub, so the hub sees it as
+// msg.sender; otherwise the relayer calls the hub directly.
+func (s *Server) executeTarget(hub common.Address) common.Address {
+	if s.config.ExpectedCaller != (common.Address{}) {
+		return s.config.ExpectedCaller
+	}
+	return hub
+}
+
+// cleanupJitter returns a random delay below CLEANUP_JITTER
+func (s *Server) cleanupJitter() time.Duration {
+	if s.config.CleanupJitter <= 0 {
+		return 0
+	}
+	return rand.N(s.config.CleanupJitter)
+}
+
+// minBigInt returns the smaller of a and b, which must both be non-nil. The
+// result is one of the arguments, not a copy.
+func minBigInt(a, b *big.Int) *big.Int {
+	if a.Cmp(b) < 0 {
+		return a
+	}
+	return b
+}
+
+// waitForReceipt waits for transaction receipt. Every transaction shares
+// the receipt watcher's polling loop; MAX_RECEIPT_WATCHERS bounds how many
+// are watched at once, and the receipt timeout only starts once a watcher
+// slot is free.
+func (s *Server) waitForReceipt(txHash common.Hash) (*types.Receipt, error) {
+	if err := s.watchers.Acquire(context.Background()); err != nil {
+		return nil, err
+	}
+	s.metrics.Set("receipt_watchers_active", float64(s.watchers.Stats().Active))
+	defer func() {
+		s.watchers.Release()
+		s.metrics.Set("receipt_watchers_active", float64(s.watchers.Stats().Active))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), receiptTimeout)
+	defer cancel()
+
+	receipt, err := s.receipts.Wait(ctx, txHash)
+	if err != nil {
+		return nil, errReceiptTimeout
+	}
+	return receipt, nil
 }
 
 // Rate limiting methods
-func (s *Server) checkRateLimit(address string) bool {
+
+// checkRateLimit records a request for address and reports whether it is
+// allowed. When it is not, it also returns the seconds until the oldest
+// request in the window expires.
+func (s *Server) checkRateLimit(address string) (bool, int) {
 	s.rateLimit.mu.Lock()
 	defer s.rateLimit.mu.Unlock()
 
 	now := time.Now().Unix()
-	requests := s.rateLimit.requests[address]
+	requests, tracked := s.rateLimit.requests[address]
+
+	// A flood of fresh addresses must not grow the table without bound
+	if !tracked && !s.rateLimit.makeRoom(now) {
+		s.metrics.Inc("rate_limit_table_full_total", "policy", s.rateLimit.policy)
+		if s.rateLimit.policy == rateLimitFailOpen {
+			return true, 0
+		}
+		return false, int(rateLimitWindow.Seconds())
+	}
 
 	// Filter recent requests
 	var recentRequests []int64
@@ -775,17 +2915,167 @@ func (s *Server) checkRateLimit(address string) bool {
 		}
 	}
 
-	if len(recentRequests) >= maxRequestsPerWindow {
-		return false
+	if len(recentRequests) >= s.rateLimit.limit(address, tracked, now) {
+		retryAfter := recentRequests[0] + int64(rateLimitWindow.Seconds()) - now
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		return false, int(retryAfter)
 	}
 
 	recentRequests = append(recentRequests, now)
 	s.rateLimit.requests[address] = recentRequests
+	s.rateLimit.touch(address)
+
+	return true, 0
+}
+
+// rateLimitStatus returns address's requests in the current window and its
+// limit, without counting a request
+func (s *Server) rateLimitStatus(address string) (int, int) {
+	s.rateLimit.mu.RLock()
+	defer s.rateLimit.mu.RUnlock()
+	return s.rateLimit.status(address, time.Now().Unix())
+}
+
+// Global in-flight limiting
 
+// enterGlobal counts a relay request against MAX_GLOBAL_INFLIGHT, reporting
+// false (without counting it) when the process is at capacity
+func (s *Server) enterGlobal() bool {
+	n := s.globalInflight.Add(1)
+	if s.config.MaxGlobalInflight > 0 && n > int64(s.config.MaxGlobalInflight) {
+		s.globalInflight.Add(-1)
+		s.metrics.Inc("relay_shed_total")
+		return false
+	}
+	s.metrics.Set("relay_global_inflight", float64(n))
 	return true
 }
 
+// leaveGlobal releases a request counted by enterGlobal
+func (s *Server) leaveGlobal() {
+	s.metrics.Set("relay_global_inflight", float64(s.globalInflight.Add(-1)))
+}
+
+// In-flight nonce space tracking
+func (s *Server) acquireSpace(address string, space uint32) {
+	s.inflightMutex.Lock()
+	defer s.inflightMutex.Unlock()
+
+	spaces := s.inflight[address]
+	if spaces == nil {
+		spaces = make(map[uint32]int)
+		s.inflight[address] = spaces
+	}
+	spaces[space]++
+}
+
+func (s *Server) releaseSpace(address string, space uint32) {
+	s.inflightMutex.Lock()
+	defer s.inflightMutex.Unlock()
+
+	spaces := s.inflight[address]
+	if spaces == nil {
+		return
+	}
+	spaces[space]--
+	if spaces[space] <= 0 {
+		delete(spaces, space)
+	}
+	if len(spaces) == 0 {
+		delete(s.inflight, address)
+	}
+}
+
+// suggestSpace returns the least-loaded space among the configured lanes
+// (preferring the lowest idle one) along with the current in-flight counts
+func (s *Server) suggestSpace(address string) (uint32, map[uint32]int) {
+	s.inflightMutex.Lock()
+	defer s.inflightMutex.Unlock()
+
+	inFlight := make(map[uint32]int)
+	for space, count := range s.inflight[address] {
+		inFlight[space] = count
+	}
+
+	best := uint32(0)
+	for space := uint32(0); space < uint32(s.config.NonceSpaces); space++ {
+		if inFlight[space] < inFlight[best] {
+			best = space
+		}
+		if inFlight[space] == 0 {
+			return space, inFlight
+		}
+	}
+	return best, inFlight
+}
+
+// assignSpace picks a space for a forward sent with the spaceSentinel, using
+// the configured SPACE_ASSIGNMENT strategy
+func (s *Server) assignSpace(address string) uint32 {
+	if s.config.SpaceAssignment == spaceRoundRobin {
+		return (s.spaceCursor.Add(1) - 1) % uint32(s.config.NonceSpaces)
+	}
+	space, _ := s.suggestSpace(address)
+	return space
+}
+
+// recordRevert counts an on-chain revert and auto-blocks the address when it
+// crosses the configured threshold
+func (s *Server) recordRevert(address string) {
+	s.metrics.Inc("relay_reverts_total")
+	if !s.reverts.Record(address) {
+		return
+	}
+
+	log.Printf("🚨 ALERT: %s auto-blocked after %d reverts within %s\n", address, s.config.RevertThreshold, s.config.RevertWindow)
+	s.metrics.Inc("relay_autoblocked_total")
+	s.metrics.Set("relay_autoblocklist_size", float64(len(s.reverts.List())))
+}
+
 // Processed requests tracking
+
+// requestHash is a content-addressed request ID: keccak256 over the
+// fixed-width encoding of every Forward field followed by the signature.
+// The signature's v is normalized to 27/28 so both encodings of the same
+// signature map to the same ID.
+func requestHash(fwd Forward, signature []byte) string {
+	sig := append([]byte(nil), signature...)
+	if len(sig) == 65 && sig[64] < 27 {
+		sig[64] += 27
+	}
+
+	space := make([]byte, 4)
+	binary.BigEndian.PutUint32(space, fwd.Space)
+
+	return crypto.Keccak256Hash(
+		fwd.From.Bytes(),
+		fwd.To.Bytes(),
+		math.U256Bytes(new(big.Int).Set(bigOrZero(fwd.Value))),
+		space,
+		math.U256Bytes(new(big.Int).Set(bigOrZero(fwd.Nonce))),
+		math.U256Bytes(new(big.Int).Set(bigOrZero(fwd.Deadline))),
+		fwd.DataHash[:],
+		fwd.Caller.Bytes(),
+		sig,
+	).Hex()
+}
+
+// dataHashKey is the processed-cache key DEDUPE_DATAHASH records for a
+// forward's sender and callData
+func dataHashKey(fwd Forward) string {
+	return fmt.Sprintf("%s-data-%s", fwd.From.Hex(), hex.EncodeToString(fwd.DataHash[:]))
+}
+
+// bigOrZero treats a missing big.Int as zero
+func bigOrZero(n *big.Int) *big.Int {
+	if n == nil {
+		return new(big.Int)
+	}
+	return n
+}
+
 func (s *Server) isProcessed(requestID string) bool {
 	s.reqMutex.RLock()
 	defer s.reqMutex.RUnlock()
@@ -803,10 +3093,13 @@ func (s *Server) markProcessed(requestID string) {
 
 // cleanupRoutine periodically cleans up old entries
 func (s *Server) cleanupRoutine() {
-	ticker := time.NewTicker(cleanupInterval)
-	defer ticker.Stop()
+	// With CLEANUP_JITTER, replicas started together drift apart instead of
+	// cleaning up in lockstep
+	timer := time.NewTimer(cleanupInterval + s.cleanupJitter())
+	defer timer.Stop()
 
-	for range ticker.C {
+	for range timer.C {
+		timer.Reset(cleanupInterval + s.cleanupJitter())
 		now := time.Now()
 
 		// Clean processed requests
@@ -819,47 +3112,58 @@ func (s *Server) cleanupRoutine() {
 		s.reqMutex.Unlock()
 
 		// Clean rate limits
-		s.rateLimit.mu.Lock()
-		cutoff := now.Add(-rateLimitWindow).Unix()
-		for addr, times := range s.rateLimit.requests {
-			var recent []int64
-			for _, t := range times {
-				if t > cutoff {
-					recent = append(recent, t)
-				}
-			}
-			if len(recent) == 0 {
-				delete(s.rateLimit.requests, addr)
-			} else {
-				s.rateLimit.requests[addr] = recent
-			}
+		s.rateLimit.Cleanup(now)
+
+		// Clean revert tracking
+		blocked := s.reverts.Cleanup(now)
+		s.metrics.Set("relay_autoblocklist_size", float64(blocked))
+
+		// Clean daily caps
+		s.dailyCap.Cleanup(now)
+
+		// Clean expired minted lookups
+		s.mintedCache.Cleanup(now)
+
+		// Clean account activity lookups
+		s.activity.Cleanup(now)
+
+		// Prune settled transaction records and persist changes since the
+		// last run
+		s.txStore.Prune(now, s.config.TxRetention)
+		if err := s.txStore.Flush(); err != nil {
+			log.Printf("⚠️  %v\n", err)
 		}
-		s.rateLimit.mu.Unlock()
-	}
-}
 
-// Helper methods
-func (s *Server) sendError(w http.ResponseWriter, status int, message, details string) {
-	log.Printf("\n❌ ERROR RESPONSE [%d]: %s\n", status, message)
-	if details != "" {
-		log.Printf("   Details: %s\n", details)
-	}
+		// Persist mint counts recorded since the last run
+		if err := s.mintHistory.Flush(); err != nil {
+			log.Printf("⚠️  %v\n", err)
+		}
 
-	response := RelayResponse{
-		Success: false,
-		Error:   message,
-		Details: details,
+		// Refresh the nonce gap gauges
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if _, err := s.nonceLag(ctx); err != nil {
+			log.Printf("⚠️  Failed to measure nonce lag: %v\n", err)
+		}
+		cancel()
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(response)
 }
 
 func (s *Server) parseError(err error) string {
 	errMsg := err.Error()
 	if strings.Contains(errMsg, "Already minted") {
 		return "This address has already minted an NFT"
+	} else if strings.Contains(errMsg, errSoftFailure.Error()) {
+		return "Transaction was mined but the mint did not happen"
+	} else if strings.Contains(errMsg, errRequestTooExpensive.Error()) {
+		return "Transaction would cost more than the relayer allows per request"
+	} else if strings.Contains(errMsg, errReplacementCapped.Error()) {
+		return "Network gas prices rose above the relayer's limit; the transaction may still confirm"
+	} else if strings.Contains(errMsg, errSettledElsewhere.Error()) {
+		return "The relay transaction was mined and already recorded"
+	} else if strings.Contains(errMsg, errNonceConsumed.Error()) {
+		return "The relay transaction was displaced and not executed; please try again"
+	} else if strings.Contains(errMsg, errInsufficientRelayerFunds.Error()) {
+		return "Relayer has insufficient funds for this transaction"
 	} else if strings.Contains(errMsg, "insufficient funds") {
 		return "Relayer has insufficient funds"
 	} else if strings.Contains(errMsg, "nonce") {
@@ -876,6 +3180,22 @@ func (s *Server) parseError(err error) string {
 	return "Transaction failed"
 }
 
+// decodeHex decodes a hex string with or without a 0x/0X prefix, rejecting
+// odd-length input with a clear error
+func decodeHex(s string) ([]byte, error) {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+	}
+	if len(s)%2 != 0 {
+		return nil, fmt.Errorf("invalid hex string: odd length %d", len(s))
+	}
+	decoded, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hex string: %v", err)
+	}
+	return decoded, nil
+}
+
 func bytes32Equal(a, b common.Address) bool {
 	return strings.EqualFold(a.Hex(), b.Hex())
 }
@@ -886,3 +3206,85 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// parseEther converts a decimal ether amount (e.g. "0.01") to wei. The
+// amount is parsed exactly, so it may not be finer than one wei.
+func parseEther(value string) (*big.Int, error) {
+	ether, ok := new(big.Rat).SetString(value)
+	if !ok || ether.Sign() < 0 {
+		return nil, fmt.Errorf("invalid ether amount %q", value)
+	}
+	wei := ether.Mul(ether, new(big.Rat).SetInt(big.NewInt(1e18)))
+	if !wei.IsInt() {
+		return nil, fmt.Errorf("ether amount %q is finer than 1 wei", value)
+	}
+	return wei.Num(), nil
+}
+
+// parseGwei converts a decimal gwei amount (e.g. "1.5") to wei
+func parseGwei(value string) (*big.Int, error) {
+	gwei, ok := new(big.Float).SetString(value)
+	if !ok || gwei.Sign() < 0 {
+		return nil, fmt.Errorf("invalid gwei amount %q", value)
+	}
+	wei, _ := new(big.Float).Mul(gwei, big.NewFloat(1e9)).Int(nil)
+	return wei, nil
+}
+
+// parseClientGasCap parses a request's optional maxGasPriceGwei into wei
+func parseClientGasCap(value json.Number) (*big.Int, error) {
+	if value == "" {
+		return nil, nil
+	}
+	wei, err := parseGwei(value.String())
+	if err != nil {
+		return nil, err
+	}
+	if wei.Sign() == 0 {
+		return nil, fmt.Errorf("maxGasPriceGwei must be positive")
+	}
+	return wei, nil
+}
+
+// splitList splits a comma-separated list, trimming blanks
+func splitList(value string) []string {
+	var out []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value == "true" || value == "1"
+}
+
+func getEnvInt(key string, defaultValue int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s", key)
+	}
+	return n, nil
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s", key)
+	}
+	return d, nil
+}
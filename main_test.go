@@ -0,0 +1,477 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// testHub returns a hub using the built-in Hub ABI
+func testHub(t *testing.T) HubConfig {
+	t.Helper()
+	hubs, err := loadHubs("0x00000000000000000000000000000000000000a1", "", "")
+	if err != nil {
+		t.Fatalf("loadHubs: %v", err)
+	}
+	return hubs[0]
+}
+
+// testForward returns a well-formed forward for callData
+func testForward(callData []byte) Forward {
+	return Forward{
+		From:     common.HexToAddress("0x00000000000000000000000000000000000000f1"),
+		To:       common.HexToAddress("0x00000000000000000000000000000000000000b1"),
+		Value:    big.NewInt(0),
+		Nonce:    big.NewInt(7),
+		Deadline: big.NewInt(2000000000),
+		DataHash: Bytes32(crypto.Keccak256Hash(callData)),
+		Caller:   common.HexToAddress("0x00000000000000000000000000000000000000c1"),
+	}
+}
+
+func TestSignatureFormsPackIdenticalCalldata(t *testing.T) {
+	s := &Server{config: Config{FeeExecuteMethod: "executeWithFee"}}
+	hub := testHub(t)
+	callData := []byte{0xde, 0xad, 0xbe, 0xef}
+
+	var rsv SignatureRSV
+	for i := range rsv.R {
+		rsv.R[i] = byte(i + 1)
+		rsv.S[i] = byte(0xff - i)
+	}
+
+	tests := []struct {
+		name string
+		v    uint8
+	}{
+		{"v 27", 27},
+		{"v 28", 28},
+		{"recovery id 0", 0},
+		{"recovery id 1", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rsv.V = tt.v
+			flatV := tt.v
+			if flatV < 27 {
+				flatV += 27
+			}
+			flat := "0x" + hex.EncodeToString(rsv.R[:]) + hex.EncodeToString(rsv.S[:]) + hex.EncodeToString([]byte{flatV})
+
+			pack := func(req RelayRequest) []byte {
+				t.Helper()
+				sig, err := decodeHex(req.flatSignature())
+				if err != nil {
+					t.Fatalf("decodeHex: %v", err)
+				}
+				data, err := s.packExecute(req, hub, sig, callData)
+				if err != nil {
+					t.Fatalf("packExecute: %v", err)
+				}
+				return data
+			}
+
+			fwd := testForward(callData)
+			fromFlat := pack(RelayRequest{Forward: fwd, Signature: flat})
+			rsvCopy := rsv
+			fromRSV := pack(RelayRequest{Forward: fwd, SignatureRSV: &rsvCopy})
+			if !bytes.Equal(fromFlat, fromRSV) {
+				t.Fatalf("calldata differs:\n flat %x\n rsv  %x", fromFlat, fromRSV)
+			}
+		})
+	}
+}
+
+func TestClampGasPrice(t *testing.T) {
+	gwei := func(n int64) *big.Int { return new(big.Int).Mul(big.NewInt(n), big.NewInt(1e9)) }
+	floor, ceiling := gwei(2), gwei(100)
+
+	tests := []struct {
+		name      string
+		suggested *big.Int
+		want      *big.Int
+	}{
+		{"suggested below floor", gwei(1), floor},
+		{"suggested within band", gwei(30), gwei(30)},
+		{"suggested above ceiling", gwei(150), ceiling},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clampGasPrice(tt.suggested, floor, ceiling); got.Cmp(tt.want) != 0 {
+				t.Fatalf("clampGasPrice(%s) = %s, want %s", tt.suggested, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("ceiling wins over a floor above it", func(t *testing.T) {
+		if got := clampGasPrice(gwei(1), gwei(200), ceiling); got.Cmp(ceiling) != 0 {
+			t.Fatalf("got %s, want %s", got, ceiling)
+		}
+	})
+	t.Run("does not modify the suggestion", func(t *testing.T) {
+		suggested := gwei(1)
+		clampGasPrice(suggested, floor, ceiling)
+		if suggested.Cmp(gwei(1)) != 0 {
+			t.Fatalf("suggested mutated to %s", suggested)
+		}
+	})
+}
+
+// relayResponse runs req through s.relay and decodes the response
+func relayResponse(t *testing.T, s *Server, req RelayRequest, query string) (int, RelayResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	s.relay(w, httptest.NewRequest(http.MethodPost, "/relay"+query, nil), req)
+
+	var response RelayResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("decode response %q: %v", w.Body.String(), err)
+	}
+	return w.Code, response
+}
+
+// testRelayRequest returns a request with a well-formed forward and a
+// 65-byte signature
+func testRelayRequest() RelayRequest {
+	callData := []byte{0xde, 0xad, 0xbe, 0xef}
+	return RelayRequest{
+		Forward:   testForward(callData),
+		Signature: "0x" + hex.EncodeToString(make([]byte, signatureLength)),
+		CallData:  "0x" + hex.EncodeToString(callData),
+	}
+}
+
+func TestRelayRejectsZeroAddresses(t *testing.T) {
+	tests := []struct {
+		name  string
+		clear func(fwd *Forward)
+		code  string
+	}{
+		{"from", func(fwd *Forward) { fwd.From = common.Address{} }, "ZERO_FROM_ADDRESS"},
+		{"to", func(fwd *Forward) { fwd.To = common.Address{} }, "ZERO_TO_ADDRESS"},
+		{"caller", func(fwd *Forward) { fwd.Caller = common.Address{} }, "ZERO_CALLER_ADDRESS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, newFakeBackend())
+			req := testRelayRequest()
+			tt.clear(&req.Forward)
+
+			status, response := relayResponse(t, s, req, "")
+			if status != http.StatusBadRequest || response.Code != tt.code {
+				t.Fatalf("got %d %q, want %d %q", status, response.Code, http.StatusBadRequest, tt.code)
+			}
+		})
+	}
+
+	t.Run("zero to allowed with ALLOW_DEPLOY", func(t *testing.T) {
+		s := newTestServer(t, newFakeBackend())
+		s.config.AllowDeploy = true
+		req := testRelayRequest()
+		req.Forward.To = common.Address{}
+
+		_, response := relayResponse(t, s, req, "?all_errors=true")
+		for _, e := range response.Errors {
+			if e.Code == "ZERO_TO_ADDRESS" {
+				t.Fatal("zero to rejected despite ALLOW_DEPLOY")
+			}
+		}
+	})
+
+	t.Run("zero caller allowed with ALLOW_ANY_CALLER", func(t *testing.T) {
+		s := newTestServer(t, newFakeBackend())
+		s.config.AllowAnyCaller = true
+		req := testRelayRequest()
+		req.Forward.Caller = common.Address{}
+
+		_, response := relayResponse(t, s, req, "?all_errors=true")
+		for _, e := range response.Errors {
+			if e.Code == "ZERO_CALLER_ADDRESS" {
+				t.Fatal("zero caller rejected despite ALLOW_ANY_CALLER")
+			}
+		}
+	})
+}
+
+func TestDecodeHex(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    []byte
+		wantErr bool
+	}{
+		{"prefixed", "0xdeadBEEF", []byte{0xde, 0xad, 0xbe, 0xef}, false},
+		{"upper-case prefix", "0XDEADBEEF", []byte{0xde, 0xad, 0xbe, 0xef}, false},
+		{"unprefixed", "deadbeef", []byte{0xde, 0xad, 0xbe, 0xef}, false},
+		{"empty", "0x", []byte{}, false},
+		{"odd length", "0xabc", nil, true},
+		{"odd length unprefixed", "abc", nil, true},
+		{"not hex", "0xzz", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeHex(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeHex(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && !bytes.Equal(got, tt.want) {
+				t.Fatalf("decodeHex(%q) = %x, want %x", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBytes32AcceptsEitherPrefix(t *testing.T) {
+	raw := hex.EncodeToString(bytes.Repeat([]byte{0xab}, 32))
+	var prefixed, unprefixed Bytes32
+	if err := json.Unmarshal([]byte(`"0x`+raw+`"`), &prefixed); err != nil {
+		t.Fatalf("prefixed: %v", err)
+	}
+	if err := json.Unmarshal([]byte(`"`+raw+`"`), &unprefixed); err != nil {
+		t.Fatalf("unprefixed: %v", err)
+	}
+	if prefixed != unprefixed {
+		t.Fatalf("prefixed %x != unprefixed %x", prefixed, unprefixed)
+	}
+	if err := json.Unmarshal([]byte(`"0x`+raw[1:]+`"`), &prefixed); err == nil {
+		t.Fatal("odd-length bytes32 accepted")
+	}
+}
+
+func TestRelayRejectsMissingNonceOrDeadline(t *testing.T) {
+	for _, query := range []string{"", "?all_errors=true"} {
+		s := newTestServer(t, newFakeBackend())
+		req := testRelayRequest()
+		req.Forward.Deadline = nil
+
+		status, response := relayResponse(t, s, req, query)
+		if status != http.StatusBadRequest || response.Code != "MISSING_FORWARD_FIELD" {
+			t.Fatalf("%q: got %d %q, want %d MISSING_FORWARD_FIELD", query, status, response.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestCallerAllowed(t *testing.T) {
+	other := common.HexToAddress("0x00000000000000000000000000000000000000c2")
+
+	intermediate := common.HexToAddress("0x00000000000000000000000000000000000000c3")
+
+	tests := []struct {
+		name           string
+		allowAnyCaller bool
+		expectedCaller common.Address
+		caller         func(s *Server) common.Address
+		want           bool
+	}{
+		{"relayer caller", false, common.Address{}, func(s *Server) common.Address { return s.relayer() }, true},
+		{"mismatched caller", false, common.Address{}, func(s *Server) common.Address { return other }, false},
+		{"zero caller in strict mode", false, common.Address{}, func(s *Server) common.Address { return common.Address{} }, false},
+		{"zero caller with ALLOW_ANY_CALLER", true, common.Address{}, func(s *Server) common.Address { return common.Address{} }, true},
+		{"mismatched caller with ALLOW_ANY_CALLER", true, common.Address{}, func(s *Server) common.Address { return other }, false},
+		{"EXPECTED_CALLER", false, intermediate, func(s *Server) common.Address { return intermediate }, true},
+		{"relayer caller with EXPECTED_CALLER", false, intermediate, func(s *Server) common.Address { return s.relayer() }, false},
+		{"mismatched caller with EXPECTED_CALLER", false, intermediate, func(s *Server) common.Address { return other }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, newFakeBackend())
+			s.config.AllowAnyCaller = tt.allowAnyCaller
+			s.config.ExpectedCaller = tt.expectedCaller
+			if got := s.callerAllowed(tt.caller(s)); got != tt.want {
+				t.Fatalf("callerAllowed = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRelayThroughExpectedCaller(t *testing.T) {
+	intermediate := common.HexToAddress("0x00000000000000000000000000000000000000c3")
+
+	t.Run("matching caller is sent to the intermediate", func(t *testing.T) {
+		backend := newFakeBackend()
+		s := newTestServer(t, backend)
+		s.config.ExpectedCaller = intermediate
+		req := testRelayRequest()
+		req.Forward.Caller = intermediate
+
+		status, response := relayResponse(t, s, req, "")
+		if status != http.StatusOK {
+			t.Fatalf("relay: %d %+v", status, response)
+		}
+		sent := backend.sentTxs()
+		if len(sent) != 1 || *sent[0].To() != intermediate {
+			t.Fatalf("sent %d transaction(s), want one to the intermediate %s", len(sent), intermediate.Hex())
+		}
+	})
+
+	t.Run("relayer caller is rejected", func(t *testing.T) {
+		backend := newFakeBackend()
+		s := newTestServer(t, backend)
+		s.config.ExpectedCaller = intermediate
+		req := testRelayRequest()
+		req.Forward.Caller = s.relayer()
+
+		status, response := relayResponse(t, s, req, "")
+		if status != http.StatusBadRequest || response.Error != "Invalid caller address" {
+			t.Fatalf("got %d %q, want %d Invalid caller address", status, response.Error, http.StatusBadRequest)
+		}
+		if sent := backend.sentTxs(); len(sent) != 0 {
+			t.Fatalf("sent %d transaction(s) for a mismatched caller", len(sent))
+		}
+	})
+}
+
+func TestRequestHashIsContentAddressed(t *testing.T) {
+	callData := []byte{0xde, 0xad, 0xbe, 0xef}
+	base := testForward(callData)
+	sig := bytes.Repeat([]byte{0x11}, signatureLength)
+	sig[64] = 27
+	baseID := requestHash(base, sig)
+
+	if again := requestHash(testForward(callData), append([]byte(nil), sig...)); again != baseID {
+		t.Fatalf("identical requests hash differently: %s vs %s", again, baseID)
+	}
+
+	variants := []struct {
+		name   string
+		modify func(fwd *Forward, sig []byte) []byte
+	}{
+		{"from", func(fwd *Forward, sig []byte) []byte { fwd.From[19]++; return sig }},
+		{"to", func(fwd *Forward, sig []byte) []byte { fwd.To[19]++; return sig }},
+		{"value", func(fwd *Forward, sig []byte) []byte { fwd.Value = big.NewInt(1); return sig }},
+		{"space", func(fwd *Forward, sig []byte) []byte { fwd.Space = 1; return sig }},
+		{"nonce", func(fwd *Forward, sig []byte) []byte { fwd.Nonce = big.NewInt(8); return sig }},
+		{"deadline", func(fwd *Forward, sig []byte) []byte { fwd.Deadline = big.NewInt(2000000001); return sig }},
+		{"dataHash", func(fwd *Forward, sig []byte) []byte { fwd.DataHash[0]++; return sig }},
+		{"caller", func(fwd *Forward, sig []byte) []byte { fwd.Caller[19]++; return sig }},
+		{"signature", func(fwd *Forward, sig []byte) []byte { sig[0]++; return sig }},
+		{"signature length", func(fwd *Forward, sig []byte) []byte { return sig[:64] }},
+	}
+	seen := map[string]string{baseID: "base"}
+	for _, variant := range variants {
+		fwd := testForward(callData)
+		id := requestHash(fwd, variant.modify(&fwd, append([]byte(nil), sig...)))
+		if other, ok := seen[id]; ok {
+			t.Errorf("changing %s gives the same ID as %s", variant.name, other)
+		}
+		seen[id] = variant.name
+	}
+
+	// Both encodings of v identify the same signature
+	lowV := append([]byte(nil), sig...)
+	lowV[64] = 0
+	if id := requestHash(base, lowV); id != baseID {
+		t.Fatalf("v=0 and v=27 hash differently: %s vs %s", id, baseID)
+	}
+}
+
+func TestParseEther(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"1", "1000000000000000000", false},
+		{"0.1", "100000000000000000", false},
+		{"0.01", "10000000000000000", false},
+		{"1.5", "1500000000000000000", false},
+		{"0.000000000000000001", "1", false},
+		{"0", "0", false},
+		{"0.0000000000000000001", "", true},
+		{"-1", "", true},
+		{"abc", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		got, err := parseEther(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseEther(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got.String() != tt.want {
+			t.Errorf("parseEther(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRelayChecksSignatureLength(t *testing.T) {
+	tests := []struct {
+		name      string
+		length    int
+		allow1271 bool
+		reject    bool
+	}{
+		{"short", signatureLength - 1, false, true},
+		{"long", signatureLength + 1, false, true},
+		{"empty", 0, false, true},
+		{"correct", signatureLength, false, false},
+		{"contract wallet signature", 2 * signatureLength, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, newFakeBackend())
+			s.config.Allow1271 = tt.allow1271
+			req := testRelayRequest()
+			req.Signature = "0x" + hex.EncodeToString(make([]byte, tt.length))
+
+			status, response := relayResponse(t, s, req, "")
+			rejected := response.Code == "INVALID_SIGNATURE_LENGTH"
+			if rejected != tt.reject {
+				t.Fatalf("got %d %q %q, want rejected=%v", status, response.Code, response.Error, tt.reject)
+			}
+			if rejected && status != http.StatusBadRequest {
+				t.Fatalf("status = %d, want %d", status, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestLoadConfigHomesteadSignerNeedsLegacyTransactions(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		ok   bool
+	}{
+		{"legacy", nil, true},
+		{"dynamic fees", map[string]string{"DYNAMIC_FEES": "1"}, false},
+		{"access lists", map[string]string{"USE_ACCESS_LIST": "true"}, false},
+		{"both disabled explicitly", map[string]string{"DYNAMIC_FEES": "false", "USE_ACCESS_LIST": "0"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("RPC_URL", "http://127.0.0.1:8545")
+			t.Setenv("RELAYER_PRIVATE_KEY", "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318")
+			t.Setenv("HUB_ADDRESS", "0x00000000000000000000000000000000000000a1")
+			t.Setenv("NFT_CONTRACT", "0x00000000000000000000000000000000000000b1")
+			t.Setenv("CHAIN_ID", "1337")
+			t.Setenv("SIGNER_TYPE", signerHomestead)
+			t.Setenv("DYNAMIC_FEES", "")
+			t.Setenv("USE_ACCESS_LIST", "")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			config, err := loadConfig()
+			if tt.ok {
+				if err != nil {
+					t.Fatalf("loadConfig: %v", err)
+				}
+				if config.DynamicFees || config.UseAccessList {
+					t.Fatal("homestead config enables typed transactions")
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), "legacy transactions only") {
+				t.Fatalf("err = %v, want the legacy-only error", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// MaintenanceState is the maintenance toggle as set via the admin API
+type MaintenanceState struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message,omitempty"`
+}
+
+// Maintenance holds the maintenance toggle, optionally persisted to a file
+// so it survives restarts
+type Maintenance struct {
+	mu    sync.RWMutex
+	state MaintenanceState
+	path  string
+}
+
+// NewMaintenance loads the persisted state from path, if set
+func NewMaintenance(path string) (*Maintenance, error) {
+	m := &Maintenance{path: path}
+	if path == "" {
+		return m, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read maintenance state: %v", err)
+	}
+	if err := json.Unmarshal(raw, &m.state); err != nil {
+		return nil, fmt.Errorf("failed to parse maintenance state: %v", err)
+	}
+	if m.state.Enabled {
+		log.Printf("🚧 Starting in maintenance mode: %s\n", m.state.Message)
+	}
+	return m, nil
+}
+
+// State returns the current maintenance state
+func (m *Maintenance) State() MaintenanceState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state
+}
+
+// Set updates the maintenance state and persists it
+func (m *Maintenance) Set(state MaintenanceState) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.state = state
+	if m.path == "" {
+		return nil
+	}
+	raw, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(m.path, raw, 0o600)
+}
+
+// maintenanceHandler toggles maintenance mode
+func (s *Server) maintenanceHandler(w http.ResponseWriter, r *http.Request) {
+	var state MaintenanceState
+	if err := json.NewDecoder(r.Body).Decode(&state); err != nil {
+		s.sendError(w, http.StatusBadRequest, "Invalid request body", err.Error())
+		return
+	}
+	if state.Enabled && state.Message == "" {
+		state.Message = s.config.MaintenanceMessage
+	}
+
+	if err := s.maintenance.Set(state); err != nil {
+		log.Printf("⚠️  Failed to persist maintenance state: %v\n", err)
+	}
+	if state.Enabled {
+		log.Printf("🚧 Maintenance mode enabled: %s\n", state.Message)
+	} else {
+		log.Println("✅ Maintenance mode disabled")
+	}
+	s.metrics.Set("relayer_maintenance", boolToFloat(state.Enabled))
+
+	writeJSON(w, http.StatusOK, state)
+}
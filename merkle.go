@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// MerkleBatchRequest submits several forwards authorized by a single user
+// signature over the merkle root of their leaves.
+//
+// Each leaf is keccak256 of the ABI-encoded Forward tuple, and proofs use
+// sorted-pair hashing (keccak256 of the smaller node followed by the larger),
+// matching OpenZeppelin's MerkleProof.
+type MerkleBatchRequest struct {
+	Root       Bytes32           `json:"root"`
+	Signature  string            `json:"signature"`
+	HubVersion string            `json:"hubVersion,omitempty"`
+	Items      []MerkleBatchItem `json:"items"`
+}
+
+// MerkleBatchItem is one forward in a merkle batch with its inclusion proof
+type MerkleBatchItem struct {
+	Forward  Forward   `json:"forward"`
+	CallData string    `json:"callData"`
+	Proof    []Bytes32 `json:"proof"`
+}
+
+// MerkleBatchResponse holds a result per submitted item, in request order
+type MerkleBatchResponse struct {
+	Success bool            `json:"success"`
+	Results []RelayResponse `json:"results"`
+}
+
+// merkleLeaf hashes a forward into its merkle leaf
+func merkleLeaf(hub HubConfig, fwd Forward) (common.Hash, error) {
+	encoded, err := hub.ABI.Methods["execute"].Inputs[:1].Pack(fwd.Tuple())
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(encoded), nil
+}
+
+// verifyMerkleProof checks leaf's inclusion under root using sorted pairs
+func verifyMerkleProof(leaf common.Hash, proof []Bytes32, root common.Hash) bool {
+	computed := leaf.Bytes()
+	for _, node := range proof {
+		if bytes.Compare(computed, node[:]) <= 0 {
+			computed = crypto.Keccak256(computed, node[:])
+		} else {
+			computed = crypto.Keccak256(node[:], computed)
+		}
+	}
+	return common.BytesToHash(computed) == root
+}
+
+// validateMerkleItem runs the /relay forward checks on one item and verifies
+// its proof, returning the decoded callData. Failures are recorded in v.
+func (s *Server) validateMerkleItem(v *validation, hub HubConfig, root common.Hash, requestID string, item MerkleBatchItem) ([]byte, bool) {
+	fwd := item.Forward
+	if s.checkForwardFields(v, fwd) {
+		return nil, false
+	}
+	if s.isProcessed(requestID) {
+		v.reject(http.StatusBadRequest, "", "This request has already been processed", "")
+		return nil, false
+	}
+	callData, stop := s.checkForward(v, hub, fwd, item.CallData)
+	if stop {
+		return nil, false
+	}
+
+	leaf, err := merkleLeaf(hub, fwd)
+	if err != nil {
+		v.reject(http.StatusBadRequest, "", "Failed to encode merkle leaf", err.Error())
+		return nil, false
+	}
+	if !verifyMerkleProof(leaf, item.Proof, root) {
+		v.reject(http.StatusBadRequest, "INVALID_MERKLE_PROOF", "Invalid merkle proof", "")
+		return nil, false
+	}
+
+	return callData, true
+}
+
+// merkleBatchHandler validates every proof up front and then submits each
+// forward through the hub's merkle execute method, one transaction per item
+func (s *Server) merkleBatchHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.enterGlobal() {
+		s.sendRetryAfter(w, http.StatusServiceUnavailable, "Relayer is at capacity. Please try again later.", 1)
+		return
+	}
+	defer s.leaveGlobal()
+
+	var req MerkleBatchRequest
+	if err := s.decodeBody(w, r, &req); err != nil {
+		s.sendBodyError(w, err)
+		return
+	}
+
+	if len(req.Items) == 0 || req.Signature == "" {
+		s.sendError(w, http.StatusBadRequest, "Missing required fields: root, signature, items", "")
+		return
+	}
+	if len(req.Items) > s.config.MaxBatchSize {
+		s.sendError(w, http.StatusBadRequest, fmt.Sprintf("Batch exceeds the maximum of %d items", s.config.MaxBatchSize), "")
+		return
+	}
+
+	hub, ok := s.resolveHub(req.HubVersion)
+	if !ok {
+		s.sendError(w, http.StatusBadRequest, "Unknown hub version", req.HubVersion)
+		return
+	}
+	method, ok := hub.ABI.Methods[s.config.MerkleExecuteMethod]
+	if !ok {
+		s.sendError(w, http.StatusBadRequest, "Hub does not support merkle batches", hub.Version)
+		return
+	}
+
+	sigBytes, err := decodeHex(req.Signature)
+	if err != nil {
+		s.sendError(w, http.StatusBadRequest, "Invalid signature format", err.Error())
+		return
+	}
+	if len(sigBytes) != signatureLength {
+		s.sendErrorCode(w, http.StatusBadRequest, "INVALID_SIGNATURE_LENGTH", fmt.Sprintf("Invalid signature length: expected %d bytes, got %d", signatureLength, len(sigBytes)), "")
+		return
+	}
+
+	// One signature authorizes the whole batch, so all items share a signer
+	signer := req.Items[0].Forward.From
+	for _, item := range req.Items {
+		if item.Forward.From != signer {
+			s.sendError(w, http.StatusBadRequest, "All batch items must have the same from address", "")
+			return
+		}
+	}
+
+	if s.reverts.IsBlocked(signer.Hex()) {
+		s.sendError(w, http.StatusForbidden, "Address temporarily blocked due to repeated reverts", "")
+		return
+	}
+	if !s.breaker.Allow() {
+		s.sendRetryAfter(w, http.StatusServiceUnavailable, "Relaying is temporarily paused. Please try again later.", int(s.config.BreakerCooldown.Seconds()))
+		return
+	}
+	if ok, retryAfter := s.checkRateLimit(signer.Hex()); !ok {
+		s.sendRetryAfter(w, http.StatusTooManyRequests, "Too many requests. Please try again later.", retryAfter)
+		return
+	}
+
+	root := common.BytesToHash(req.Root[:])
+	log.Printf("\n🌳 Merkle batch from %s: %d items under root %s\n", signer.Hex(), len(req.Items), root.Hex())
+
+	// Validate every item and proof before spending any gas
+	callDatas := make([][]byte, len(req.Items))
+	requestIDs := make([]string, len(req.Items))
+	for i, item := range req.Items {
+		requestIDs[i] = requestHash(item.Forward, sigBytes)
+		v := s.newItemValidation()
+		callData, ok := s.validateMerkleItem(v, hub, root, requestIDs[i], item)
+		if !ok {
+			failure, _ := v.first()
+			log.Printf("❌ Merkle item %d invalid: %s\n", i, failure.Error)
+			s.sendErrorCode(w, failure.status, failure.Code, fmt.Sprintf("Invalid batch item %d: %s", i, failure.Error), failure.Details)
+			return
+		}
+		callDatas[i] = callData
+	}
+	log.Println("✅ All merkle proofs verified")
+
+	if s.checkGasPrice(w, nil) {
+		return
+	}
+
+	response := MerkleBatchResponse{Success: true, Results: make([]RelayResponse, len(req.Items))}
+	for i, item := range req.Items {
+		requestID := requestIDs[i]
+		if s.isProcessed(requestID) {
+			response.Success = false
+			response.Results[i] = RelayResponse{Success: false, Error: "This request has already been processed"}
+			continue
+		}
+		if ok, _ := s.dailyCap.Allow(signer.Hex()); !ok {
+			response.Success = false
+			response.Results[i] = RelayResponse{Success: false, Code: "DAILY_CAP_EXCEEDED", Error: "Daily relay limit reached for this address"}
+			continue
+		}
+
+		proof := make([][32]byte, len(item.Proof))
+		for j, node := range item.Proof {
+			proof[j] = node
+		}
+
+		data, err := hub.ABI.Pack(method.Name, item.Forward.Tuple(), callDatas[i], [32]byte(req.Root), proof, sigBytes)
+		if err != nil {
+			response.Success = false
+			response.Results[i] = RelayResponse{Success: false, Error: "Failed to encode transaction", Details: err.Error()}
+			continue
+		}
+		log.Printf("📦 Merkle item %d packed: 0x%s...\n", i, hex.EncodeToString(data[:min(50, len(data))]))
+
+		s.acquireSpace(signer.Hex(), item.Forward.Space)
+		result, err := s.submitWithRetry(data, hub, item.Forward.To, &submission{from: signer, requestID: requestID})
+		s.releaseSpace(signer.Hex(), item.Forward.Space)
+		if err != nil {
+			log.Printf("❌ Merkle item %d failed: %v\n", i, err)
+			if errors.Is(err, errTxReverted) {
+				s.recordRevert(signer.Hex())
+			}
+			if s.breaker.RecordFailure() {
+				log.Printf("🚨 ALERT: circuit breaker tripped after %d consecutive failures\n", s.config.BreakerThreshold)
+				s.metrics.Inc("relay_breaker_trips_total")
+			}
+			response.Success = false
+			response.Results[i] = RelayResponse{Success: false, Error: s.parseError(err), Details: err.Error()}
+			continue
+		}
+
+		s.markProcessed(requestID)
+		s.dailyCap.Record(signer.Hex())
+		s.mintedCache.Invalidate(s.config.NFTContract, signer)
+		s.breaker.RecordSuccess()
+		s.recordGasEconomics(result)
+		s.watchForReorg(signer, result)
+		response.Results[i] = RelayResponse{
+			Success:         true,
+			TxHash:          result.TxHash,
+			TransactionHash: result.TxHash,
+			BlockNumber:     result.BlockNumber,
+			GasUsed:         result.GasUsed.String(),
+			Attempts:        result.Attempts,
+			Simulated:       result.Simulated,
+		}
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// checkMerkleSupport ensures at least one hub exposes the merkle method
+func checkMerkleSupport(hubs []HubConfig, method string) error {
+	for _, hub := range hubs {
+		if m, ok := hub.ABI.Methods[method]; ok && len(m.Inputs) == 5 {
+			return nil
+		}
+	}
+	return fmt.Errorf("MERKLE_BATCH requires a hub ABI with a 5-argument %q method (forward, callData, root, proof, signature); set HUB_ABI_FILES", method)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"net/http"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// testMerkleBatch returns two items from the same signer for s along with
+// the root both proofs lead to
+func testMerkleBatch(t *testing.T, s *Server) (common.Hash, []MerkleBatchItem) {
+	t.Helper()
+	hub := testHub(t)
+	callData := []byte{0xde, 0xad, 0xbe, 0xef}
+
+	items := make([]MerkleBatchItem, 2)
+	leaves := make([]common.Hash, 2)
+	for i := range items {
+		fwd := testForward(callData)
+		fwd.Nonce = big.NewInt(int64(i))
+		fwd.Caller = s.relayer()
+		leaf, err := merkleLeaf(hub, fwd)
+		if err != nil {
+			t.Fatalf("merkleLeaf: %v", err)
+		}
+		items[i] = MerkleBatchItem{Forward: fwd, CallData: "0x" + hex.EncodeToString(callData)}
+		leaves[i] = leaf
+	}
+
+	items[0].Proof = []Bytes32{Bytes32(leaves[1])}
+	items[1].Proof = []Bytes32{Bytes32(leaves[0])}
+	first, second := leaves[0], leaves[1]
+	if bytes.Compare(first[:], second[:]) > 0 {
+		first, second = second, first
+	}
+	root := crypto.Keccak256Hash(first[:], second[:])
+	return root, items
+}
+
+func TestValidateMerkleItem(t *testing.T) {
+	sig := make([]byte, signatureLength)
+
+	tests := []struct {
+		name   string
+		setup  func(s *Server, backend *fakeBackend, item *MerkleBatchItem)
+		status int
+		code   string
+		error  string
+	}{
+		{
+			name:  "valid item",
+			setup: func(s *Server, backend *fakeBackend, item *MerkleBatchItem) {},
+		},
+		{
+			name:   "missing deadline",
+			setup:  func(s *Server, backend *fakeBackend, item *MerkleBatchItem) { item.Forward.Deadline = nil },
+			status: http.StatusBadRequest,
+			code:   "MISSING_FORWARD_FIELD",
+		},
+		{
+			name:   "missing nonce",
+			setup:  func(s *Server, backend *fakeBackend, item *MerkleBatchItem) { item.Forward.Nonce = nil },
+			status: http.StatusBadRequest,
+			code:   "MISSING_FORWARD_FIELD",
+		},
+		{
+			name:   "already minted",
+			setup:  func(s *Server, backend *fakeBackend, item *MerkleBatchItem) { backend.views["minted(address)"] = true },
+			status: http.StatusBadRequest,
+			error:  "You already minted an NFT",
+		},
+		{
+			name: "already processed",
+			setup: func(s *Server, backend *fakeBackend, item *MerkleBatchItem) {
+				s.markProcessed(requestHash(item.Forward, sig))
+			},
+			status: http.StatusBadRequest,
+			error:  "This request has already been processed",
+		},
+		{
+			name:   "proof for another leaf",
+			setup:  func(s *Server, backend *fakeBackend, item *MerkleBatchItem) { item.Proof = nil },
+			status: http.StatusBadRequest,
+			code:   "INVALID_MERKLE_PROOF",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newFakeBackend()
+			s := newTestServer(t, backend)
+			root, items := testMerkleBatch(t, s)
+			item := items[0]
+			tt.setup(s, backend, &item)
+
+			v := s.newItemValidation()
+			callData, ok := s.validateMerkleItem(v, testHub(t), root, requestHash(item.Forward, sig), item)
+			failure, failed := v.first()
+			if tt.status == 0 {
+				if !ok || failed || len(callData) == 0 {
+					t.Fatalf("valid item rejected: %+v", failure)
+				}
+				return
+			}
+			if ok || !failed {
+				t.Fatal("invalid item accepted")
+			}
+			if failure.status != tt.status || failure.Code != tt.code || (tt.error != "" && failure.Error != tt.error) {
+				t.Fatalf("got %d %q %q, want %d %q %q", failure.status, failure.Code, failure.Error, tt.status, tt.code, tt.error)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// defaultBuckets are the histogram buckets used when none are specified
+var defaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// histogram accumulates observations into cumulative buckets
+type histogram struct {
+	buckets []float64
+	counts  []uint64
+	sum     float64
+	count   uint64
+}
+
+// Metrics is a minimal in-process registry exposed in the Prometheus text format
+type Metrics struct {
+	mu         sync.Mutex
+	path       string
+	counters   map[string]map[string]float64
+	gauges     map[string]map[string]float64
+	histograms map[string]map[string]*histogram
+}
+
+// NewMetrics creates an empty metrics registry
+func NewMetrics() *Metrics {
+	return &Metrics{
+		counters:   make(map[string]map[string]float64),
+		gauges:     make(map[string]map[string]float64),
+		histograms: make(map[string]map[string]*histogram),
+	}
+}
+
+// Restore loads counters saved by the previous run from path and saves them
+// back there on Close, so totals survive a restart. Gauges and histograms
+// start fresh.
+func (m *Metrics) Restore(path string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.path = path
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read metrics file: %v", err)
+	}
+	if err := json.Unmarshal(raw, &m.counters); err != nil {
+		return fmt.Errorf("failed to parse metrics file: %v", err)
+	}
+	return nil
+}
+
+// Close saves counters when Restore configured a file
+func (m *Metrics) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.path == "" {
+		return nil
+	}
+	raw, err := json.Marshal(m.counters)
+	if err != nil {
+		return fmt.Errorf("failed to encode metrics: %v", err)
+	}
+	if err := writeFileAtomic(m.path, raw); err != nil {
+		return fmt.Errorf("failed to write metrics file: %v", err)
+	}
+	return nil
+}
+
+// Inc increments a counter by one. Labels are given as key, value pairs.
+func (m *Metrics) Inc(name string, labels ...string) {
+	m.Add(name, 1, labels...)
+}
+
+// Add increments a counter by delta
+func (m *Metrics) Add(name string, delta float64, labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	series := m.counters[name]
+	if series == nil {
+		series = make(map[string]float64)
+		m.counters[name] = series
+	}
+	series[labelKey(labels)] += delta
+}
+
+// Set sets a gauge to value
+func (m *Metrics) Set(name string, value float64, labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	series := m.gauges[name]
+	if series == nil {
+		series = make(map[string]float64)
+		m.gauges[name] = series
+	}
+	series[labelKey(labels)] = value
+}
+
+// Observe records a histogram observation
+func (m *Metrics) Observe(name string, value float64, labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	series := m.histograms[name]
+	if series == nil {
+		series = make(map[string]*histogram)
+		m.histograms[name] = series
+	}
+	key := labelKey(labels)
+	h := series[key]
+	if h == nil {
+		h = &histogram{buckets: defaultBuckets, counts: make([]uint64, len(defaultBuckets))}
+		series[key] = h
+	}
+	for i, upper := range h.buckets {
+		if value <= upper {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+// ServeHTTP writes all series in the Prometheus text exposition format
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	for _, name := range sortedKeys(m.counters) {
+		fmt.Fprintf(w, "# TYPE %s counter\n", name)
+		for _, key := range sortedKeys(m.counters[name]) {
+			fmt.Fprintf(w, "%s%s %v\n", name, key, m.counters[name][key])
+		}
+	}
+
+	for _, name := range sortedKeys(m.gauges) {
+		fmt.Fprintf(w, "# TYPE %s gauge\n", name)
+		for _, key := range sortedKeys(m.gauges[name]) {
+			fmt.Fprintf(w, "%s%s %v\n", name, key, m.gauges[name][key])
+		}
+	}
+
+	for _, name := range sortedKeys(m.histograms) {
+		fmt.Fprintf(w, "# TYPE %s histogram\n", name)
+		for _, key := range sortedKeys(m.histograms[name]) {
+			h := m.histograms[name][key]
+			for i, upper := range h.buckets {
+				fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(key, "le", fmt.Sprintf("%v", upper)), h.counts[i])
+			}
+			fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(key, "le", "+Inf"), h.count)
+			fmt.Fprintf(w, "%s_sum%s %v\n", name, key, h.sum)
+			fmt.Fprintf(w, "%s_count%s %d\n", name, key, h.count)
+		}
+	}
+}
+
+// labelKey renders key, value pairs as a Prometheus label set
+func labelKey(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	parts := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		parts = append(parts, fmt.Sprintf("%s=%q", labels[i], labels[i+1]))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// withLabel appends an extra label to a rendered label set
+func withLabel(key, name, value string) string {
+	extra := fmt.Sprintf("%s=%q", name, value)
+	if key == "" {
+		return "{" + extra + "}"
+	}
+	return strings.TrimSuffix(key, "}") + "," + extra + "}"
+}
+
+// boolToFloat converts a boolean to a 0/1 gauge value
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
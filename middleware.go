@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"runtime/debug"
+	"strings"
+)
+
+// prettyWriter marks a response whose JSON should be indented
+type prettyWriter struct {
+	http.ResponseWriter
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (pw *prettyWriter) Unwrap() http.ResponseWriter {
+	return pw.ResponseWriter
+}
+
+// prettyMiddleware enables indented JSON for ?pretty=true or an X-Pretty
+// header, for debugging and CLI use
+func prettyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("pretty") == "true" || r.Header.Get("X-Pretty") != "" {
+			w = &prettyWriter{ResponseWriter: w}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isPretty reports whether w, or a writer it wraps, asked for indented JSON
+func isPretty(w http.ResponseWriter) bool {
+	for {
+		if _, ok := w.(*prettyWriter); ok {
+			return true
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return false
+		}
+		w = u.Unwrap()
+	}
+}
+
+// requestIDKey is the context key holding the request ID
+type requestIDKey struct{}
+
+// requestIDMiddleware tags each request with an ID, reusing the client's
+// X-Request-ID when present, and echoes it in the response
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if id == "" || len(id) > 64 {
+			buf := make([]byte, 8)
+			rand.Read(buf)
+			id = hex.EncodeToString(buf)
+		}
+
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// requestID returns the ID assigned by requestIDMiddleware
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+// recoverMiddleware turns a handler panic into a 500 JSON response instead
+// of a dropped connection
+func (s *Server) recoverMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if p := recover(); p != nil {
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+				log.Printf("🔥 PANIC in %s %s [request %s]: %v\n%s\n", r.Method, r.URL.Path, requestID(r), p, debug.Stack())
+				s.metrics.Inc("http_panics_total")
+
+				writeJSON(w, http.StatusInternalServerError, RelayResponse{
+					Success: false,
+					Error:   "Internal server error",
+					Details: "request " + requestID(r),
+				})
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireHTTPSMiddleware refuses plaintext requests when REQUIRE_HTTPS is set.
+// A request counts as secure if TLS terminated here or a proxy forwarded it
+// with X-Forwarded-Proto: https. Plaintext requests are 308-redirected when
+// HTTPS_REDIRECT is set and rejected otherwise. /health stays reachable so
+// load balancer probes on the plaintext port keep working.
+func (s *Server) requireHTTPSMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secure := r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+		if secure || r.URL.Path == "/health" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if s.config.HTTPSRedirect {
+			http.Redirect(w, r, "https://"+r.Host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+			return
+		}
+		s.sendErrorCode(w, http.StatusForbidden, "HTTPS_REQUIRED", "HTTPS is required", "")
+	})
+}
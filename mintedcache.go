@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// mintedEntry is a cached minted() result
+type mintedEntry struct {
+	contract  common.Address
+	minted    bool
+	fetchedAt time.Time
+}
+
+// MintedCache caches minted() lookups keyed by contract and address. A true
+// result never expires since an address can't un-mint; a false result is
+// kept for the contract's false TTL so that it's re-checked soon after a
+// mint.
+type MintedCache struct {
+	mu        sync.Mutex
+	entries   map[string]mintedEntry
+	falseTTL  time.Duration
+	falseTTLs map[common.Address]time.Duration
+	hits      uint64
+	misses    uint64
+}
+
+// NewMintedCache creates a cache. falseTTLs overrides falseTTL for specific
+// contracts. A TTL of 0 disables caching of false results.
+func NewMintedCache(falseTTL time.Duration, falseTTLs map[common.Address]time.Duration) *MintedCache {
+	return &MintedCache{
+		entries:   make(map[string]mintedEntry),
+		falseTTL:  falseTTL,
+		falseTTLs: falseTTLs,
+	}
+}
+
+// parseMintedFalseTTLs parses MINTED_FALSE_TTLS entries of the form
+// contract:ttl
+func parseMintedFalseTTLs(entries []string) (map[common.Address]time.Duration, error) {
+	ttls := make(map[common.Address]time.Duration, len(entries))
+	for _, entry := range entries {
+		addr, ttlStr, _ := strings.Cut(strings.TrimSpace(entry), ":")
+		if !common.IsHexAddress(addr) {
+			return nil, fmt.Errorf("invalid MINTED_FALSE_TTLS entry %q", entry)
+		}
+		ttl, err := time.ParseDuration(ttlStr)
+		if err != nil || ttl < 0 {
+			return nil, fmt.Errorf("invalid TTL in MINTED_FALSE_TTLS entry %q", entry)
+		}
+		ttls[common.HexToAddress(addr)] = ttl
+	}
+	return ttls, nil
+}
+
+// falseTTLFor returns how long a false result for contract is kept
+func (c *MintedCache) falseTTLFor(contract common.Address) time.Duration {
+	if ttl, ok := c.falseTTLs[contract]; ok {
+		return ttl
+	}
+	return c.falseTTL
+}
+
+// mintedKey identifies a cache entry
+func mintedKey(contract, address common.Address) string {
+	return contract.Hex() + ":" + address.Hex()
+}
+
+// Get returns the cached result, if any, and records a hit or miss
+func (c *MintedCache) Get(contract, address common.Address) (minted bool, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, found := c.entries[mintedKey(contract, address)]
+	if found && (entry.minted || time.Since(entry.fetchedAt) < c.falseTTLFor(contract)) {
+		c.hits++
+		return entry.minted, true
+	}
+	c.misses++
+	return false, false
+}
+
+// Set stores a freshly fetched result
+func (c *MintedCache) Set(contract, address common.Address, minted bool) {
+	if !minted && c.falseTTLFor(contract) <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[mintedKey(contract, address)] = mintedEntry{contract: contract, minted: minted, fetchedAt: time.Now()}
+}
+
+// Invalidate drops a cached false result, e.g. after a successful relay
+func (c *MintedCache) Invalidate(contract, address common.Address) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := mintedKey(contract, address)
+	if entry, ok := c.entries[key]; ok && !entry.minted {
+		delete(c.entries, key)
+	}
+}
+
+// HitRate returns the fraction of lookups served from the cache
+func (c *MintedCache) HitRate() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	total := c.hits + c.misses
+	if total == 0 {
+		return 0
+	}
+	return float64(c.hits) / float64(total)
+}
+
+// Cleanup drops expired false results
+func (c *MintedCache) Cleanup(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, entry := range c.entries {
+		if !entry.minted && now.Sub(entry.fetchedAt) >= c.falseTTLFor(entry.contract) {
+			delete(c.entries, key)
+		}
+	}
+}
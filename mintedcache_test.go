@@ -0,0 +1,115 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// age backdates the cached entry for contract and address by d
+func (c *MintedCache) age(contract, address common.Address, d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	key := mintedKey(contract, address)
+	entry := c.entries[key]
+	entry.fetchedAt = entry.fetchedAt.Add(-d)
+	c.entries[key] = entry
+}
+
+func TestMintedCacheFalseTTL(t *testing.T) {
+	fallback := common.HexToAddress("0x00000000000000000000000000000000000000b1")
+	overridden := common.HexToAddress("0x00000000000000000000000000000000000000b2")
+	uncached := common.HexToAddress("0x00000000000000000000000000000000000000b3")
+	user := common.HexToAddress("0x00000000000000000000000000000000000000f1")
+
+	tests := []struct {
+		name     string
+		contract common.Address
+		minted   bool
+		age      time.Duration
+		want     bool
+	}{
+		{"fallback TTL fresh", fallback, false, 30 * time.Second, true},
+		{"fallback TTL expired", fallback, false, 2 * time.Minute, false},
+		{"override fresh", overridden, false, 5 * time.Second, true},
+		{"override shorter than fallback", overridden, false, 30 * time.Second, false},
+		{"zero TTL override never caches false", uncached, false, 0, false},
+		{"zero TTL override still caches true", uncached, true, 24 * time.Hour, true},
+		{"true never expires", fallback, true, 24 * time.Hour, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewMintedCache(time.Minute, map[common.Address]time.Duration{
+				overridden: 10 * time.Second,
+				uncached:   0,
+			})
+			c.Set(tt.contract, user, tt.minted)
+			c.age(tt.contract, user, tt.age)
+
+			minted, ok := c.Get(tt.contract, user)
+			if ok != tt.want {
+				t.Fatalf("cached = %v, want %v", ok, tt.want)
+			}
+			if ok && minted != tt.minted {
+				t.Fatalf("minted = %v, want %v", minted, tt.minted)
+			}
+		})
+	}
+}
+
+func TestMintedCacheZeroTTL(t *testing.T) {
+	contract := common.HexToAddress("0x00000000000000000000000000000000000000b1")
+	user := common.HexToAddress("0x00000000000000000000000000000000000000f1")
+	c := NewMintedCache(0, nil)
+
+	c.Set(contract, user, false)
+	if _, ok := c.Get(contract, user); ok {
+		t.Fatal("false result cached with a zero TTL")
+	}
+	c.Set(contract, user, true)
+	if minted, ok := c.Get(contract, user); !ok || !minted {
+		t.Fatal("true result not cached with a zero TTL")
+	}
+	if rate := c.HitRate(); rate != 0.5 {
+		t.Fatalf("hit rate = %v, want 0.5", rate)
+	}
+}
+
+func TestMintedCacheCleanupUsesContractTTL(t *testing.T) {
+	fallback := common.HexToAddress("0x00000000000000000000000000000000000000b1")
+	overridden := common.HexToAddress("0x00000000000000000000000000000000000000b2")
+	user := common.HexToAddress("0x00000000000000000000000000000000000000f1")
+	c := NewMintedCache(time.Minute, map[common.Address]time.Duration{overridden: 10 * time.Second})
+
+	c.Set(fallback, user, false)
+	c.Set(overridden, user, false)
+	c.Cleanup(time.Now().Add(30 * time.Second))
+
+	if _, ok := c.entries[mintedKey(overridden, user)]; ok {
+		t.Fatal("expired override entry kept")
+	}
+	if _, ok := c.entries[mintedKey(fallback, user)]; !ok {
+		t.Fatal("fresh fallback entry dropped")
+	}
+}
+
+func TestParseMintedFalseTTLs(t *testing.T) {
+	contract := common.HexToAddress("0x00000000000000000000000000000000000000b1")
+	ttls, err := parseMintedFalseTTLs([]string{" " + contract.Hex() + ":30s", "0x00000000000000000000000000000000000000b2:0s"})
+	if err != nil {
+		t.Fatalf("parseMintedFalseTTLs: %v", err)
+	}
+	if ttls[contract] != 30*time.Second {
+		t.Fatalf("ttl = %v, want 30s", ttls[contract])
+	}
+	if ttl, ok := ttls[common.HexToAddress("0x00000000000000000000000000000000000000b2")]; !ok || ttl != 0 {
+		t.Fatalf("zero TTL entry = %v, %v", ttl, ok)
+	}
+
+	for _, entry := range []string{"not-an-address:30s", contract.Hex() + ":soon", contract.Hex() + ":-1s", contract.Hex()} {
+		if _, err := parseMintedFalseTTLs([]string{entry}); err == nil {
+			t.Errorf("accepted %q", entry)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// multicall3ABI is the aggregate3 entrypoint of the Multicall3 contract
+const multicall3ABI = `[
+	{
+		"inputs": [
+			{
+				"components": [
+					{"name": "target", "type": "address"},
+					{"name": "allowFailure", "type": "bool"},
+					{"name": "callData", "type": "bytes"}
+				],
+				"name": "calls",
+				"type": "tuple[]"
+			}
+		],
+		"name": "aggregate3",
+		"outputs": [
+			{
+				"components": [
+					{"name": "success", "type": "bool"},
+					{"name": "returnData", "type": "bytes"}
+				],
+				"name": "returnData",
+				"type": "tuple[]"
+			}
+		],
+		"stateMutability": "payable",
+		"type": "function"
+	}
+]`
+
+// multicallCall is one aggregate3 call
+type multicallCall struct {
+	Target       common.Address
+	AllowFailure bool
+	CallData     []byte
+}
+
+// multicallResult is one aggregate3 result
+type multicallResult struct {
+	Success    bool
+	ReturnData []byte
+}
+
+// viewCall is a single bool-returning view read
+type viewCall struct {
+	contract abi.ABI
+	address  common.Address
+	method   string
+	args     []interface{}
+	result   *bool
+}
+
+// ValidationReads are the on-chain reads the relay pipeline validates against
+type ValidationReads struct {
+	Minted        bool
+	NonceUsed     bool
+	CallerAllowed bool
+	// CallerChecked is false when the hub has no isCallerAllowed
+	CallerChecked bool
+}
+
+// readValidationState reads minted(from), isNonceUsed(from, space, nonce) and
+// isCallerAllowed(expected caller), in one Multicall3 round-trip when
+// MULTICALL_ADDRESS is set and sequentially otherwise. minted is skipped
+// when checkMinted is false or the answer is cached.
+func (s *Server) readValidationState(hub HubConfig, f Forward, checkMinted bool) (ValidationReads, error) {
+	nft, err := abi.JSON(strings.NewReader(nftABI))
+	if err != nil {
+		return ValidationReads{}, err
+	}
+
+	var reads ValidationReads
+	var calls []viewCall
+	mintedFetched := false
+	if checkMinted {
+		if minted, ok := s.mintedCache.Get(s.config.NFTContract, f.From); ok {
+			reads.Minted = minted
+			s.metrics.Inc("minted_cache_lookups_total", "result", "hit")
+		} else {
+			calls = append(calls, viewCall{nft, s.config.NFTContract, "minted", []interface{}{f.From}, &reads.Minted})
+			mintedFetched = true
+			s.metrics.Inc("minted_cache_lookups_total", "result", "miss")
+		}
+		s.metrics.Set("minted_cache_hit_ratio", s.mintedCache.HitRate())
+	}
+	if _, ok := hub.ABI.Methods["isNonceUsed"]; ok {
+		calls = append(calls, viewCall{hub.ABI, hub.Address, "isNonceUsed", []interface{}{f.From, f.Space, bigOrZero(f.Nonce)}, &reads.NonceUsed})
+	}
+	if _, ok := hub.ABI.Methods["isCallerAllowed"]; ok {
+		reads.CallerChecked = true
+		calls = append(calls, viewCall{hub.ABI, hub.Address, "isCallerAllowed", []interface{}{s.expectedCaller()}, &reads.CallerAllowed})
+	}
+	if len(calls) == 0 {
+		return reads, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	mode := "sequential"
+	start := time.Now()
+	if s.config.MulticallAddress != (common.Address{}) {
+		mode = "multicall"
+		err = s.multicallViews(ctx, calls)
+	} else {
+		err = s.sequentialViews(ctx, calls)
+	}
+	elapsed := time.Since(start)
+	s.metrics.Observe("validation_reads_duration_seconds", elapsed.Seconds(), "mode", mode)
+	if err != nil {
+		return ValidationReads{}, err
+	}
+	log.Printf("   %d validation read(s) via %s in %s\n", len(calls), mode, elapsed)
+
+	if mintedFetched {
+		s.mintedCache.Set(s.config.NFTContract, f.From, reads.Minted)
+	}
+	return reads, nil
+}
+
+// sequentialViews performs each view read as its own eth_call
+func (s *Server) sequentialViews(ctx context.Context, calls []viewCall) error {
+	for _, call := range calls {
+		data, err := call.contract.Pack(call.method, call.args...)
+		if err != nil {
+			return fmt.Errorf("failed to pack %s: %v", call.method, err)
+		}
+		result, err := s.client.CallContract(ctx, ethereum.CallMsg{To: &call.address, Data: data}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to call %s: %v", call.method, err)
+		}
+		if err := call.contract.UnpackIntoInterface(call.result, call.method, result); err != nil {
+			return fmt.Errorf("failed to unpack %s: %v", call.method, err)
+		}
+	}
+	return nil
+}
+
+// multicallViews performs all view reads in a single aggregate3 call
+func (s *Server) multicallViews(ctx context.Context, calls []viewCall) error {
+	multicall, err := abi.JSON(strings.NewReader(multicall3ABI))
+	if err != nil {
+		return err
+	}
+
+	batch := make([]multicallCall, len(calls))
+	for i, call := range calls {
+		data, err := call.contract.Pack(call.method, call.args...)
+		if err != nil {
+			return fmt.Errorf("failed to pack %s: %v", call.method, err)
+		}
+		batch[i] = multicallCall{Target: call.address, CallData: data}
+	}
+
+	data, err := multicall.Pack("aggregate3", batch)
+	if err != nil {
+		return fmt.Errorf("failed to pack aggregate3: %v", err)
+	}
+	raw, err := s.client.CallContract(ctx, ethereum.CallMsg{To: &s.config.MulticallAddress, Data: data}, nil)
+	if err != nil {
+		return fmt.Errorf("multicall failed: %v", err)
+	}
+
+	out, err := multicall.Unpack("aggregate3", raw)
+	if err != nil {
+		return fmt.Errorf("failed to unpack aggregate3: %v", err)
+	}
+	results := *abi.ConvertType(out[0], new([]multicallResult)).(*[]multicallResult)
+	if len(results) != len(calls) {
+		return fmt.Errorf("multicall returned %d results for %d calls", len(results), len(calls))
+	}
+
+	for i, call := range calls {
+		if err := call.contract.UnpackIntoInterface(call.result, call.method, results[i].ReturnData); err != nil {
+			return fmt.Errorf("failed to unpack %s: %v", call.method, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// loadNetworkConfig derives the config for an additional named network from
+// base. Each network is configured through variables prefixed with its
+// upper-cased name, e.g. for "amoy": AMOY_RPC_URL, AMOY_CHAIN_ID,
+// AMOY_HUB_ADDRESS, AMOY_NFT_CONTRACT and AMOY_RELAYER_PRIVATE_KEY.
+// Optional AMOY_HUB_VERSIONS, AMOY_HUB_ABI_FILES, AMOY_EXPECTED_CALLER and
+// AMOY_BROADCAST_RPCS mirror their unprefixed counterparts. All other settings are shared with
+// the default network.
+func loadNetworkConfig(base Config, name string) (Config, error) {
+	prefix := strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+
+	required := func(key string) (string, error) {
+		value := os.Getenv(prefix + key)
+		if value == "" {
+			return "", fmt.Errorf("%s%s is required for network %q", prefix, key, name)
+		}
+		return value, nil
+	}
+
+	rpcURL, err := required("RPC_URL")
+	if err != nil {
+		return Config{}, err
+	}
+	chainIDStr, err := required("CHAIN_ID")
+	if err != nil {
+		return Config{}, err
+	}
+	hubAddr, err := required("HUB_ADDRESS")
+	if err != nil {
+		return Config{}, err
+	}
+	nftAddr, err := required("NFT_CONTRACT")
+	if err != nil {
+		return Config{}, err
+	}
+	relayerKey, err := required("RELAYER_PRIVATE_KEY")
+	if err != nil {
+		return Config{}, err
+	}
+
+	chainID, ok := new(big.Int).SetString(chainIDStr, 10)
+	if !ok {
+		return Config{}, fmt.Errorf("invalid %sCHAIN_ID", prefix)
+	}
+	if !common.IsHexAddress(nftAddr) {
+		return Config{}, fmt.Errorf("invalid %sNFT_CONTRACT", prefix)
+	}
+
+	hubs, err := loadHubs(hubAddr, os.Getenv(prefix+"HUB_VERSIONS"), os.Getenv(prefix+"HUB_ABI_FILES"))
+	if err != nil {
+		return Config{}, fmt.Errorf("network %q: %v", name, err)
+	}
+	if base.MerkleBatch {
+		if err := checkMerkleSupport(hubs, base.MerkleExecuteMethod); err != nil {
+			return Config{}, fmt.Errorf("network %q: %v", name, err)
+		}
+	}
+
+	config := base
+	config.NetworkName = name
+	config.Networks = nil
+	config.RPCURL = rpcURL
+	config.ChainID = chainID
+	config.Hubs = hubs
+	config.HubAddress = hubs[0].Address
+	config.NFTContract = common.HexToAddress(nftAddr)
+	config.RelayerPrivateKey = relayerKey
+	config.RemoteSignerURL = ""
+
+	// An intermediate caller contract lives on one chain only
+	config.ExpectedCaller = common.Address{}
+	if v := os.Getenv(prefix + "EXPECTED_CALLER"); v != "" {
+		if !common.IsHexAddress(v) {
+			return Config{}, fmt.Errorf("invalid %sEXPECTED_CALLER", prefix)
+		}
+		if len(hubs) > 1 {
+			return Config{}, fmt.Errorf("%sEXPECTED_CALLER forwards to a single hub but %sHUB_ADDRESS lists %d", prefix, prefix, len(hubs))
+		}
+		config.ExpectedCaller = common.HexToAddress(v)
+	}
+
+	// Broadcast endpoints serve one chain only
+	config.BroadcastRPCs = splitList(os.Getenv(prefix + "BROADCAST_RPCS"))
+
+	// Networks can't share state files; metrics are shared with the default
+	// network and saved by it
+	config.MetricsFile = ""
+	if base.ProcessedFile != "" {
+		config.ProcessedFile = base.ProcessedFile + "." + name
+	}
+	if base.MintHistoryFile != "" {
+		config.MintHistoryFile = base.MintHistoryFile + "." + name
+	}
+	if path := os.Getenv(prefix + "TX_STORE_FILE"); path != "" {
+		config.TxStoreFile = path
+	} else if base.TxStoreFile != "" {
+		config.TxStoreFile = base.TxStoreFile + "." + name
+	}
+	return config, nil
+}
+
+// addNetworks creates a server per configured additional network. Each has
+// its own RPC client, signer and nonce tracking; metrics are shared.
+func (s *Server) addNetworks() error {
+	s.networks = make(map[string]*Server)
+	for _, name := range s.config.Networks {
+		if name == s.config.NetworkName {
+			return fmt.Errorf("network %q duplicates the default network name", name)
+		}
+		if name == "merkle" {
+			return fmt.Errorf("network name %q is reserved by /relay/merkle", name)
+		}
+		if _, exists := s.networks[name]; exists {
+			return fmt.Errorf("duplicate network %q", name)
+		}
+
+		config, err := loadNetworkConfig(s.config, name)
+		if err != nil {
+			return err
+		}
+		log.Printf("🌐 Adding network %q (chain %s)\n", name, config.ChainID.String())
+
+		network, err := NewServer(config, s.metrics)
+		if err != nil {
+			return fmt.Errorf("network %q: %v", name, err)
+		}
+		s.networks[name] = network
+	}
+	return nil
+}
+
+// networkServer returns the server for a network name. An empty name or the
+// default network's name selects s itself.
+func (s *Server) networkServer(name string) (*Server, bool) {
+	if name == "" || name == s.config.NetworkName {
+		return s, true
+	}
+	network, ok := s.networks[name]
+	return network, ok
+}
+
+// startBackground launches the periodic workers for s and every network
+func (s *Server) startBackground() {
+	go s.cleanupRoutine()
+	if s.config.ChainIDCheckInterval > 0 {
+		go s.chainIDRoutine()
+	}
+
+	// Pick up transactions left pending by the previous run
+	s.resumePendingTxs()
+
+	// Start the reorg monitor
+	if s.reorgs != nil {
+		go s.reorgs.Run(s.handleReorg)
+	}
+
+	for _, network := range s.networks {
+		network.startBackground()
+	}
+}
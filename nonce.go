@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// NonceManager hands out relayer nonces locally so concurrent submissions
+// never collide, re-syncing with the node's pending nonce whenever the node
+// is ahead (e.g. after transactions sent from elsewhere). Released nonces
+// are handed out again, lowest first, before any fresh one.
+type NonceManager struct {
+	mu      sync.Mutex
+	client  EthBackend
+	address common.Address
+	next    uint64
+	seeded  bool
+	closed  bool
+	// free holds released nonces below next
+	free map[uint64]bool
+	// reserved holds nonces handed out but not yet broadcast; the node
+	// doesn't know about them, so a re-seed never goes below them
+	reserved map[uint64]bool
+}
+
+// NewNonceManager creates an unseeded nonce manager
+func NewNonceManager(client EthBackend) *NonceManager {
+	return &NonceManager{
+		client:   client,
+		free:     make(map[uint64]bool),
+		reserved: make(map[uint64]bool),
+	}
+}
+
+// Next reserves the next nonce for address. The node is queried without
+// holding the lock, so a slow RPC doesn't stall Release or Commit.
+func (n *NonceManager) Next(ctx context.Context, address common.Address) (uint64, error) {
+	n.mu.Lock()
+	closed := n.closed
+	n.mu.Unlock()
+	if closed {
+		return 0, fmt.Errorf("nonce manager is closed")
+	}
+
+	pending, err := n.client.PendingNonceAt(ctx, address)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get nonce: %v", err)
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.closed {
+		return 0, fmt.Errorf("nonce manager is closed")
+	}
+	if address != n.address {
+		// Reservations belong to the previous key
+		n.address = address
+		n.seeded = false
+		n.free = make(map[uint64]bool)
+		n.reserved = make(map[uint64]bool)
+	}
+	if !n.seeded || pending > n.next {
+		n.next = pending
+		n.seeded = true
+	}
+	for nonce := range n.reserved {
+		if nonce >= n.next {
+			n.next = nonce + 1
+		}
+	}
+	// Released nonces the node has since seen used are gone for good
+	for nonce := range n.free {
+		if nonce < pending || nonce >= n.next {
+			delete(n.free, nonce)
+		}
+	}
+
+	nonce := n.next
+	for free := range n.free {
+		if free < nonce {
+			nonce = free
+		}
+	}
+	if nonce == n.next {
+		n.next++
+	} else {
+		delete(n.free, nonce)
+	}
+	n.reserved[nonce] = true
+	return nonce, nil
+}
+
+// Release returns a reserved nonce that was never broadcast so it is handed
+// out again before any fresh nonce. Releasing a nonce that isn't reserved
+// is a no-op, as is releasing one while unseeded beyond dropping the
+// reservation: the next re-seed reads the node afresh.
+func (n *NonceManager) Release(nonce uint64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if !n.reserved[nonce] {
+		return
+	}
+	delete(n.reserved, nonce)
+	if !n.seeded {
+		return
+	}
+	n.free[nonce] = true
+	// Shrink back over released nonces at the top
+	for n.next > 0 && n.free[n.next-1] {
+		n.next--
+		delete(n.free, n.next)
+	}
+}
+
+// Commit marks a reserved nonce as used, whether by our broadcast or by
+// another transaction found holding it. The node's pending nonce accounts
+// for it from then on.
+func (n *NonceManager) Commit(nonce uint64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.reserved, nonce)
+}
+
+// Reset forgets the local nonce so the next reservation re-reads the node.
+// Nonces still reserved keep the re-seed above them.
+func (n *NonceManager) Reset() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.seeded = false
+}
+
+// Close stops handing out nonces during shutdown. Nothing is persisted: the
+// next run re-seeds from the node's pending nonce, which already accounts for
+// every transaction broadcast before Close.
+func (n *NonceManager) Close() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.seeded {
+		log.Printf("🔢 Nonce manager closed at nonce %d\n", n.next)
+	}
+	n.closed = true
+}
+
+// Local returns the next nonce the manager would hand out, if seeded
+func (n *NonceManager) Local() (uint64, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.next, n.seeded
+}
+
+// NonceLag compares the local next nonce with the chain's view. A gap
+// against the latest (confirmed) nonce that persists means transactions are
+// stuck.
+type NonceLag struct {
+	Local         uint64 `json:"local"`
+	Pending       uint64 `json:"pending"`
+	Latest        uint64 `json:"latest"`
+	PendingGap    int64  `json:"pendingGap"`
+	ConfirmedGap  int64  `json:"confirmedGap"`
+	LocalIsSeeded bool   `json:"localIsSeeded"`
+}
+
+// nonceLag measures the nonce gap and publishes it as gauges
+func (s *Server) nonceLag(ctx context.Context) (NonceLag, error) {
+	relayer := s.relayer()
+	pending, err := s.client.PendingNonceAt(ctx, relayer)
+	if err != nil {
+		return NonceLag{}, err
+	}
+	latest, err := s.client.NonceAt(ctx, relayer, nil)
+	if err != nil {
+		return NonceLag{}, err
+	}
+
+	// Until the first submission the local nonce is the node's pending one
+	local, seeded := s.nonces.Local()
+	if !seeded {
+		local = pending
+	}
+
+	lag := NonceLag{
+		Local:         local,
+		Pending:       pending,
+		Latest:        latest,
+		PendingGap:    int64(local) - int64(pending),
+		ConfirmedGap:  int64(local) - int64(latest),
+		LocalIsSeeded: seeded,
+	}
+	s.metrics.Set("relayer_nonce_gap", float64(lag.PendingGap), "against", "pending")
+	s.metrics.Set("relayer_nonce_gap", float64(lag.ConfirmedGap), "against", "latest")
+	return lag, nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// nextNonces reserves count nonces from n
+func nextNonces(t *testing.T, n *NonceManager, count int) []uint64 {
+	t.Helper()
+	nonces := make([]uint64, count)
+	for i := range nonces {
+		nonce, err := n.Next(context.Background(), common.Address{})
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		nonces[i] = nonce
+	}
+	return nonces
+}
+
+func TestNonceManagerReusesReleasedNonces(t *testing.T) {
+	n := NewNonceManager(newFakeBackend())
+	nextNonces(t, n, 4)
+
+	// Release out of order, leaving holes below the top
+	n.Release(2)
+	n.Release(0)
+	if got := nextNonces(t, n, 3); got[0] != 0 || got[1] != 2 || got[2] != 4 {
+		t.Fatalf("nonces = %v, want [0 2 4]", got)
+	}
+
+	// Releasing the top nonces shrinks back over released ones below
+	n.Release(3)
+	n.Release(4)
+	if next, _ := n.Local(); next != 3 {
+		t.Fatalf("next = %d, want 3", next)
+	}
+}
+
+func TestNonceManagerReleaseIgnoresUnreservedNonces(t *testing.T) {
+	backend := newFakeBackend()
+	backend.pendingNonce = 5
+	n := NewNonceManager(backend)
+
+	// Unseeded: nothing was handed out
+	n.Release(4)
+	if _, seeded := n.Local(); seeded {
+		t.Fatal("Release seeded the manager")
+	}
+	if got := nextNonces(t, n, 1); got[0] != 5 {
+		t.Fatalf("nonce = %d, want the node's pending 5", got[0])
+	}
+
+	// Releasing twice, or a nonce never reserved, changes nothing
+	n.Release(5)
+	n.Release(5)
+	n.Release(9)
+	if got := nextNonces(t, n, 2); got[0] != 5 || got[1] != 6 {
+		t.Fatalf("nonces = %v, want [5 6]", got)
+	}
+}
+
+func TestNonceManagerReseedRespectsReservations(t *testing.T) {
+	backend := newFakeBackend()
+	n := NewNonceManager(backend)
+	nextNonces(t, n, 2)
+
+	// Neither reservation is broadcast, so the node still reports 0
+	n.Reset()
+	if got := nextNonces(t, n, 1); got[0] != 2 {
+		t.Fatalf("nonce = %d after Reset, want 2 above the reservations", got[0])
+	}
+
+	// Once broadcast, the node's pending nonce takes over
+	for nonce := uint64(0); nonce < 3; nonce++ {
+		n.Commit(nonce)
+	}
+	backend.pendingNonce = 1
+	n.Reset()
+	if got := nextNonces(t, n, 1); got[0] != 1 {
+		t.Fatalf("nonce = %d after committing, want the node's pending 1", got[0])
+	}
+}
+
+func TestNonceManagerDropsReleasedNoncesTheNodeUsed(t *testing.T) {
+	backend := newFakeBackend()
+	n := NewNonceManager(backend)
+	nextNonces(t, n, 3)
+	n.Release(0)
+
+	// Something else took nonce 0 and the node moved past it
+	backend.pendingNonce = 1
+	if got := nextNonces(t, n, 1); got[0] != 3 {
+		t.Fatalf("nonce = %d, want 3", got[0])
+	}
+}
+
+// blockingBackend holds PendingNonceAt until release is closed
+type blockingBackend struct {
+	*fakeBackend
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingBackend) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	close(b.entered)
+	<-b.release
+	return b.fakeBackend.PendingNonceAt(ctx, account)
+}
+
+func TestNonceManagerDoesNotLockAcrossRPC(t *testing.T) {
+	backend := &blockingBackend{fakeBackend: newFakeBackend(), entered: make(chan struct{}), release: make(chan struct{})}
+	n := NewNonceManager(backend)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := n.Next(context.Background(), common.Address{})
+		done <- err
+	}()
+	<-backend.entered
+
+	unlocked := make(chan struct{})
+	go func() {
+		n.Release(0)
+		n.Local()
+		close(unlocked)
+	}()
+	select {
+	case <-unlocked:
+	case <-time.After(time.Second):
+		t.Fatal("nonce manager locked while waiting on the node")
+	}
+
+	close(backend.release)
+	if err := <-done; err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+}
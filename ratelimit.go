@@ -0,0 +1,123 @@
+package main
+
+import (
+	"container/list"
+	"time"
+)
+
+// Policies for new addresses once the rate limiter is tracking
+// MAX_RATE_LIMIT_ENTRIES addresses that are all still active
+const (
+	rateLimitFailClosed = "closed"
+	rateLimitFailOpen   = "open"
+)
+
+// newRateLimit creates a rate limiter tracking at most maxEntries addresses
+// (0 for unbounded), granting firstBonus extra requests in an address's
+// first window
+func newRateLimit(maxEntries int, policy string, firstBonus int) *RateLimit {
+	return &RateLimit{
+		requests:   make(map[string][]int64),
+		order:      list.New(),
+		elems:      make(map[string]*list.Element),
+		maxEntries: maxEntries,
+		policy:     policy,
+		firstSeen:  make(map[string]int64),
+		firstBonus: firstBonus,
+	}
+}
+
+// limit returns the number of requests address may make in the current
+// window. An untracked address opens a first window with the bonus.
+// Callers hold rl.mu.
+func (rl *RateLimit) limit(address string, tracked bool, now int64) int {
+	if rl.firstBonus <= 0 {
+		return maxRequestsPerWindow
+	}
+	if !tracked {
+		rl.firstSeen[address] = now
+	}
+	if first, ok := rl.firstSeen[address]; ok && now-first < int64(rateLimitWindow.Seconds()) {
+		return maxRequestsPerWindow + rl.firstBonus
+	}
+	return maxRequestsPerWindow
+}
+
+// status returns how many requests address has made in the current window
+// and how many it may make, without counting a request. Callers hold rl.mu.
+func (rl *RateLimit) status(address string, now int64) (used, limit int) {
+	for _, t := range rl.requests[address] {
+		if now-t < int64(rateLimitWindow.Seconds()) {
+			used++
+		}
+	}
+
+	limit = maxRequestsPerWindow
+	if first, ok := rl.firstSeen[address]; ok && rl.firstBonus > 0 && now-first < int64(rateLimitWindow.Seconds()) {
+		limit += rl.firstBonus
+	}
+	return used, limit
+}
+
+// touch marks address as most recently used. Callers hold rl.mu.
+func (rl *RateLimit) touch(address string) {
+	if elem, ok := rl.elems[address]; ok {
+		rl.order.MoveToFront(elem)
+		return
+	}
+	rl.elems[address] = rl.order.PushFront(address)
+}
+
+// remove stops tracking address. Callers hold rl.mu.
+func (rl *RateLimit) remove(address string) {
+	if elem, ok := rl.elems[address]; ok {
+		rl.order.Remove(elem)
+		delete(rl.elems, address)
+	}
+	delete(rl.requests, address)
+	delete(rl.firstSeen, address)
+}
+
+// makeRoom evicts the least recently used address if the table is full and
+// that address has no requests left in the window. Evicting an address that
+// is still inside its window would reset its limit, so in that case it
+// reports false and the caller falls back to the configured policy.
+// Callers hold rl.mu.
+func (rl *RateLimit) makeRoom(now int64) bool {
+	if rl.maxEntries <= 0 || len(rl.requests) < rl.maxEntries {
+		return true
+	}
+
+	oldest := rl.order.Back()
+	if oldest == nil {
+		return true
+	}
+	address := oldest.Value.(string)
+	times := rl.requests[address]
+	if len(times) > 0 && now-times[len(times)-1] < int64(rateLimitWindow.Seconds()) {
+		return false
+	}
+	rl.remove(address)
+	return true
+}
+
+// Cleanup drops addresses with no requests left in the window
+func (rl *RateLimit) Cleanup(now time.Time) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	cutoff := now.Add(-rateLimitWindow).Unix()
+	for addr, times := range rl.requests {
+		var recent []int64
+		for _, t := range times {
+			if t > cutoff {
+				recent = append(recent, t)
+			}
+		}
+		if len(recent) == 0 {
+			rl.remove(addr)
+		} else {
+			rl.requests[addr] = recent
+		}
+	}
+}
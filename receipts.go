@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// receiptFetcher looks up receipts for hashes, returning nil for those not
+// mined yet
+type receiptFetcher func(ctx context.Context, hashes []common.Hash) ([]*types.Receipt, error)
+
+// ReceiptWatcher replaces per-transaction polling loops with a single
+// coordinated loop that fetches every outstanding receipt once per interval
+// and notifies the waiters. The loop only runs while something is waited on.
+type ReceiptWatcher struct {
+	fetch    receiptFetcher
+	interval time.Duration
+	mu       sync.Mutex
+	waiters  map[common.Hash][]chan *types.Receipt
+	running  bool
+}
+
+// NewReceiptWatcher creates a watcher polling every interval with one
+// batched eth_getTransactionReceipt request per client, recorded as
+// rpc_call_duration_seconds{method="BatchTransactionReceipt"}. A receipt
+// found by any client is used.
+func NewReceiptWatcher(clients []*rpc.Client, interval time.Duration, metrics *Metrics) *ReceiptWatcher {
+	fetchers := make([]receiptFetcher, len(clients))
+	for i, client := range clients {
+		fetchers[i] = batchReceipts(client, metrics)
+	}
+	return newReceiptWatcher(anyReceipts(fetchers), interval)
+}
+
+// NewPollingReceiptWatcher creates a watcher polling every interval with one
+// TransactionReceipt call per outstanding transaction
+func NewPollingReceiptWatcher(backend EthBackend, interval time.Duration) *ReceiptWatcher {
+	return newReceiptWatcher(func(ctx context.Context, hashes []common.Hash) ([]*types.Receipt, error) {
+		results := make([]*types.Receipt, len(hashes))
+		for i, hash := range hashes {
+			receipt, err := backend.TransactionReceipt(ctx, hash)
+			if err != nil && !errors.Is(err, ethereum.NotFound) {
+				return nil, err
+			}
+			results[i] = receipt
+		}
+		return results, nil
+	}, interval)
+}
+
+func newReceiptWatcher(fetch receiptFetcher, interval time.Duration) *ReceiptWatcher {
+	return &ReceiptWatcher{
+		fetch:    fetch,
+		interval: interval,
+		waiters:  make(map[common.Hash][]chan *types.Receipt),
+	}
+}
+
+// batchReceipts fetches receipts from client in a single batch request
+func batchReceipts(client *rpc.Client, metrics *Metrics) receiptFetcher {
+	return func(ctx context.Context, hashes []common.Hash) ([]*types.Receipt, error) {
+		batch := make([]rpc.BatchElem, len(hashes))
+		results := make([]*types.Receipt, len(hashes))
+		for i, hash := range hashes {
+			batch[i] = rpc.BatchElem{
+				Method: "eth_getTransactionReceipt",
+				Args:   []interface{}{hash},
+				Result: &results[i],
+			}
+		}
+
+		start := time.Now()
+		err := client.BatchCallContext(ctx, batch)
+		metrics.Observe("rpc_call_duration_seconds", time.Since(start).Seconds(), "method", "BatchTransactionReceipt")
+		if err != nil {
+			metrics.Inc("rpc_call_errors_total", "method", "BatchTransactionReceipt")
+			return nil, err
+		}
+		for i := range batch {
+			if batch[i].Error != nil {
+				results[i] = nil
+			}
+		}
+		return results, nil
+	}
+}
+
+// anyReceipts queries every fetcher at once and keeps the first receipt
+// found for each hash. It fails only when every fetcher fails, with the
+// first fetcher's error.
+func anyReceipts(fetchers []receiptFetcher) receiptFetcher {
+	if len(fetchers) == 1 {
+		return fetchers[0]
+	}
+	return func(ctx context.Context, hashes []common.Hash) ([]*types.Receipt, error) {
+		receipts := make([][]*types.Receipt, len(fetchers))
+		errs := make([]error, len(fetchers))
+		var wg sync.WaitGroup
+		for i, fetch := range fetchers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				receipts[i], errs[i] = fetch(ctx, hashes)
+			}()
+		}
+		wg.Wait()
+
+		var merged []*types.Receipt
+		for i := range fetchers {
+			if errs[i] != nil {
+				continue
+			}
+			if merged == nil {
+				merged = make([]*types.Receipt, len(hashes))
+			}
+			for j, receipt := range receipts[i] {
+				if merged[j] == nil {
+					merged[j] = receipt
+				}
+			}
+		}
+		if merged == nil {
+			return nil, errs[0]
+		}
+		return merged, nil
+	}
+}
+
+// Wait blocks until the receipt for txHash is found or ctx is done
+func (rw *ReceiptWatcher) Wait(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	ch := make(chan *types.Receipt, 1)
+
+	rw.mu.Lock()
+	rw.waiters[txHash] = append(rw.waiters[txHash], ch)
+	if !rw.running {
+		rw.running = true
+		go rw.run()
+	}
+	rw.mu.Unlock()
+
+	select {
+	case receipt := <-ch:
+		return receipt, nil
+	case <-ctx.Done():
+		rw.remove(txHash, ch)
+		return nil, ctx.Err()
+	}
+}
+
+// remove unregisters a single waiter
+func (rw *ReceiptWatcher) remove(txHash common.Hash, ch chan *types.Receipt) {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	waiters := rw.waiters[txHash]
+	for i, w := range waiters {
+		if w == ch {
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(waiters) == 0 {
+		delete(rw.waiters, txHash)
+	} else {
+		rw.waiters[txHash] = waiters
+	}
+}
+
+// Pending returns the number of transactions currently being watched
+func (rw *ReceiptWatcher) Pending() int {
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+	return len(rw.waiters)
+}
+
+// run polls for outstanding receipts until nothing is waited on
+func (rw *ReceiptWatcher) run() {
+	ticker := time.NewTicker(rw.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		rw.poll()
+
+		rw.mu.Lock()
+		if len(rw.waiters) == 0 {
+			rw.running = false
+			rw.mu.Unlock()
+			return
+		}
+		rw.mu.Unlock()
+	}
+}
+
+// poll fetches all outstanding receipts at once
+func (rw *ReceiptWatcher) poll() {
+	rw.mu.Lock()
+	hashes := make([]common.Hash, 0, len(rw.waiters))
+	for hash := range rw.waiters {
+		hashes = append(hashes, hash)
+	}
+	rw.mu.Unlock()
+
+	if len(hashes) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), rw.interval*5)
+	defer cancel()
+
+	results, err := rw.fetch(ctx, hashes)
+	if err != nil {
+		log.Printf("⚠️  Receipt poll failed (%d txs): %v\n", len(hashes), err)
+		return
+	}
+
+	rw.mu.Lock()
+	defer rw.mu.Unlock()
+
+	for i, hash := range hashes {
+		if results[i] == nil {
+			continue
+		}
+		for _, ch := range rw.waiters[hash] {
+			ch <- results[i]
+		}
+		delete(rw.waiters, hash)
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// rpcMessage is a single JSON-RPC request or response
+type rpcMessage struct {
+	JSONRPC string            `json:"jsonrpc"`
+	ID      json.RawMessage   `json:"id"`
+	Method  string            `json:"method,omitempty"`
+	Params  []json.RawMessage `json:"params,omitempty"`
+	Result  interface{}       `json:"result"`
+}
+
+// newRPCServer serves JSON-RPC batches, answering each call with
+// handle(method, params). A nil handle fails every request with a 500.
+func newRPCServer(t *testing.T, handle func(method string, params []json.RawMessage) interface{}) *rpc.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if handle == nil {
+			http.Error(w, "unavailable", http.StatusInternalServerError)
+			return
+		}
+		var batch []rpcMessage
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for i := range batch {
+			batch[i].Result = handle(batch[i].Method, batch[i].Params)
+			batch[i].Method, batch[i].Params = "", nil
+		}
+		json.NewEncoder(w).Encode(batch)
+	}))
+	t.Cleanup(srv.Close)
+
+	client, err := rpc.DialHTTP(srv.URL)
+	if err != nil {
+		t.Fatalf("DialHTTP: %v", err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+// metricsText renders m in the exposition format
+func metricsText(m *Metrics) string {
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	return rec.Body.String()
+}
+
+func TestReceiptWatcherRecordsRPCMetrics(t *testing.T) {
+	mined := common.HexToHash("0x01")
+	client := newRPCServer(t, func(method string, params []json.RawMessage) interface{} {
+		var hash common.Hash
+		json.Unmarshal(params[0], &hash)
+		if hash != mined {
+			return nil
+		}
+		return &types.Receipt{TxHash: hash, Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{}}
+	})
+	metrics := NewMetrics()
+	rw := NewReceiptWatcher([]*rpc.Client{client}, time.Second, metrics)
+	ch := make(chan *types.Receipt, 1)
+	rw.waiters[mined] = []chan *types.Receipt{ch}
+
+	rw.poll()
+
+	select {
+	case receipt := <-ch:
+		if receipt.TxHash != mined {
+			t.Fatalf("receipt for %s, want %s", receipt.TxHash.Hex(), mined.Hex())
+		}
+	default:
+		t.Fatal("waiter not notified")
+	}
+	if text := metricsText(metrics); !strings.Contains(text, `rpc_call_duration_seconds_count{method="BatchTransactionReceipt"} 1`) {
+		t.Fatalf("batch call not recorded:\n%s", text)
+	}
+}
+
+func TestReceiptWatcherRecordsRPCErrors(t *testing.T) {
+	metrics := NewMetrics()
+	rw := NewReceiptWatcher([]*rpc.Client{newRPCServer(t, nil)}, time.Second, metrics)
+	rw.waiters[common.HexToHash("0x01")] = []chan *types.Receipt{make(chan *types.Receipt, 1)}
+
+	rw.poll()
+
+	if text := metricsText(metrics); !strings.Contains(text, `rpc_call_errors_total{method="BatchTransactionReceipt"} 1`) {
+		t.Fatalf("failed batch not recorded:\n%s", text)
+	}
+}
+
+// countingBackend counts TransactionReceipt calls
+type countingBackend struct {
+	*fakeBackend
+	calls atomic.Int32
+}
+
+func (b *countingBackend) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	b.calls.Add(1)
+	return b.fakeBackend.TransactionReceipt(ctx, txHash)
+}
+
+func TestPollingReceiptWatcherSharesOneLoop(t *testing.T) {
+	backend := &countingBackend{fakeBackend: newFakeBackend()}
+	backend.noMine = true
+	interval := 20 * time.Millisecond
+	rw := NewPollingReceiptWatcher(backend, interval)
+
+	txs := make([]*types.Transaction, 5)
+	results := make(chan error, len(txs))
+	start := time.Now()
+	for i := range txs {
+		txs[i] = types.NewTransaction(uint64(i), common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil)
+		go func(tx *types.Transaction) {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_, err := rw.Wait(ctx, tx.Hash())
+			results <- err
+		}(txs[i])
+	}
+
+	// Let a few polls find nothing, then mine everything at once
+	time.Sleep(5 * interval)
+	for _, tx := range txs {
+		backend.mine(tx)
+	}
+	for range txs {
+		if err := <-results; err != nil {
+			t.Fatalf("Wait: %v", err)
+		}
+	}
+
+	// One loop polls every waiter once per tick
+	elapsed := time.Since(start)
+	polls := float64(backend.calls.Load()) / float64(len(txs))
+	if limit := float64(elapsed/interval) + 1; polls > limit {
+		t.Fatalf("%v polls per transaction in %s, want at most %v", polls, elapsed, limit)
+	}
+
+	// The loop stops once nothing is waited on
+	time.Sleep(3 * interval)
+	rw.mu.Lock()
+	running := rw.running
+	rw.mu.Unlock()
+	if running {
+		t.Fatal("receipt loop still running with no waiters")
+	}
+}
+
+func TestWaitForReceiptQueuesForWatcherSlot(t *testing.T) {
+	backend := newFakeBackend()
+	s := newTestServer(t, backend)
+	s.watchers = NewWatcherPool(1)
+	tx := types.NewTransaction(0, common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil)
+	backend.mine(tx)
+
+	// Another transaction holds the only slot
+	if err := s.watchers.Acquire(context.Background()); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.waitForReceipt(tx.Hash())
+		done <- err
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for s.watchers.Stats().Queued != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("waitForReceipt did not queue for a slot")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("waitForReceipt returned %v without a slot", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	s.watchers.Release()
+	if err := <-done; err != nil {
+		t.Fatalf("waitForReceipt: %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// relayLabels returns the contract and function labels for a relay. Only
+// the configured NFT contract and its ABI methods are named, everything else
+// is "other", so label cardinality stays bounded however clients call.
+func (s *Server) relayLabels(req RelayRequest) (contract, function string) {
+	contract, function = "other", "other"
+	switch {
+	case s.isDeploy(req.Forward.To):
+		return "deploy", "deploy"
+	case req.Forward.To == s.config.NFTContract:
+		contract = s.config.NFTContract.Hex()
+	default:
+		return contract, function
+	}
+
+	callData, err := decodeHex(req.CallData)
+	if err != nil || len(callData) < 4 {
+		return contract, function
+	}
+	parsedABI, err := abi.JSON(strings.NewReader(nftABI))
+	if err != nil {
+		return contract, function
+	}
+	for name, method := range parsedABI.Methods {
+		if bytes.Equal(callData[:4], method.ID) {
+			return contract, name
+		}
+	}
+	return contract, function
+}
+
+// recordRelayMetrics records a finished relay's outcome, duration and gas
+// labeled by target contract and function
+func (s *Server) recordRelayMetrics(req RelayRequest, result *TxResult, err error, elapsed time.Duration) {
+	contract, function := s.relayLabels(req)
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+
+	s.metrics.Inc("relay_results_total", "contract", contract, "function", function, "result", outcome)
+	s.metrics.Observe("relay_duration_seconds", elapsed.Seconds(), "contract", contract, "function", function)
+	if result != nil && result.GasUsed != nil {
+		s.metrics.Add("relay_gas_used_total", float64(result.GasUsed.Uint64()), "contract", contract, "function", function)
+	}
+}
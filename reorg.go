@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// watchedTx is a confirmed relay being re-checked for reorgs
+type watchedTx struct {
+	from        common.Address
+	blockNumber uint64
+	blockHash   common.Hash
+	until       time.Time
+}
+
+// ReorgMonitor re-checks confirmed relays for a window after confirmation
+// and reports any whose receipt disappeared or moved to a different block,
+// so operators can re-relay orphaned mints
+type ReorgMonitor struct {
+	client   EthBackend
+	window   time.Duration
+	interval time.Duration
+	mu       sync.Mutex
+	watched  map[common.Hash]watchedTx
+}
+
+// NewReorgMonitor creates a monitor that watches each tx for window,
+// checking every interval
+func NewReorgMonitor(client EthBackend, window, interval time.Duration) *ReorgMonitor {
+	return &ReorgMonitor{
+		client:   client,
+		window:   window,
+		interval: interval,
+		watched:  make(map[common.Hash]watchedTx),
+	}
+}
+
+// Watch starts monitoring a confirmed transaction
+func (rm *ReorgMonitor) Watch(txHash common.Hash, from common.Address, blockNumber uint64, blockHash common.Hash) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.watched[txHash] = watchedTx{
+		from:        from,
+		blockNumber: blockNumber,
+		blockHash:   blockHash,
+		until:       time.Now().Add(rm.window),
+	}
+}
+
+// Pending returns the number of transactions currently being monitored
+func (rm *ReorgMonitor) Pending() int {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	return len(rm.watched)
+}
+
+// Run checks watched transactions until the process exits, calling
+// onReorg for each one found to have been reorged out
+func (rm *ReorgMonitor) Run(onReorg func(txHash common.Hash, tx watchedTx, reason string)) {
+	ticker := time.NewTicker(rm.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		rm.check(onReorg)
+	}
+}
+
+// check re-fetches every watched receipt and drops expired entries
+func (rm *ReorgMonitor) check(onReorg func(txHash common.Hash, tx watchedTx, reason string)) {
+	rm.mu.Lock()
+	snapshot := make(map[common.Hash]watchedTx, len(rm.watched))
+	for hash, tx := range rm.watched {
+		snapshot[hash] = tx
+	}
+	rm.mu.Unlock()
+
+	now := time.Now()
+	for hash, tx := range snapshot {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		receipt, err := rm.client.TransactionReceipt(ctx, hash)
+		cancel()
+
+		reason := ""
+		switch {
+		case errors.Is(err, ethereum.NotFound):
+			reason = "receipt no longer found"
+		case err != nil:
+			// RPC trouble is not evidence of a reorg; try again next tick
+			log.Printf("⚠️  Reorg check for %s failed: %v\n", hash.Hex(), err)
+			continue
+		case receipt.BlockHash != tx.blockHash:
+			reason = "receipt moved to block " + receipt.BlockHash.Hex()
+		}
+
+		rm.mu.Lock()
+		if reason != "" || now.After(tx.until) {
+			delete(rm.watched, hash)
+		}
+		rm.mu.Unlock()
+
+		if reason != "" {
+			onReorg(hash, tx, reason)
+		}
+	}
+}
+
+// watchForReorg registers a confirmed relay with the reorg monitor, if enabled
+func (s *Server) watchForReorg(from common.Address, result *TxResult) {
+	if s.reorgs == nil || result.Simulated {
+		return
+	}
+	s.reorgs.Watch(common.HexToHash(result.TxHash), from, result.BlockNumber, result.BlockHash)
+}
+
+// handleReorg alerts operators that a confirmed relay was reorged out
+func (s *Server) handleReorg(txHash common.Hash, tx watchedTx, reason string) {
+	log.Printf("🚨 ALERT: relay %s from %s (block %d %s) was reorged out: %s\n",
+		txHash.Hex(), tx.from.Hex(), tx.blockNumber, tx.blockHash.Hex(), reason)
+	s.metrics.Inc("relay_reorged_total")
+
+	s.sendWebhook(WebhookEvent{
+		Event:       webhookRelayReorged,
+		From:        tx.from.Hex(),
+		TxHash:      txHash.Hex(),
+		BlockNumber: tx.blockNumber,
+		Error:       reason,
+	})
+}
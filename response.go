@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// All JSON responses go through writeJSON so that headers, the status code
+// and the body are always written in that order.
+
+// writeJSON sends v as a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	var body []byte
+	var err error
+	if isPretty(w) {
+		body, err = json.MarshalIndent(v, "", "  ")
+	} else {
+		body, err = json.Marshal(v)
+	}
+	if err != nil {
+		log.Printf("❌ Failed to encode response: %v\n", err)
+		http.Error(w, `{"success":false,"error":"Failed to encode response"}`, http.StatusInternalServerError)
+		return
+	}
+
+	body = append(body, '\n')
+	signResponseHeader(w, status, body)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+// sendError sends an error response
+func (s *Server) sendError(w http.ResponseWriter, status int, message, details string) {
+	s.sendErrorCode(w, status, "", message, details)
+}
+
+// sendErrorCode sends an error response tagged with a machine-readable code
+func (s *Server) sendErrorCode(w http.ResponseWriter, status int, code, message, details string) {
+	log.Printf("\n❌ ERROR RESPONSE [%d]: %s\n", status, message)
+	if details != "" {
+		log.Printf("   Details: %s\n", details)
+	}
+
+	response := RelayResponse{
+		Success: false,
+		Error:   message,
+		Code:    code,
+		Details: details,
+	}
+
+	writeJSON(w, status, response)
+}
+
+// sendRetryAfter sends an error response telling the client when to retry,
+// both as a Retry-After header and in the body for non-header-aware clients
+func (s *Server) sendRetryAfter(w http.ResponseWriter, status int, message string, retryAfter int) {
+	log.Printf("\n❌ ERROR RESPONSE [%d]: %s (retry after %ds)\n", status, message, retryAfter)
+
+	response := RelayResponse{
+		Success:    false,
+		Error:      message,
+		RetryAfter: retryAfter,
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	writeJSON(w, status, response)
+}
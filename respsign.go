@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// responseSignatureHeader carries the relayer's signature over a JSON response; see README.md
+const responseSignatureHeader = "X-Relayer-Signature"
+
+// signingWriter marks a response whose JSON body should be signed. The
+// signature is a header, so it can only be added before the headers go out.
+type signingWriter struct {
+	http.ResponseWriter
+	s           *Server
+	wroteHeader bool
+}
+
+// WriteHeader records that the headers, and any signature, are fixed
+func (sw *signingWriter) WriteHeader(status int) {
+	sw.wroteHeader = true
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+// Write records that the headers, and any signature, are fixed
+func (sw *signingWriter) Write(p []byte) (int, error) {
+	sw.wroteHeader = true
+	return sw.ResponseWriter.Write(p)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (sw *signingWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
+
+// signResponsesMiddleware has writeJSON sign every response
+func (s *Server) signResponsesMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(&signingWriter{ResponseWriter: w, s: s}, r)
+	})
+}
+
+// responseSigner returns the signing writer under w, or nil when signing is
+// off
+func responseSigner(w http.ResponseWriter) *signingWriter {
+	for {
+		if sw, ok := w.(*signingWriter); ok {
+			return sw
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			return nil
+		}
+		w = u.Unwrap()
+	}
+}
+
+// signsResponses reports whether responses written to w must be signed
+func signsResponses(w http.ResponseWriter) bool {
+	return responseSigner(w) != nil
+}
+
+// signResponseHeader sets the signature header for a status and body about
+// to be written to w when w signs its responses. Once the headers are sent
+// the signature can no longer be attached, so nothing is signed. Failures
+// are logged and the response goes out unsigned.
+func signResponseHeader(w http.ResponseWriter, status int, body []byte) {
+	sw := responseSigner(w)
+	if sw == nil {
+		return
+	}
+	if sw.wroteHeader {
+		log.Printf("⚠️  Not signing response: headers already sent\n")
+		sw.s.metrics.Inc("response_signing_skipped_total")
+		return
+	}
+	signature, err := sw.s.signResponse(status, w.Header().Get("X-Request-ID"), body)
+	if err != nil {
+		log.Printf("⚠️  Failed to sign response: %v\n", err)
+		return
+	}
+	w.Header().Set(responseSignatureHeader, signature)
+}
+
+// responsePayload is the canonical payload signed for a response, as
+// documented on responseSignatureHeader
+func responsePayload(status int, requestID string, body []byte) []byte {
+	payload := fmt.Appendf(nil, "%d\n%s\n", status, requestID)
+	return append(payload, body...)
+}
+
+// signResponse signs keccak256 of the response payload as an EIP-191
+// personal message with the relayer key, returning a 65-byte r || s || v
+// signature with v = 27 or 28
+func (s *Server) signResponse(status int, requestID string, body []byte) (string, error) {
+	local, ok := s.currentSigner().(*localSigner)
+	if !ok {
+		return "", fmt.Errorf("signer cannot sign messages")
+	}
+
+	digest := crypto.Keccak256(responsePayload(status, requestID, body))
+	signature, err := crypto.Sign(accounts.TextHash(digest), local.key)
+	if err != nil {
+		return "", err
+	}
+	signature[crypto.RecoveryIDOffset] += 27
+	return hexutil.Encode(signature), nil
+}
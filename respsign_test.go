@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// signedHandler serves h behind the request ID and response signing
+// middleware, as the router does under SIGN_RESPONSES
+func signedHandler(s *Server, h http.HandlerFunc) http.Handler {
+	return requestIDMiddleware(s.signResponsesMiddleware(h))
+}
+
+func TestSignedResponseCoversStatusAndRequestID(t *testing.T) {
+	s := newTestServer(t, newFakeBackend())
+	handler := signedHandler(s, func(w http.ResponseWriter, r *http.Request) {
+		s.sendError(w, http.StatusBadRequest, "Invalid request body", "")
+	})
+
+	r := httptest.NewRequest(http.MethodPost, "/relay", nil)
+	r.Header.Set("X-Request-ID", "req-1")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	signature, err := hexutil.Decode(w.Header().Get(responseSignatureHeader))
+	if err != nil || len(signature) != signatureLength {
+		t.Fatalf("signature header %q: %v", w.Header().Get(responseSignatureHeader), err)
+	}
+	signature[crypto.RecoveryIDOffset] -= 27
+
+	recovers := func(status int, id string) bool {
+		payload := []byte(strings.Join([]string{strconv.Itoa(status), id, w.Body.String()}, "\n"))
+		pub, err := crypto.SigToPub(accounts.TextHash(crypto.Keccak256(payload)), signature)
+		return err == nil && crypto.PubkeyToAddress(*pub) == s.relayer()
+	}
+	if !recovers(http.StatusBadRequest, "req-1") {
+		t.Fatal("signature does not recover the relayer over status, request ID and body")
+	}
+	if recovers(http.StatusOK, "req-1") {
+		t.Fatal("signature verifies for another status")
+	}
+	if recovers(http.StatusBadRequest, "req-2") {
+		t.Fatal("signature verifies for another request ID")
+	}
+}
+
+func TestSignResponsesRefusesSentHeaders(t *testing.T) {
+	s := newTestServer(t, newFakeBackend())
+	handler := signedHandler(s, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		writeJSON(w, http.StatusOK, RelayResponse{Success: true})
+	})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if got := w.Header().Get(responseSignatureHeader); got != "" {
+		t.Fatalf("signed a response after its headers were sent: %s", got)
+	}
+}
+
+func TestSignResponsesRejectsEventStream(t *testing.T) {
+	s := newTestServer(t, newFakeBackend())
+	handler := signedHandler(s, s.relayHandler)
+
+	r := httptest.NewRequest(http.MethodPost, "/relay", strings.NewReader("{}"))
+	r.Header.Set("Accept", "text/event-stream")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotAcceptable || !strings.Contains(w.Body.String(), "SSE_NOT_SIGNED") {
+		t.Fatalf("got %d %s, want 406 SSE_NOT_SIGNED", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Content-Type") == "text/event-stream" {
+		t.Fatal("started an unsigned event stream")
+	}
+	if w.Header().Get(responseSignatureHeader) == "" {
+		t.Fatal("rejection was not signed")
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// minedScanDepth is how many recent blocks are scanned for the transaction
+// that consumed a nonce when no receipt turned up for any of our hashes
+const minedScanDepth = 64
+
+// checkNonceMined reports whether nonce is already below the relayer's
+// confirmed nonce. If so, the relay's outcome is taken from whichever of its
+// broadcasts was mined rather than resubmitting. A nonce consumed by any
+// other transaction fails the relay with errNonceConsumed.
+func (s *Server) checkNonceMined(nonce uint64, target common.Address, sub *submission) (*TxResult, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	latest, err := s.client.NonceAt(ctx, s.relayer(), nil)
+	if err != nil {
+		// Can't tell; let the resubmission go ahead as before
+		log.Printf("⚠️  Could not check confirmed nonce before resubmitting: %v\n", err)
+		return nil, false, nil
+	}
+	if nonce >= latest {
+		return nil, false, nil
+	}
+
+	log.Printf("ℹ️  Nonce %d already confirmed (latest %d), not resubmitting\n", nonce, latest)
+	s.metrics.Inc("relay_resubmit_skipped_total")
+
+	// Most likely one of our own broadcasts was mined
+	for _, tx := range sub.sent {
+		receipt, err := s.client.TransactionReceipt(ctx, tx.Hash())
+		if err == nil && receipt != nil {
+			result, err := s.receiptResult(tx, receipt, target, sub)
+			return result, true, err
+		}
+	}
+
+	tx, receipt, err := s.findMinedAtNonce(ctx, nonce)
+	if err != nil {
+		return nil, true, fmt.Errorf("nonce %d already used, original transaction not found: %v", nonce, err)
+	}
+	if !sub.broadcast(tx.Hash()) {
+		log.Printf("❌ Nonce %d was consumed by %s, not by this relay\n", nonce, tx.Hash().Hex())
+		s.metrics.Inc("relay_nonce_consumed_total")
+		s.supersedeTxs(sub, tx.Hash())
+		return nil, true, fmt.Errorf("%w: nonce %d used by %s", errNonceConsumed, nonce, tx.Hash().Hex())
+	}
+	result, err := s.receiptResult(tx, receipt, target, sub)
+	return result, true, err
+}
+
+// broadcast reports whether txHash is one of the submission's broadcasts
+func (sub *submission) broadcast(txHash common.Hash) bool {
+	for _, tx := range sub.sent {
+		if tx.Hash() == txHash {
+			return true
+		}
+	}
+	return false
+}
+
+// findMinedAtNonce scans recent blocks for the relayer's transaction at nonce
+func (s *Server) findMinedAtNonce(ctx context.Context, nonce uint64) (*types.Transaction, *types.Receipt, error) {
+	head, err := s.client.BlockNumber(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	relayer := s.relayer()
+	signer := types.LatestSignerForChainID(s.config.ChainID)
+	for n := head; n+minedScanDepth > head; n-- {
+		block, err := s.client.BlockByNumber(ctx, new(big.Int).SetUint64(n))
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, tx := range block.Transactions() {
+			if tx.Nonce() != nonce {
+				continue
+			}
+			if from, err := types.Sender(signer, tx); err != nil || from != relayer {
+				continue
+			}
+			receipt, err := s.client.TransactionReceipt(ctx, tx.Hash())
+			if err != nil {
+				return nil, nil, err
+			}
+			return tx, receipt, nil
+		}
+		if n == 0 {
+			break
+		}
+	}
+	return nil, nil, fmt.Errorf("not in the last %d blocks", minedScanDepth)
+}
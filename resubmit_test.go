@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// signedRelayTx signs a relay transaction at nonce and gasPrice with the
+// server's key
+func signedRelayTx(t *testing.T, s *Server, nonce uint64, gasPrice int64, data []byte) *types.Transaction {
+	t.Helper()
+	tx := s.newRelayTx(nonce, testHub(t).Address, 100000, big.NewInt(gasPrice), nil, big.NewInt(0), data, nil)
+	signed, err := s.currentSigner().SignTx(context.Background(), tx, s.config.ChainID)
+	if err != nil {
+		t.Fatalf("SignTx: %v", err)
+	}
+	return signed
+}
+
+// broadcastSubmission returns a submission whose only broadcast is tx,
+// recorded as pending
+func broadcastSubmission(s *Server, tx *types.Transaction) *submission {
+	nonce := tx.Nonce()
+	s.txStore.Put(TxRecord{TxHash: tx.Hash().Hex(), Status: txPending, SubmittedAt: time.Now().Unix()})
+	return &submission{nonce: &nonce, gasPrice: tx.GasPrice(), sent: []*types.Transaction{tx}}
+}
+
+func TestResubmitPicksUpBroadcastConfirmedMeanwhile(t *testing.T) {
+	backend := newFakeBackend()
+	s := newTestServer(t, backend)
+	tx := signedRelayTx(t, s, 0, 10e9, []byte{1})
+	sub := broadcastSubmission(s, tx)
+
+	// The original broadcast confirms just before the resubmission
+	backend.mine(tx)
+
+	result, err := s.submitTransaction([]byte{1}, testHub(t).Address, common.Address{}, sub, true)
+	if err != nil {
+		t.Fatalf("submitTransaction: %v", err)
+	}
+	if result.TxHash != tx.Hash().Hex() {
+		t.Fatalf("result for %s, want the confirmed %s", result.TxHash, tx.Hash().Hex())
+	}
+	if sent := backend.sentTxs(); len(sent) != 0 {
+		t.Fatalf("sent %d replacement(s) for a confirmed nonce", len(sent))
+	}
+	if rec, _ := s.txStore.Get(tx.Hash().Hex()); rec.Status != txConfirmed {
+		t.Fatalf("status = %q, want %q", rec.Status, txConfirmed)
+	}
+}
+
+func TestResubmitFailsWhenAnotherTxTookTheNonce(t *testing.T) {
+	backend := newFakeBackend()
+	s := newTestServer(t, backend)
+	ours := signedRelayTx(t, s, 0, 10e9, []byte{1})
+	sub := broadcastSubmission(s, ours)
+
+	// A different relay from the same key was mined at the nonce instead
+	other := signedRelayTx(t, s, 0, 20e9, []byte{2})
+	backend.mine(other)
+
+	result, err := s.submitTransaction([]byte{1}, testHub(t).Address, common.Address{}, sub, true)
+	if !errors.Is(err, errNonceConsumed) {
+		t.Fatalf("got result %+v, err %v; want errNonceConsumed", result, err)
+	}
+	if s.config.Retry.Retryable(err) {
+		t.Fatal("a consumed nonce must not be retried")
+	}
+	if sent := backend.sentTxs(); len(sent) != 0 {
+		t.Fatalf("sent %d replacement(s) for a consumed nonce", len(sent))
+	}
+	if rec, _ := s.txStore.Get(ours.Hash().Hex()); rec.Status != txReplaced {
+		t.Fatalf("status = %q, want %q", rec.Status, txReplaced)
+	}
+}
+
+func TestFinishRelayDoesNotMarkConsumedNonceProcessed(t *testing.T) {
+	s := newTestServer(t, newFakeBackend())
+	req := testRelayRequest()
+	requestID := requestHash(req.Forward, make([]byte, signatureLength))
+
+	s.finishRelay(req, requestID, "", nil, errNonceConsumed)
+	if s.isProcessed(requestID) {
+		t.Fatal("request marked processed although it never executed")
+	}
+}
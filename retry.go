@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Retry classes for errors that may be retried by the relay pipeline
+const (
+	retryClassSend     = "send"
+	retryClassEstimate = "estimate"
+	retryClassTimeout  = "timeout"
+)
+
+// maxUnderpricedBumps bounds gas price bumps after "replacement transaction underpriced"
+const maxUnderpricedBumps = 3
+
+// RetryPolicy controls how a relay is retried across submission attempts
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	RetryOn     map[string]bool
+}
+
+// attemptError tags a submission error with its retry class. Errors that are
+// not wrapped in an attemptError (reverts, encoding errors) are never retried.
+type attemptError struct {
+	class string
+	err   error
+}
+
+func (e *attemptError) Error() string { return e.err.Error() }
+func (e *attemptError) Unwrap() error { return e.err }
+
+// retryable wraps err with a retry class
+func retryable(class string, err error) error {
+	return &attemptError{class: class, err: err}
+}
+
+// Retryable reports whether err belongs to a class the policy retries
+func (p RetryPolicy) Retryable(err error) bool {
+	var ae *attemptError
+	if !errors.As(err, &ae) {
+		return false
+	}
+	return p.RetryOn[ae.class]
+}
+
+// Delay returns the exponential backoff before the given attempt's retry
+func (p RetryPolicy) Delay(attempt int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if p.MaxBackoff > 0 && delay >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	return delay
+}
+
+// retryClass returns the retry class of err, or "none"
+func retryClass(err error) string {
+	var ae *attemptError
+	if errors.As(err, &ae) {
+		return ae.class
+	}
+	return "none"
+}
+
+// loadRetryPolicy reads the retry policy from the environment. The default of
+// a single attempt preserves the original fail-once behavior.
+func loadRetryPolicy() (RetryPolicy, error) {
+	maxAttempts, err := getEnvInt("RETRY_MAX_ATTEMPTS", 1)
+	if err != nil {
+		return RetryPolicy{}, err
+	}
+	if maxAttempts < 1 {
+		return RetryPolicy{}, fmt.Errorf("RETRY_MAX_ATTEMPTS must be at least 1")
+	}
+
+	backoff, err := getEnvDuration("RETRY_BACKOFF", 1*time.Second)
+	if err != nil {
+		return RetryPolicy{}, err
+	}
+
+	maxBackoff, err := getEnvDuration("RETRY_MAX_BACKOFF", 30*time.Second)
+	if err != nil {
+		return RetryPolicy{}, err
+	}
+
+	retryOn := make(map[string]bool)
+	for _, class := range strings.Split(getEnv("RETRY_ON", "send,estimate,timeout"), ",") {
+		class = strings.TrimSpace(class)
+		switch class {
+		case retryClassSend, retryClassEstimate, retryClassTimeout:
+			retryOn[class] = true
+		case "":
+		default:
+			return RetryPolicy{}, fmt.Errorf("invalid RETRY_ON class %q", class)
+		}
+	}
+
+	return RetryPolicy{
+		MaxAttempts: maxAttempts,
+		Backoff:     backoff,
+		MaxBackoff:  maxBackoff,
+		RetryOn:     retryOn,
+	}, nil
+}
+
+// isExecutionError reports whether an estimation error comes from the EVM
+// (a deterministic revert) rather than a transient RPC problem
+func isExecutionError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "revert") || strings.Contains(msg, "execution")
+}
+
+// isTransientSendError reports whether a send error may succeed on another
+// attempt: transport failures and overloaded nodes, as opposed to the node
+// rejecting the transaction itself
+func isTransientSendError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var httpErr rpc.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusTooManyRequests || httpErr.StatusCode >= http.StatusInternalServerError
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, transient := range []string{"timeout", "connection refused", "connection reset", "too many requests", "txpool is full", "transaction pool is full"} {
+		if strings.Contains(msg, transient) {
+			return true
+		}
+	}
+	return false
+}
+
+// isAlreadyKnown reports whether a send error means the node already has
+// the exact transaction in its pool, which is not a failure
+func isAlreadyKnown(err error) bool {
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "already known") || strings.Contains(msg, "known transaction")
+}
+
+// isReplacementUnderpriced reports whether a send error means another pool
+// transaction holds the nonce and a higher gas price is needed
+func isReplacementUnderpriced(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "replacement transaction underpriced")
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func TestIsTransientSendError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{context.DeadlineExceeded, true},
+		{fmt.Errorf("send: %w", context.DeadlineExceeded), true},
+		{errors.New("dial tcp 127.0.0.1:8545: connect: connection refused"), true},
+		{errors.New("read: connection reset by peer"), true},
+		{rpc.HTTPError{StatusCode: http.StatusTooManyRequests, Status: "429 Too Many Requests"}, true},
+		{rpc.HTTPError{StatusCode: http.StatusBadGateway, Status: "502 Bad Gateway"}, true},
+		{rpc.HTTPError{StatusCode: http.StatusUnauthorized, Status: "401 Unauthorized"}, false},
+		{errors.New("txpool is full"), true},
+		{errors.New("insufficient funds for gas * price + value"), false},
+		{errors.New("intrinsic gas too low"), false},
+		{errors.New("nonce too low"), false},
+		{errors.New("exceeds block gas limit"), false},
+		{errors.New("invalid sender"), false},
+	}
+	for _, tt := range tests {
+		if got := isTransientSendError(tt.err); got != tt.want {
+			t.Errorf("isTransientSendError(%q) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+// retryServer returns a server that retries sends up to three times
+func retryServer(t *testing.T, backend *fakeBackend) *Server {
+	s := newTestServer(t, backend)
+	s.config.Retry = RetryPolicy{MaxAttempts: 3, RetryOn: map[string]bool{retryClassSend: true, retryClassTimeout: true}}
+	return s
+}
+
+func TestSubmitWithRetryStopsOnNonTransientSendError(t *testing.T) {
+	backend := newFakeBackend()
+	backend.sendErrs = []error{errors.New("insufficient funds for gas * price + value")}
+	s := retryServer(t, backend)
+
+	_, err := s.submitWithRetry([]byte{1}, testHub(t), common.Address{}, &submission{})
+	if err == nil {
+		t.Fatal("expected the send error to be returned")
+	}
+	if s.config.Retry.Retryable(err) {
+		t.Fatalf("non-transient send error classified as retryable: %v", err)
+	}
+	if sent := backend.sentTxs(); len(sent) != 0 {
+		t.Fatalf("retried after a final error: %d transaction(s) sent", len(sent))
+	}
+}
+
+func TestSubmitWithRetryRetriesTransientSendError(t *testing.T) {
+	backend := newFakeBackend()
+	backend.sendErrs = []error{errors.New("read: connection reset by peer")}
+	s := retryServer(t, backend)
+
+	result, err := s.submitWithRetry([]byte{1}, testHub(t), common.Address{}, &submission{})
+	if err != nil {
+		t.Fatalf("submitWithRetry: %v", err)
+	}
+	if result.Attempts != 2 {
+		t.Fatalf("attempts = %d, want 2", result.Attempts)
+	}
+}
+
+func TestResubmitStopsWhenBumpIsCappedAtCeiling(t *testing.T) {
+	backend := newFakeBackend()
+	s := newTestServer(t, backend)
+
+	// The previous broadcast already pays the ceiling, so no 10% bump fits
+	nonce := uint64(0)
+	sub := &submission{nonce: &nonce, gasPrice: new(big.Int).Set(s.config.MaxGasPrice)}
+	_, err := s.submitTransaction([]byte{1}, testHub(t).Address, common.Address{}, sub, false)
+	if !errors.Is(err, errReplacementCapped) {
+		t.Fatalf("err = %v, want errReplacementCapped", err)
+	}
+	if s.config.Retry.Retryable(err) {
+		t.Fatal("a capped replacement must not be retried")
+	}
+	if sent := backend.sentTxs(); len(sent) != 0 {
+		t.Fatalf("sent %d underpriced replacement(s)", len(sent))
+	}
+}
+
+func TestSendErrorClassification(t *testing.T) {
+	tests := []struct {
+		msg          string
+		alreadyKnown bool
+		underpriced  bool
+	}{
+		{"already known", true, false},
+		{"ALREADY KNOWN", true, false},
+		{"known transaction: 0x4c3e", true, false},
+		{"replacement transaction underpriced", false, true},
+		{"Replacement Transaction Underpriced", false, true},
+		{"transaction underpriced", false, false},
+		{"nonce too low", false, false},
+		{"insufficient funds for gas * price + value", false, false},
+	}
+	for _, tt := range tests {
+		err := fmt.Errorf("failed to send transaction: %w", errors.New(tt.msg))
+		if got := isAlreadyKnown(err); got != tt.alreadyKnown {
+			t.Errorf("isAlreadyKnown(%q) = %v, want %v", tt.msg, got, tt.alreadyKnown)
+		}
+		if got := isReplacementUnderpriced(err); got != tt.underpriced {
+			t.Errorf("isReplacementUnderpriced(%q) = %v, want %v", tt.msg, got, tt.underpriced)
+		}
+	}
+}
+
+func TestSubmitWaitsOnAlreadyKnownTransaction(t *testing.T) {
+	backend := newFakeBackend()
+	// The node already holds the exact transaction and later mines it
+	backend.sendHook = func(tx *types.Transaction) error {
+		backend.mineLocked(tx)
+		return errors.New("already known")
+	}
+	s := newTestServer(t, backend)
+
+	result, err := s.submitTransaction([]byte{1}, testHub(t).Address, common.Address{}, &submission{}, true)
+	if err != nil {
+		t.Fatalf("submitTransaction: %v", err)
+	}
+	if result.BlockNumber != 1 {
+		t.Fatalf("block = %d, want the mined block 1", result.BlockNumber)
+	}
+}
+
+func TestSubmitSkipsForeignNonceWithoutBumping(t *testing.T) {
+	backend := newFakeBackend()
+	// Something outside this relay holds nonce 0 in the pool
+	backend.sendHook = func(tx *types.Transaction) error {
+		if tx.Nonce() == 0 {
+			backend.pendingNonce = 1
+			return errors.New("replacement transaction underpriced")
+		}
+		return nil
+	}
+	s := newTestServer(t, backend)
+
+	if _, err := s.submitTransaction([]byte{1}, testHub(t).Address, common.Address{}, &submission{}, true); err != nil {
+		t.Fatalf("submitTransaction: %v", err)
+	}
+	sent := backend.sentTxs()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d transactions, want 1", len(sent))
+	}
+	if sent[0].Nonce() != 1 {
+		t.Fatalf("nonce = %d, want the next free nonce 1", sent[0].Nonce())
+	}
+	if sent[0].GasPrice().Cmp(backend.gasPrice) != 0 {
+		t.Fatalf("gas price = %s, want the unbumped %s", sent[0].GasPrice(), backend.gasPrice)
+	}
+}
+
+func TestSubmitBumpsOwnReplacementAndDebitsBumpedCost(t *testing.T) {
+	backend := newFakeBackend()
+	backend.sendErrs = []error{errors.New("replacement transaction underpriced")}
+	s := newTestServer(t, backend)
+
+	// Replacing our own broadcast at nonce 0, first paying 10 gwei
+	nonce := uint64(0)
+	sub := &submission{nonce: &nonce, gasPrice: big.NewInt(10e9)}
+	if _, err := s.submitTransaction([]byte{1}, testHub(t).Address, common.Address{}, sub, true); err != nil {
+		t.Fatalf("submitTransaction: %v", err)
+	}
+
+	sent := backend.sentTxs()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d transactions, want 1", len(sent))
+	}
+	// 10 gwei bumped once to replace, then again after the underpriced error
+	want := big.NewInt(12.1e9)
+	if sent[0].Nonce() != 0 || sent[0].GasPrice().Cmp(want) != 0 {
+		t.Fatalf("sent nonce %d at %s, want nonce 0 at %s", sent[0].Nonce(), sent[0].GasPrice(), want)
+	}
+
+	cost := new(big.Int).Mul(new(big.Int).SetUint64(sent[0].Gas()), want)
+	wantBalance := new(big.Int).Sub(backend.balance, cost)
+	if s.balance.Cmp(wantBalance) != 0 {
+		t.Fatalf("balance = %s, want %s debited at the bumped price", s.balance, wantBalance)
+	}
+}
+
+func TestSubmitRejectsHugeGasEstimateOverRequestBudget(t *testing.T) {
+	backend := newFakeBackend()
+	// A contract inflating its estimate to make the relayer burn gas
+	backend.estimate = 25000000
+	s := newTestServer(t, backend)
+	s.config.MaxRequestCost = big.NewInt(1e16)
+
+	_, err := s.submitTransaction([]byte{1}, testHub(t).Address, common.Address{}, &submission{}, true)
+	if !errors.Is(err, errRequestTooExpensive) {
+		t.Fatalf("err = %v, want errRequestTooExpensive", err)
+	}
+	if s.config.Retry.Retryable(err) {
+		t.Fatal("an over-budget request must not be retried")
+	}
+	if sent := backend.sentTxs(); len(sent) != 0 {
+		t.Fatalf("sent %d over-budget transaction(s)", len(sent))
+	}
+}
+
+func TestWorstCaseCostIncludesValue(t *testing.T) {
+	gasPrice := big.NewInt(10e9)
+	gasCost := new(big.Int).Mul(big.NewInt(100000), gasPrice)
+	value := big.NewInt(1e18)
+
+	tests := []struct {
+		name    string
+		budget  *big.Int
+		balance *big.Int
+		want    error
+	}{
+		{"fits", nil, new(big.Int).Add(gasCost, value), nil},
+		{"value over budget", new(big.Int).Add(gasCost, big.NewInt(1)), new(big.Int).Mul(value, big.NewInt(10)), errRequestTooExpensive},
+		{"value over balance", nil, new(big.Int).Add(gasCost, big.NewInt(1)), errInsufficientRelayerFunds},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newFakeBackend()
+			backend.balance = tt.balance
+			s := newTestServer(t, backend)
+			s.config.MaxRequestCost = tt.budget
+
+			cost, err := s.checkWorstCaseCost(&submission{value: value}, 100000, gasPrice, true)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if err == nil && cost.Cmp(new(big.Int).Add(gasCost, value)) != 0 {
+				t.Fatalf("cost = %s, want gas %s plus value %s", cost, gasCost, value)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// RotateKeyRequest supplies the new relayer key either directly or as an
+// encrypted keystore file in KEYSTORE_DIR
+type RotateKeyRequest struct {
+	PrivateKey   string `json:"privateKey,omitempty"`
+	KeystorePath string `json:"keystorePath,omitempty"`
+	Password     string `json:"password,omitempty"`
+}
+
+// RotateKeyResponse reports the outcome of a key rotation
+type RotateKeyResponse struct {
+	Success    bool   `json:"success"`
+	OldAddress string `json:"oldAddress"`
+	NewAddress string `json:"newAddress"`
+}
+
+// relayer returns the address transactions are currently sent from
+func (s *Server) relayer() common.Address {
+	s.signerMutex.RLock()
+	defer s.signerMutex.RUnlock()
+	return s.relayerAddress
+}
+
+// currentSigner returns the signer transactions are currently signed with
+func (s *Server) currentSigner() TxSigner {
+	s.signerMutex.RLock()
+	defer s.signerMutex.RUnlock()
+	return s.signer
+}
+
+// loadRotationSigner builds a local signer from a rotation request, reading
+// keystore files only from keystoreDir
+func loadRotationSigner(req RotateKeyRequest, keystoreDir string) (*localSigner, error) {
+	switch {
+	case req.PrivateKey != "" && req.KeystorePath != "":
+		return nil, fmt.Errorf("provide either privateKey or keystorePath, not both")
+	case req.PrivateKey != "":
+		return newLocalSigner(req.PrivateKey)
+	case req.KeystorePath != "":
+		if keystoreDir == "" {
+			return nil, fmt.Errorf("keystorePath requires KEYSTORE_DIR to be configured")
+		}
+		if !filepath.IsLocal(req.KeystorePath) {
+			return nil, fmt.Errorf("keystorePath must be a relative path inside KEYSTORE_DIR")
+		}
+		// Missing, unreadable and undecryptable files get the same answer so
+		// the endpoint can't be used to probe the filesystem
+		raw, err := os.ReadFile(filepath.Join(keystoreDir, req.KeystorePath))
+		if err != nil {
+			log.Printf("⚠️  Failed to read keystore %s: %v\n", req.KeystorePath, err)
+			return nil, fmt.Errorf("failed to load keystore")
+		}
+		key, err := keystore.DecryptKey(raw, req.Password)
+		if err != nil {
+			log.Printf("⚠️  Failed to decrypt keystore %s: %v\n", req.KeystorePath, err)
+			return nil, fmt.Errorf("failed to load keystore")
+		}
+		return &localSigner{key: key.PrivateKey, address: crypto.PubkeyToAddress(key.PrivateKey.PublicKey)}, nil
+	default:
+		return nil, fmt.Errorf("privateKey or keystorePath is required")
+	}
+}
+
+// rotateKeyHandler swaps the relayer key at runtime. The new key must be an
+// allowed caller on every hub that exposes isCallerAllowed. Submissions on
+// the old key are drained first: new relays wait while in-flight ones are
+// confirmed, then the signer is swapped and the nonce manager re-seeded from
+// the new key's pending nonce.
+func (s *Server) rotateKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var req RotateKeyRequest
+	if err := s.decodeBody(w, r, &req); err != nil {
+		s.sendBodyError(w, err)
+		return
+	}
+
+	newSigner, err := loadRotationSigner(req, s.config.KeystoreDir)
+	if err != nil {
+		s.sendError(w, http.StatusBadRequest, "Invalid key", err.Error())
+		return
+	}
+	newSigner.homestead = s.config.SignerType == signerHomestead
+	newAddress := newSigner.Address()
+
+	// With EXPECTED_CALLER the intermediate, not the relayer key, calls the
+	// hub, so the new key needn't be an allowed caller
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	for _, hub := range s.config.Hubs {
+		if _, ok := hub.ABI.Methods["isCallerAllowed"]; !ok || s.config.ExpectedCaller != (common.Address{}) {
+			continue
+		}
+		allowed, err := s.isCallerAllowed(ctx, hub, newAddress)
+		if err != nil {
+			s.sendError(w, http.StatusBadGateway, "Failed to verify caller permission", err.Error())
+			return
+		}
+		if !allowed {
+			s.sendError(w, http.StatusBadRequest, fmt.Sprintf("New key is not an allowed caller on hub %s", hub.Version), newAddress.Hex())
+			return
+		}
+	}
+
+	log.Printf("🔑 Rotating relayer key to %s, draining in-flight transactions...\n", newAddress.Hex())
+	s.submitMutex.Lock()
+	defer s.submitMutex.Unlock()
+
+	s.signerMutex.Lock()
+	oldAddress := s.relayerAddress
+	s.signer = newSigner
+	s.relayerAddress = newAddress
+	s.signerMutex.Unlock()
+
+	// Re-seed from the new key's pending nonce on the next submission
+	s.nonces.Reset()
+
+	// The cached balance belonged to the old key
+	s.balanceMutex.Lock()
+	s.balance = nil
+	s.balanceMutex.Unlock()
+
+	log.Printf("✅ Relayer key rotated: %s -> %s\n", oldAddress.Hex(), newAddress.Hex())
+	s.metrics.Inc("relayer_key_rotations_total")
+
+	writeJSON(w, http.StatusOK, RotateKeyResponse{
+		Success:    true,
+		OldAddress: oldAddress.Hex(),
+		NewAddress: newAddress.Hex(),
+	})
+}
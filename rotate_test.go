@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// rotateKey posts req to the rotation handler
+func rotateKey(t *testing.T, s *Server, req RotateKeyRequest) *httptest.ResponseRecorder {
+	t.Helper()
+	s.config.MaxBodyBytes = 1 << 20
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	w := httptest.NewRecorder()
+	s.rotateKeyHandler(w, httptest.NewRequest(http.MethodPost, "/admin/rotate-key", bytes.NewReader(body)))
+	return w
+}
+
+func TestRotateKeyCallerCheck(t *testing.T) {
+	tests := []struct {
+		name           string
+		expectedCaller common.Address
+		want           int
+	}{
+		{"relayer calls the hub", common.Address{}, http.StatusBadRequest},
+		{"intermediate calls the hub", common.HexToAddress("0x00000000000000000000000000000000000000c3"), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newFakeBackend()
+			// Only the intermediate is an allowed caller, never the new key
+			backend.views["isCallerAllowed(address)"] = false
+			s := newTestServer(t, backend)
+			s.config.ExpectedCaller = tt.expectedCaller
+
+			key, err := crypto.GenerateKey()
+			if err != nil {
+				t.Fatalf("GenerateKey: %v", err)
+			}
+			w := rotateKey(t, s, RotateKeyRequest{PrivateKey: hex.EncodeToString(crypto.FromECDSA(key))})
+			if w.Code != tt.want {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.want, w.Body.String())
+			}
+			if rotated := s.relayer() == crypto.PubkeyToAddress(key.PublicKey); rotated != (tt.want == http.StatusOK) {
+				t.Fatalf("rotated = %v after status %d", rotated, w.Code)
+			}
+		})
+	}
+}
+
+func TestRotateKeyReadsKeystoresOnlyFromKeystoreDir(t *testing.T) {
+	dir := t.TempDir()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.ImportECDSA(key, "spooky")
+	if err != nil {
+		t.Fatalf("ImportECDSA: %v", err)
+	}
+	name := filepath.Base(account.URL.Path)
+
+	tests := []struct {
+		name     string
+		dir      string
+		path     string
+		password string
+		detail   string
+	}{
+		{"no keystore dir", "", name, "spooky", "keystorePath requires KEYSTORE_DIR to be configured"},
+		{"absolute path", dir, account.URL.Path, "spooky", "keystorePath must be a relative path inside KEYSTORE_DIR"},
+		{"parent directory", dir, "../" + filepath.Base(dir) + "/" + name, "spooky", "keystorePath must be a relative path inside KEYSTORE_DIR"},
+		// A missing file and a wrong password look the same
+		{"missing file", dir, "missing.json", "spooky", "failed to load keystore"},
+		{"wrong password", dir, name, "sweet", "failed to load keystore"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, newFakeBackend())
+			s.config.KeystoreDir = tt.dir
+			w := rotateKey(t, s, RotateKeyRequest{KeystorePath: tt.path, Password: tt.password})
+			var response RelayResponse
+			json.Unmarshal(w.Body.Bytes(), &response)
+			if w.Code != http.StatusBadRequest || response.Details != tt.detail {
+				t.Fatalf("status %d %+v, want 400 with %q", w.Code, response, tt.detail)
+			}
+		})
+	}
+
+	s := newTestServer(t, newFakeBackend())
+	s.config.KeystoreDir = dir
+	if w := rotateKey(t, s, RotateKeyRequest{KeystorePath: name, Password: "spooky"}); w.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200: %s", w.Code, w.Body.String())
+	}
+	if s.relayer() != account.Address {
+		t.Fatalf("relayer = %s, want %s", s.relayer().Hex(), account.Address.Hex())
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// push4 is the EVM opcode that loads a 4-byte selector in a dispatcher
+const push4 = 0x63
+
+// hubSelectorMethods are the hub methods the relayer packs calls for
+func (s *Server) hubSelectorMethods(hub HubConfig) []string {
+	methods := []string{"execute"}
+	if _, ok := hub.ABI.Methods[s.config.FeeExecuteMethod]; ok {
+		methods = append(methods, s.config.FeeExecuteMethod)
+	}
+	if s.config.MerkleBatch {
+		methods = append(methods, s.config.MerkleExecuteMethod)
+	}
+	return methods
+}
+
+// checkHubSelectors looks for the selector of every method the relayer calls
+// in the hub's deployed bytecode, where a Solidity dispatcher PUSH4es it. A
+// missing selector means the ABI the relayer packs with has drifted from the
+// contract. Hubs behind a proxy hold no dispatcher, so a miss there is only
+// a hint; hence HUB_SELECTOR_STRICT defaults to off.
+func (s *Server) checkHubSelectors(ctx context.Context) (string, error) {
+	var found []string
+	for _, hub := range s.config.Hubs {
+		code, err := s.client.CodeAt(ctx, hub.Address, nil)
+		if err != nil {
+			return "", fmt.Errorf("hub %s: %v", hub.Version, err)
+		}
+
+		for _, name := range s.hubSelectorMethods(hub) {
+			method, ok := hub.ABI.Methods[name]
+			if !ok {
+				return "", fmt.Errorf("hub %s: ABI has no %s", hub.Version, name)
+			}
+			selector := hexutil.Encode(method.ID)
+			if !bytes.Contains(code, append([]byte{push4}, method.ID...)) {
+				return "", fmt.Errorf("hub %s: %s selector %s (%s) not found in deployed bytecode", hub.Version, name, selector, method.Sig)
+			}
+			found = append(found, fmt.Sprintf("%s:%s=%s", hub.Version, name, selector))
+		}
+	}
+	return strings.Join(found, ", "), nil
+}
+
+// checkHubSelectorsHealth reports a selector miss as a failing check only
+// with HUB_SELECTOR_STRICT, so proxied hubs don't degrade health forever
+func (s *Server) checkHubSelectorsHealth(ctx context.Context) (string, error) {
+	detail, err := s.checkHubSelectors(ctx)
+	if err != nil && !s.config.HubSelectorStrict {
+		return "unverified: " + err.Error(), nil
+	}
+	return detail, err
+}
+
+// logHubSelectors reports the selectors packed for each hub at startup and
+// checks them against the deployed bytecode, failing only when
+// HUB_SELECTOR_STRICT is set
+func (s *Server) logHubSelectors() error {
+	for _, hub := range s.config.Hubs {
+		for _, name := range s.hubSelectorMethods(hub) {
+			if method, ok := hub.ABI.Methods[name]; ok {
+				log.Printf("🧬 Hub %s %s selector: %s (%s)\n", hub.Version, name, hexutil.Encode(method.ID), method.Sig)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	if _, err := s.checkHubSelectors(ctx); err != nil {
+		if s.config.HubSelectorStrict {
+			return err
+		}
+		log.Printf("⚠️  Hub ABI check: %v\n", err)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+)
+
+// selfTest simulates a canned meta-transaction with eth_call to catch broken
+// deployments (wrong domain, bad ABI, disallowed caller) before real traffic.
+//
+// SELFTEST_FIXTURE points at a JSON file in the /relay request format whose
+// forward is signed for the deployed hub with caller set to the relayer and
+// a deadline far in the future. Since the call is only simulated, the
+// fixture can be reused across restarts.
+func (s *Server) selfTest() error {
+	raw, err := os.ReadFile(s.config.SelfTestFixture)
+	if err != nil {
+		return fmt.Errorf("failed to read fixture: %v", err)
+	}
+
+	var req RelayRequest
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return fmt.Errorf("invalid fixture: %v", err)
+	}
+
+	hub, ok := s.resolveHub(req.HubVersion)
+	if !ok {
+		return fmt.Errorf("fixture names unknown hub version %q", req.HubVersion)
+	}
+
+	var signature []byte
+	if req.SignatureRSV != nil {
+		signature = req.SignatureRSV.Bytes()
+	} else if signature, err = decodeHex(req.Signature); err != nil {
+		return fmt.Errorf("invalid fixture signature: %v", err)
+	}
+
+	callData, err := decodeHex(req.CallData)
+	if err != nil {
+		return fmt.Errorf("invalid fixture callData: %v", err)
+	}
+
+	data, err := hub.ABI.Pack("execute", req.Forward.Tuple(), callData, signature)
+	if err != nil {
+		return fmt.Errorf("failed to pack execute: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	to := s.executeTarget(hub.Address)
+	_, err = s.client.CallContract(ctx, ethereum.CallMsg{
+		From: s.relayer(),
+		To:   &to,
+		Data: data,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("simulated execute on hub %s reverted: %v", hub.Version, err)
+	}
+	return nil
+}
+
+// runSelfTest runs the startup self-test if enabled, returning an error only
+// when SELFTEST_STRICT requires a passing test to start
+func (s *Server) runSelfTest() error {
+	if !s.config.SelfTest {
+		return nil
+	}
+
+	log.Println("🧪 Running startup self-test...")
+	if err := s.selfTest(); err != nil {
+		log.Printf("❌ Self-test failed: %v\n", err)
+		if s.config.SelfTestStrict {
+			return err
+		}
+		return nil
+	}
+	log.Println("✅ Self-test passed")
+	return nil
+}
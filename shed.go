@@ -0,0 +1,45 @@
+package main
+
+import (
+	"math"
+	"math/big"
+	"math/rand/v2"
+	"time"
+)
+
+// gasShedProbability returns the fraction of relays to shed at gasPrice:
+// 0 at or below soft, 1 at or above ceiling, and ((gasPrice - soft) /
+// (ceiling - soft)) ^ exponent in between. An exponent above 1 sheds gently
+// just over the soft limit and steeply near the cap; below 1 the reverse.
+func gasShedProbability(gasPrice, soft, ceiling *big.Int, exponent float64) float64 {
+	if gasPrice.Cmp(soft) <= 0 {
+		return 0
+	}
+	if gasPrice.Cmp(ceiling) >= 0 {
+		return 1
+	}
+
+	over, _ := new(big.Float).SetInt(new(big.Int).Sub(gasPrice, soft)).Float64()
+	span, _ := new(big.Float).SetInt(new(big.Int).Sub(ceiling, soft)).Float64()
+	return math.Pow(over/span, exponent)
+}
+
+// shedForGas decides whether to turn a relay away at gasPrice under
+// GAS_SOFT_LIMIT_GWEI. When it does, it also returns how long the client
+// should wait: GAS_RETRY_AFTER scaled by the shed probability, so clients
+// come back sooner when gas is only just over the soft limit.
+func (s *Server) shedForGas(gasPrice *big.Int) (bool, time.Duration) {
+	if s.config.GasSoftLimit == nil {
+		return false, 0
+	}
+
+	p := gasShedProbability(gasPrice, s.config.GasSoftLimit, s.config.MaxGasPrice, s.config.GasShedExponent)
+	if p == 0 || rand.Float64() >= p {
+		return false, 0
+	}
+	retryAfter := time.Duration(p * float64(s.config.GasRetryAfter))
+	if retryAfter < time.Second {
+		retryAfter = time.Second
+	}
+	return true, retryAfter
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// TxSigner signs relayer transactions
+type TxSigner interface {
+	Address() common.Address
+	SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+}
+
+// Local signing schemes selected by SIGNER_TYPE
+const (
+	signerEIP155    = "eip155"
+	signerHomestead = "homestead"
+)
+
+// localSigner signs with an in-process private key
+type localSigner struct {
+	key     *ecdsa.PrivateKey
+	address common.Address
+	// homestead signs without a chain ID for devnets that reject EIP-155
+	homestead bool
+}
+
+// newLocalSigner loads a hex-encoded private key
+func newLocalSigner(hexKey string) (*localSigner, error) {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(hexKey, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load private key: %v", err)
+	}
+	return &localSigner{key: key, address: crypto.PubkeyToAddress(key.PublicKey)}, nil
+}
+
+func (l *localSigner) Address() common.Address { return l.address }
+
+func (l *localSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	if l.homestead {
+		return types.SignTx(tx, types.HomesteadSigner{}, l.key)
+	}
+	// The latest signer handles EIP-155 legacy, access list and dynamic fee txs
+	return types.SignTx(tx, types.LatestSignerForChainID(chainID), l.key)
+}
+
+// remoteSigner delegates signing to a clef or web3signer endpoint so the
+// private key never enters the relayer process
+type remoteSigner struct {
+	client  *rpc.Client
+	address common.Address
+	kind    string
+}
+
+// newRemoteSigner connects to a clef ("clef") or web3signer ("web3signer") endpoint
+func newRemoteSigner(url, kind string, address common.Address) (*remoteSigner, error) {
+	if kind != "clef" && kind != "web3signer" {
+		return nil, fmt.Errorf("unsupported remote signer type %q", kind)
+	}
+
+	client, err := rpc.Dial(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to remote signer: %v", err)
+	}
+	return &remoteSigner{client: client, address: address, kind: kind}, nil
+}
+
+func (r *remoteSigner) Address() common.Address { return r.address }
+
+func (r *remoteSigner) SignTx(ctx context.Context, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	args := map[string]interface{}{
+		"from":    r.address,
+		"to":      tx.To(),
+		"gas":     hexutil.Uint64(tx.Gas()),
+		"value":   (*hexutil.Big)(tx.Value()),
+		"nonce":   hexutil.Uint64(tx.Nonce()),
+		"chainId": (*hexutil.Big)(chainID),
+	}
+	if tx.Type() == types.DynamicFeeTxType {
+		args["maxFeePerGas"] = (*hexutil.Big)(tx.GasFeeCap())
+		args["maxPriorityFeePerGas"] = (*hexutil.Big)(tx.GasTipCap())
+	} else {
+		args["gasPrice"] = (*hexutil.Big)(tx.GasPrice())
+	}
+	// clef expects "input", web3signer expects "data"
+	if r.kind == "clef" {
+		args["input"] = hexutil.Bytes(tx.Data())
+	} else {
+		args["data"] = hexutil.Bytes(tx.Data())
+	}
+	if tx.Type() == types.AccessListTxType || tx.Type() == types.DynamicFeeTxType {
+		args["accessList"] = tx.AccessList()
+	}
+
+	var raw hexutil.Bytes
+	switch r.kind {
+	case "clef":
+		var result struct {
+			Raw hexutil.Bytes `json:"raw"`
+		}
+		if err := r.client.CallContext(ctx, &result, "account_signTransaction", args); err != nil {
+			return nil, fmt.Errorf("remote signer: %v", err)
+		}
+		raw = result.Raw
+	default:
+		if err := r.client.CallContext(ctx, &raw, "eth_signTransaction", args); err != nil {
+			return nil, fmt.Errorf("remote signer: %v", err)
+		}
+	}
+
+	signed := new(types.Transaction)
+	if err := signed.UnmarshalBinary(raw); err != nil {
+		return nil, fmt.Errorf("remote signer returned an invalid transaction: %v", err)
+	}
+
+	// Make sure the signer didn't sign something else or with another key
+	if !sameUnsignedTx(signed, tx, chainID) {
+		return nil, fmt.Errorf("remote signer returned a transaction that does not match the request")
+	}
+	sender, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+	if err != nil {
+		return nil, fmt.Errorf("remote signer returned an unrecoverable signature: %v", err)
+	}
+	if sender != r.address {
+		return nil, fmt.Errorf("remote signer returned a transaction that does not match the request")
+	}
+
+	return signed, nil
+}
+
+// sameUnsignedTx reports whether signed carries exactly the fields of the
+// unsigned tx that was sent for signing, on chainID
+func sameUnsignedTx(signed, tx *types.Transaction, chainID *big.Int) bool {
+	if signed.Type() != tx.Type() || signed.Nonce() != tx.Nonce() || signed.Gas() != tx.Gas() {
+		return false
+	}
+	if (signed.To() == nil) != (tx.To() == nil) || (tx.To() != nil && *signed.To() != *tx.To()) {
+		return false
+	}
+	if !bytes.Equal(signed.Data(), tx.Data()) || signed.Value().Cmp(tx.Value()) != 0 {
+		return false
+	}
+	if signed.ChainId() == nil || signed.ChainId().Cmp(chainID) != 0 {
+		return false
+	}
+	if signed.GasPrice().Cmp(tx.GasPrice()) != 0 || signed.GasFeeCap().Cmp(tx.GasFeeCap()) != 0 || signed.GasTipCap().Cmp(tx.GasTipCap()) != 0 {
+		return false
+	}
+	return sameAccessList(signed.AccessList(), tx.AccessList())
+}
+
+// sameAccessList compares access lists, treating nil and empty as equal
+func sameAccessList(a, b types.AccessList) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Address != b[i].Address || !slices.Equal(a[i].StorageKeys, b[i].StorageKeys) {
+			return false
+		}
+	}
+	return true
+}
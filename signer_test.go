@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// fakeWeb3Signer answers eth_signTransaction by signing tamper's version of
+// the requested transaction
+type fakeWeb3Signer struct {
+	key     *ecdsa.PrivateKey
+	chainID *big.Int
+	tx      *types.DynamicFeeTx
+	tamper  func(tx *types.DynamicFeeTx)
+}
+
+func (f *fakeWeb3Signer) SignTransaction(args map[string]interface{}) (hexutil.Bytes, error) {
+	tx := *f.tx
+	tx.ChainID = new(big.Int).Set(f.chainID)
+	if f.tamper != nil {
+		f.tamper(&tx)
+	}
+	signed, err := types.SignNewTx(f.key, types.LatestSignerForChainID(tx.ChainID), &tx)
+	if err != nil {
+		return nil, err
+	}
+	return signed.MarshalBinary()
+}
+
+func TestRemoteSignerRejectsTamperedTransactions(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	chainID := big.NewInt(31337)
+	to := common.HexToAddress("0x00000000000000000000000000000000000000f1")
+	unsigned := &types.DynamicFeeTx{
+		Nonce:     7,
+		GasTipCap: big.NewInt(1e9),
+		GasFeeCap: big.NewInt(2e9),
+		Gas:       100000,
+		To:        &to,
+		Value:     big.NewInt(0),
+		Data:      []byte{0x01, 0x02},
+	}
+
+	cases := map[string]func(tx *types.DynamicFeeTx){
+		"honest":      nil,
+		"recipient":   func(tx *types.DynamicFeeTx) { tx.To = &common.Address{0xbe} },
+		"data":        func(tx *types.DynamicFeeTx) { tx.Data = []byte{0xde, 0xad} },
+		"value":       func(tx *types.DynamicFeeTx) { tx.Value = big.NewInt(1) },
+		"chain":       func(tx *types.DynamicFeeTx) { tx.ChainID = big.NewInt(1) },
+		"fee cap":     func(tx *types.DynamicFeeTx) { tx.GasFeeCap = big.NewInt(9e9) },
+		"tip cap":     func(tx *types.DynamicFeeTx) { tx.GasTipCap = big.NewInt(2e9) },
+		"access list": func(tx *types.DynamicFeeTx) { tx.AccessList = types.AccessList{{Address: to}} },
+	}
+	for name, tamper := range cases {
+		t.Run(name, func(t *testing.T) {
+			server := rpc.NewServer()
+			defer server.Stop()
+			fake := &fakeWeb3Signer{key: key, chainID: chainID, tx: unsigned, tamper: tamper}
+			if err := server.RegisterName("eth", fake); err != nil {
+				t.Fatalf("RegisterName: %v", err)
+			}
+			signer := &remoteSigner{client: rpc.DialInProc(server), address: crypto.PubkeyToAddress(key.PublicKey), kind: "web3signer"}
+
+			signed, err := signer.SignTx(context.Background(), types.NewTx(unsigned), chainID)
+			if tamper == nil {
+				if err != nil {
+					t.Fatalf("SignTx: %v", err)
+				}
+				if signed.Nonce() != unsigned.Nonce {
+					t.Fatalf("nonce = %d, want %d", signed.Nonce(), unsigned.Nonce)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), "does not match") {
+				t.Fatalf("SignTx = %v, want a mismatch error", err)
+			}
+		})
+	}
+}
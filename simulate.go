@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// SimulateBatchRequest is a batch of relays to dry-run without submitting
+type SimulateBatchRequest struct {
+	Items []RelayRequest `json:"items"`
+}
+
+// SimulateResult is the dry-run outcome of one batch item. Error is set when
+// the item fails the relayer's own validation; RevertReason when the hub
+// call would revert.
+type SimulateResult struct {
+	Index        int    `json:"index"`
+	WillSucceed  bool   `json:"willSucceed"`
+	Error        string `json:"error,omitempty"`
+	Code         string `json:"code,omitempty"`
+	RevertReason string `json:"revertReason,omitempty"`
+}
+
+// SimulateBatchResponse holds a result per item, in request order
+type SimulateBatchResponse struct {
+	Success bool             `json:"success"`
+	Results []SimulateResult `json:"results"`
+}
+
+// validateSimulateItem runs the same checks /relay does on one item,
+// returning the resolved hub and the decoded signature and callData
+func (s *Server) validateSimulateItem(v *validation, req RelayRequest) (HubConfig, []byte, []byte, bool) {
+	if req.Signature == "" || req.CallData == "" {
+		v.reject(http.StatusBadRequest, "", "Missing required fields: forward, signature, callData", "")
+		return HubConfig{}, nil, nil, false
+	}
+	if s.checkForwardFields(v, req.Forward) {
+		return HubConfig{}, nil, nil, false
+	}
+
+	hub, ok := s.resolveHub(req.HubVersion)
+	if !ok {
+		v.reject(http.StatusBadRequest, "", "Unknown hub version", req.HubVersion)
+		return HubConfig{}, nil, nil, false
+	}
+	sigBytes, err := decodeHex(req.Signature)
+	if err != nil {
+		v.reject(http.StatusBadRequest, "", "Invalid signature format", err.Error())
+		return HubConfig{}, nil, nil, false
+	}
+	if len(sigBytes) != signatureLength && !s.config.Allow1271 {
+		v.reject(http.StatusBadRequest, "INVALID_SIGNATURE_LENGTH", fmt.Sprintf("Invalid signature length: expected %d bytes, got %d", signatureLength, len(sigBytes)), "")
+		return HubConfig{}, nil, nil, false
+	}
+	if s.config.VerifySignature {
+		if err := s.verifySignature(hub.Address, req.Forward, sigBytes); err != nil {
+			v.reject(http.StatusBadRequest, "INVALID_SIGNATURE", "Signature does not match forward.from", err.Error())
+			return HubConfig{}, nil, nil, false
+		}
+	}
+
+	callData, stop := s.checkForward(v, hub, req.Forward, req.CallData)
+	if stop {
+		return HubConfig{}, nil, nil, false
+	}
+	return hub, sigBytes, callData, true
+}
+
+// revertReason extracts the revert reason from a failed eth_call, falling
+// back to the error text when the node returns no decodable reason
+func revertReason(err error) string {
+	var dataErr rpc.DataError
+	if errors.As(err, &dataErr) {
+		if data, ok := dataErr.ErrorData().(string); ok {
+			if raw, decodeErr := hexutil.Decode(data); decodeErr == nil {
+				if reason, unpackErr := abi.UnpackRevert(raw); unpackErr == nil {
+					return reason
+				}
+			}
+		}
+	}
+	return err.Error()
+}
+
+// simulateItem validates one item and simulates its hub call from the relayer
+func (s *Server) simulateItem(ctx context.Context, index int, req RelayRequest) SimulateResult {
+	result := SimulateResult{Index: index}
+
+	v := s.newItemValidation()
+	hub, sigBytes, callData, ok := s.validateSimulateItem(v, req)
+	if !ok {
+		failure, _ := v.first()
+		result.Error = failure.Error
+		result.Code = failure.Code
+		return result
+	}
+	data, err := s.packExecute(req, hub, sigBytes, callData)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	to := s.executeTarget(hub.Address)
+	_, err = s.client.CallContract(ctx, ethereum.CallMsg{
+		From:  s.relayer(),
+		To:    &to,
+		Value: bigOrZero(req.Forward.Value),
+		Data:  data,
+	}, nil)
+	if err != nil {
+		result.RevertReason = revertReason(err)
+		return result
+	}
+	result.WillSucceed = true
+	return result
+}
+
+// simulateBatchHandler dry-runs each item independently so clients can drop
+// the ones that would fail before submitting. Nothing is broadcast or
+// recorded as processed. Anyone can call it, so each batch counts against
+// the client IP's rate limit.
+func (s *Server) simulateBatchHandler(w http.ResponseWriter, r *http.Request) {
+	if !s.enterGlobal() {
+		s.sendRetryAfter(w, http.StatusServiceUnavailable, "Relayer is at capacity. Please try again later.", 1)
+		return
+	}
+	defer s.leaveGlobal()
+
+	clientIP := s.clientIP(r)
+	if s.ipBlocked(clientIP) {
+		log.Printf("❌ Client IP %s is blocklisted\n", clientIP)
+		s.metrics.Inc("relay_ip_blocked_total")
+		s.sendErrorCode(w, http.StatusForbidden, "IP_BLOCKED", "Requests from this address are not allowed", "")
+		return
+	}
+	if ok, retryAfter := s.checkRateLimit("simulate:" + clientIP); !ok {
+		s.metrics.Inc("simulate_rate_limited_total")
+		s.sendRetryAfter(w, http.StatusTooManyRequests, "Too many requests. Please try again later.", retryAfter)
+		return
+	}
+
+	var req SimulateBatchRequest
+	if err := s.decodeBody(w, r, &req); err != nil {
+		s.sendBodyError(w, err)
+		return
+	}
+	if len(req.Items) == 0 {
+		s.sendError(w, http.StatusBadRequest, "Missing required field: items", "")
+		return
+	}
+	if len(req.Items) > s.config.MaxBatchSize {
+		s.sendError(w, http.StatusBadRequest, fmt.Sprintf("Batch exceeds the maximum of %d items", s.config.MaxBatchSize), "")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	response := SimulateBatchResponse{Success: true, Results: make([]SimulateResult, len(req.Items))}
+	for i, item := range req.Items {
+		response.Results[i] = s.simulateItem(ctx, i, item)
+	}
+	writeJSON(w, http.StatusOK, response)
+}
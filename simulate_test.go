@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// simulateRequest returns a relay request s would accept
+func simulateRequest(s *Server) RelayRequest {
+	callData := []byte{0xde, 0xad, 0xbe, 0xef}
+	fwd := testForward(callData)
+	fwd.Caller = s.relayer()
+	return RelayRequest{
+		Forward:   fwd,
+		Signature: "0x" + hex.EncodeToString(make([]byte, signatureLength)),
+		CallData:  "0x" + hex.EncodeToString(callData),
+	}
+}
+
+func TestSimulateItemUsesRelayValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(s *Server, backend *fakeBackend, req *RelayRequest)
+		code  string
+		error string
+	}{
+		{
+			name:  "valid item",
+			setup: func(s *Server, backend *fakeBackend, req *RelayRequest) {},
+		},
+		{
+			name:  "missing deadline",
+			setup: func(s *Server, backend *fakeBackend, req *RelayRequest) { req.Forward.Deadline = nil },
+			code:  "MISSING_FORWARD_FIELD",
+		},
+		{
+			name:  "missing nonce",
+			setup: func(s *Server, backend *fakeBackend, req *RelayRequest) { req.Forward.Nonce = nil },
+			code:  "MISSING_FORWARD_FIELD",
+		},
+		{
+			name:  "short signature",
+			setup: func(s *Server, backend *fakeBackend, req *RelayRequest) { req.Signature = "0x1234" },
+			code:  "INVALID_SIGNATURE_LENGTH",
+		},
+		{
+			name: "signature from another key",
+			setup: func(s *Server, backend *fakeBackend, req *RelayRequest) {
+				s.config.VerifySignature = true
+				s.config.DomainName = "HalloweenHub"
+				s.config.DomainVersion = "1"
+				key, err := crypto.GenerateKey()
+				if err != nil {
+					t.Fatalf("GenerateKey: %v", err)
+				}
+				req.Signature = "0x" + hex.EncodeToString(signForward(t, s, testHub(t).Address, req.Forward, key))
+			},
+			code: "INVALID_SIGNATURE",
+		},
+		{
+			name:  "already minted",
+			setup: func(s *Server, backend *fakeBackend, req *RelayRequest) { backend.views["minted(address)"] = true },
+			error: "You already minted an NFT",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newFakeBackend()
+			s := newTestServer(t, backend)
+			req := simulateRequest(s)
+			tt.setup(s, backend, &req)
+
+			result := s.simulateItem(t.Context(), 0, req)
+			if tt.code == "" && tt.error == "" {
+				if !result.WillSucceed {
+					t.Fatalf("valid item rejected: %+v", result)
+				}
+				return
+			}
+			if result.WillSucceed {
+				t.Fatal("invalid item reported as succeeding")
+			}
+			if result.Code != tt.code || (tt.error != "" && result.Error != tt.error) {
+				t.Fatalf("got %q %q, want %q %q", result.Code, result.Error, tt.code, tt.error)
+			}
+		})
+	}
+}
+
+func TestSimulateBatchLimitsClients(t *testing.T) {
+	s := newTestServer(t, newFakeBackend())
+	s.config.MaxBodyBytes = 1 << 20
+	s.config.MaxBatchSize = 10
+	body, err := json.Marshal(SimulateBatchRequest{Items: []RelayRequest{simulateRequest(s)}})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	simulate := func(remoteAddr string) int {
+		r := httptest.NewRequest("POST", "/simulate/batch", bytes.NewReader(body))
+		r.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		s.simulateBatchHandler(w, r)
+		return w.Code
+	}
+
+	for i := 0; i < maxRequestsPerWindow; i++ {
+		if code := simulate("203.0.113.7:1234"); code != http.StatusOK {
+			t.Fatalf("batch %d: status %d, want 200", i, code)
+		}
+	}
+	if code := simulate("203.0.113.7:1234"); code != http.StatusTooManyRequests {
+		t.Fatalf("status %d over the limit, want 429", code)
+	}
+	if code := simulate("203.0.113.8:1234"); code != http.StatusOK {
+		t.Fatalf("another client: status %d, want 200", code)
+	}
+
+	s.blocklist = func(ip net.IP) bool { return ip.String() == "203.0.113.9" }
+	if code := simulate("203.0.113.9:1234"); code != http.StatusForbidden {
+		t.Fatalf("blocked client: status %d, want 403", code)
+	}
+}
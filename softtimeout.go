@@ -0,0 +1,85 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// relayOutcome is the result of a relay executed in the background
+type relayOutcome struct {
+	result *TxResult
+	err    error
+}
+
+// softTracker forwards relay progress to the client until the handler stops
+// waiting, and remembers the latest broadcast hash for a pending answer
+type softTracker struct {
+	mu        sync.Mutex
+	forward   func(stage, txHash string)
+	txHash    string
+	submitted chan struct{}
+	detached  bool
+}
+
+// newSoftTracker creates a tracker forwarding to forward, which may be nil
+func newSoftTracker(forward func(stage, txHash string)) *softTracker {
+	return &softTracker{forward: forward, submitted: make(chan struct{})}
+}
+
+// progress records a pipeline stage and passes it on while the client waits
+func (t *softTracker) progress(stage, txHash string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if stage == stageSubmitted {
+		if t.txHash == "" {
+			close(t.submitted)
+		}
+		t.txHash = txHash
+	}
+	if t.forward != nil && !t.detached {
+		t.forward(stage, txHash)
+	}
+}
+
+// wait returns the relay outcome, or the pending transaction hash once
+// softTimeout has passed and the transaction has been broadcast. After
+// maxDuration it gives up regardless and reports the relay abandoned along
+// with the latest hash, if any. Zero durations disable either limit.
+func (t *softTracker) wait(outcome <-chan relayOutcome, softTimeout, maxDuration time.Duration) (done relayOutcome, pendingHash string, abandoned bool) {
+	var soft, hard <-chan time.Time
+	if softTimeout > 0 {
+		timer := time.NewTimer(softTimeout)
+		defer timer.Stop()
+		soft = timer.C
+	}
+	if maxDuration > 0 {
+		timer := time.NewTimer(maxDuration)
+		defer timer.Stop()
+		hard = timer.C
+	}
+
+	// Only answer pending once there is a hash to look up
+	var submitted <-chan struct{}
+	for {
+		select {
+		case done := <-outcome:
+			return done, "", false
+		case <-soft:
+			soft = nil
+			submitted = t.submitted
+		case <-submitted:
+			return relayOutcome{}, t.detach(), false
+		case <-hard:
+			return relayOutcome{}, t.detach(), true
+		}
+	}
+}
+
+// detach stops forwarding progress and returns the latest broadcast hash
+func (t *softTracker) detach() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.detached = true
+	return t.txHash
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// Relay progress stages streamed to SSE clients
+const (
+	stageValidated  = "validated"
+	stageSubmitted  = "submitted"
+	stageConfirming = "confirming"
+)
+
+// ProgressEvent is the payload of an SSE progress event
+type ProgressEvent struct {
+	Stage  string `json:"stage"`
+	TxHash string `json:"txHash,omitempty"`
+}
+
+// sseWriter streams relay progress as Server-Sent Events. Whatever the
+// handler writes as its normal JSON response is held back and sent as the
+// final "result" event.
+type sseWriter struct {
+	http.ResponseWriter
+	started bool
+	buf     bytes.Buffer
+}
+
+// wantsEventStream reports whether the client asked for an SSE response
+func wantsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// WriteHeader is ignored: an SSE response is always 200 and the outcome is
+// carried in the result event
+func (sw *sseWriter) WriteHeader(status int) {}
+
+// Write buffers the handler's response body for the result event
+func (sw *sseWriter) Write(p []byte) (int, error) {
+	return sw.buf.Write(p)
+}
+
+// start sends the stream headers on first use
+func (sw *sseWriter) start() {
+	if sw.started {
+		return
+	}
+	sw.started = true
+
+	h := sw.ResponseWriter.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("X-Accel-Buffering", "no")
+	sw.ResponseWriter.WriteHeader(http.StatusOK)
+}
+
+// Unwrap exposes the underlying writer
+func (sw *sseWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
+
+// event writes one SSE event and flushes it to the client. Multi-line data,
+// such as pretty-printed JSON, is sent as one data field per line.
+func (sw *sseWriter) event(name string, data []byte) {
+	sw.start()
+	fmt.Fprintf(sw.ResponseWriter, "event: %s\n", name)
+	for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+		fmt.Fprintf(sw.ResponseWriter, "data: %s\n", line)
+	}
+	fmt.Fprint(sw.ResponseWriter, "\n")
+	if err := http.NewResponseController(sw.ResponseWriter).Flush(); err != nil {
+		log.Printf("⚠️  Failed to flush SSE event: %v\n", err)
+	}
+}
+
+// progress streams a pipeline stage
+func (sw *sseWriter) progress(stage, txHash string) {
+	data, _ := json.Marshal(ProgressEvent{Stage: stage, TxHash: txHash})
+	sw.event("progress", data)
+}
+
+// finish sends the buffered response as the final result event
+func (sw *sseWriter) finish() {
+	sw.event("result", sw.buf.Bytes())
+}
+
+// relayProgress returns the progress reporter for w, or nil when the client
+// didn't ask for a stream
+func relayProgress(w http.ResponseWriter) func(stage, txHash string) {
+	if sw, ok := w.(*sseWriter); ok {
+		return sw.progress
+	}
+	return nil
+}
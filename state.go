@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// writeFileAtomic replaces path with raw via a temp file and rename so a
+// crash mid-write never leaves a truncated file
+func writeFileAtomic(path string, raw []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	// Make sure the contents are on disk before the rename makes them live
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	tmp.Close()
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// loadProcessed reads the processed request cache saved by the previous
+// run, dropping entries that have already expired
+func loadProcessed(path string) (map[string]time.Time, error) {
+	processed := make(map[string]time.Time)
+	if path == "" {
+		return processed, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return processed, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read processed cache: %v", err)
+	}
+	var saved map[string]time.Time
+	if err := json.Unmarshal(raw, &saved); err != nil {
+		return nil, fmt.Errorf("failed to parse processed cache: %v", err)
+	}
+
+	now := time.Now()
+	for id, at := range saved {
+		if now.Sub(at) <= cacheDuration {
+			processed[id] = at
+		}
+	}
+	return processed, nil
+}
+
+// saveProcessed writes the processed request cache to PROCESSED_FILE
+func (s *Server) saveProcessed() error {
+	if s.config.ProcessedFile == "" {
+		return nil
+	}
+
+	s.reqMutex.RLock()
+	raw, err := json.Marshal(s.processedRequests)
+	s.reqMutex.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode processed cache: %v", err)
+	}
+	if err := writeFileAtomic(s.config.ProcessedFile, raw); err != nil {
+		return fmt.Errorf("failed to write processed cache: %v", err)
+	}
+	return nil
+}
+
+// drainRelays waits for relays, resumed transactions and webhooks running in
+// the background, on s and every network, to finish or for ctx to expire
+func (s *Server) drainRelays(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.relays.Wait()
+		for _, network := range s.networks {
+			network.relays.Wait()
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("in-flight relays did not finish: %v", ctx.Err())
+	}
+}
+
+// closeState closes the per-network subsystems. The nonce manager goes
+// first so nothing new can be broadcast while the stores are flushed.
+func (s *Server) closeState() error {
+	s.nonces.Close()
+	return errors.Join(s.txStore.Close(), s.mintHistory.Close(), s.saveProcessed())
+}
+
+// Close runs the shutdown sequence once the HTTP server has stopped: wait
+// for in-flight relays, then flush the nonce manager, tx store, mint history
+// and processed cache of every network, and finally the shared metrics
+func (s *Server) Close(ctx context.Context) error {
+	log.Println("⏳ Waiting for in-flight relays...")
+	var errs []error
+	if err := s.drainRelays(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	for name, network := range s.networks {
+		if err := network.closeState(); err != nil {
+			errs = append(errs, fmt.Errorf("network %q: %v", name, err))
+		}
+	}
+	if err := s.closeState(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := s.metrics.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestClosePersistsStateAfterBackgroundWork(t *testing.T) {
+	dir := t.TempDir()
+	txPath := filepath.Join(dir, "txs.json")
+	historyPath := filepath.Join(dir, "history.json")
+	processedPath := filepath.Join(dir, "processed.json")
+
+	var delivered atomic.Int32
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond)
+		delivered.Add(1)
+	}))
+	defer webhook.Close()
+
+	backend := newFakeBackend()
+	s := newTestServer(t, backend)
+	var err error
+	if s.txStore, err = NewTxStore(txPath); err != nil {
+		t.Fatalf("NewTxStore: %v", err)
+	}
+	if s.mintHistory, err = NewMintHistory(historyPath); err != nil {
+		t.Fatalf("NewMintHistory: %v", err)
+	}
+	s.config.ProcessedFile = processedPath
+	s.config.WebhookURL = webhook.URL
+	s.config.WebhookTimeout = time.Second
+
+	// A transaction left pending by the previous run has since been mined
+	from := common.HexToAddress("0x00000000000000000000000000000000000000f1")
+	tx := types.NewTransaction(0, common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil)
+	backend.mine(tx)
+	s.txStore.Put(TxRecord{TxHash: tx.Hash().Hex(), RequestID: "resumed-request", From: from.Hex(), Status: txPending, SubmittedAt: time.Now().Unix()})
+
+	s.resumePendingTxs()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Close(ctx); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if delivered.Load() != 1 {
+		t.Fatalf("%d webhook(s) delivered before Close returned, want 1", delivered.Load())
+	}
+
+	txStore, err := NewTxStore(txPath)
+	if err != nil {
+		t.Fatalf("reload tx store: %v", err)
+	}
+	if rec, _ := txStore.Get(tx.Hash().Hex()); rec.Status != txConfirmed {
+		t.Fatalf("reloaded status = %q, want %q", rec.Status, txConfirmed)
+	}
+	processed, err := loadProcessed(processedPath)
+	if err != nil {
+		t.Fatalf("reload processed cache: %v", err)
+	}
+	if _, ok := processed["resumed-request"]; !ok {
+		t.Fatal("resumed request missing from the reloaded processed cache")
+	}
+	history, err := NewMintHistory(historyPath)
+	if err != nil {
+		t.Fatalf("reload mint history: %v", err)
+	}
+	if count := history.Get(from.Hex()); count.Successes != 1 {
+		t.Fatalf("reloaded successes = %d, want 1", count.Successes)
+	}
+}
+
+func TestDrainRelaysTimesOut(t *testing.T) {
+	s := newTestServer(t, newFakeBackend())
+	release := make(chan struct{})
+	s.relays.Add(1)
+	go func() {
+		defer s.relays.Done()
+		<-release
+	}()
+	defer close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := s.drainRelays(ctx); err == nil {
+		t.Fatal("drainRelays returned before the relay finished")
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+)
+
+// StatsResponse is a JSON summary of relayer activity for dashboards
+type StatsResponse struct {
+	Relayer        string            `json:"relayer"`
+	Breaker        string            `json:"breaker"`
+	RelayedTxs     uint64            `json:"relayedTxs"`
+	GasSpentWei    string            `json:"gasSpentWei"`
+	GasSpentEther  float64           `json:"gasSpentEther"`
+	GasUsedAverage uint64            `json:"gasUsedAverage"`
+	GasUsedSamples int               `json:"gasUsedSamples"`
+	Blocklisted    int               `json:"blocklisted"`
+	AccruedFees    map[string]string `json:"accruedFees,omitempty"`
+	InFlight       int64             `json:"inFlight"`
+	Watchers       WatcherStats      `json:"receiptWatchers"`
+	GasBudget      *GasBudgetState   `json:"gasBudget,omitempty"`
+	Nonce          *NonceLag         `json:"nonce,omitempty"`
+	Maintenance    MaintenanceState  `json:"maintenance"`
+	Timestamp      int64             `json:"timestamp"`
+}
+
+// statsHandler reports relayer statistics
+func (s *Server) statsHandler(w http.ResponseWriter, r *http.Request) {
+	spent, count := s.economics.Total()
+	gasAverage, gasSamples := s.gasAverage.Value()
+
+	response := StatsResponse{
+		Relayer:        s.relayer().Hex(),
+		Breaker:        s.breaker.State(),
+		RelayedTxs:     count,
+		GasSpentWei:    spent.String(),
+		GasSpentEther:  weiToEther(spent),
+		GasUsedAverage: gasAverage,
+		GasUsedSamples: gasSamples,
+		Blocklisted:    len(s.reverts.List()),
+		AccruedFees:    s.fees.Totals(),
+		InFlight:       s.globalInflight.Load(),
+		Watchers:       s.watchers.Stats(),
+		GasBudget:      s.budget.State(s.relayer()),
+		Maintenance:    s.maintenance.State(),
+		Timestamp:      time.Now().Unix(),
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+	if lag, err := s.nonceLag(ctx); err != nil {
+		log.Printf("⚠️  Failed to measure nonce lag: %v\n", err)
+	} else {
+		response.Nonce = &lag
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"golang.org/x/sync/singleflight"
+)
+
+// SupplyCache holds the last totalSupply()/maxSupply() read from the NFT
+// contract. Both are re-read once the TTL passes, since the owner may raise
+// the cap after a sell-out.
+type SupplyCache struct {
+	mu        sync.Mutex
+	abi       abi.ABI
+	total     *big.Int
+	max       *big.Int
+	fetchedAt time.Time
+	ttl       time.Duration
+	// refresh lets concurrent checks share one pair of contract reads
+	refresh singleflight.Group
+}
+
+// NewSupplyCache builds the ABI for the configured view methods, each taking
+// no arguments and returning a uint256
+func NewSupplyCache(totalMethod, maxMethod string, ttl time.Duration) (*SupplyCache, error) {
+	def := `[
+		{"inputs": [], "name": "%s", "outputs": [{"name": "", "type": "uint256"}], "stateMutability": "view", "type": "function"},
+		{"inputs": [], "name": "%s", "outputs": [{"name": "", "type": "uint256"}], "stateMutability": "view", "type": "function"}
+	]`
+	parsed, err := abi.JSON(strings.NewReader(fmt.Sprintf(def, totalMethod, maxMethod)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid supply method names: %v", err)
+	}
+	return &SupplyCache{abi: parsed, ttl: ttl}, nil
+}
+
+// soldOut reports whether the cached supply has reached the cap
+func (c *SupplyCache) soldOut() bool {
+	return c.total != nil && c.max != nil && c.max.Sign() > 0 && c.total.Cmp(c.max) >= 0
+}
+
+// callUint reads a no-argument uint256 view method from the NFT contract
+func (s *Server) callUint(ctx context.Context, method string) (*big.Int, error) {
+	c := s.supply
+	data, err := c.abi.Pack(method)
+	if err != nil {
+		return nil, err
+	}
+	result, err := s.client.CallContract(ctx, ethereum.CallMsg{To: &s.config.NFTContract, Data: data}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s: %v", method, err)
+	}
+
+	var value *big.Int
+	if err := c.abi.UnpackIntoInterface(&value, method, result); err != nil {
+		return nil, fmt.Errorf("failed to unpack %s: %v", method, err)
+	}
+	return value, nil
+}
+
+// checkSupplyCap reports whether the NFT collection has sold out, reading
+// the supply from the contract at most once per cache TTL
+func (s *Server) checkSupplyCap() (bool, error) {
+	c := s.supply
+	c.mu.Lock()
+	fresh := c.total != nil && time.Since(c.fetchedAt) < c.ttl
+	soldOut := c.soldOut()
+	c.mu.Unlock()
+	if fresh {
+		return soldOut, nil
+	}
+
+	// The reads happen without c.mu so a slow node doesn't stall checks
+	// that could be answered from the cache
+	if _, err, _ := c.refresh.Do("supply", func() (interface{}, error) {
+		return nil, s.refreshSupply()
+	}); err != nil {
+		return false, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.soldOut(), nil
+}
+
+// refreshSupply reads the current supply and cap from the contract into
+// the cache
+func (s *Server) refreshSupply() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	total, err := s.callUint(ctx, s.config.SupplyTotalMethod)
+	if err != nil {
+		return err
+	}
+	capacity, err := s.callUint(ctx, s.config.SupplyMaxMethod)
+	if err != nil {
+		return err
+	}
+
+	c := s.supply
+	c.mu.Lock()
+	c.total = total
+	c.max = capacity
+	c.fetchedAt = time.Now()
+	c.mu.Unlock()
+	s.metrics.Set("nft_total_supply", float64(total.Uint64()))
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// supplyServer answers totalSupply() and maxSupply() from total and capacity,
+// counting the reads
+func supplyServer(t *testing.T, ttl time.Duration, total, capacity *atomic.Int64, reads *atomic.Int32, delay time.Duration) *Server {
+	t.Helper()
+	backend := newFakeBackend()
+	totalSelector := string(crypto.Keccak256([]byte("totalSupply()"))[:4])
+	backend.callContract = func(msg ethereum.CallMsg) ([]byte, error) {
+		reads.Add(1)
+		time.Sleep(delay)
+		if string(msg.Data[:4]) == totalSelector {
+			return common.BigToHash(big.NewInt(total.Load())).Bytes(), nil
+		}
+		return common.BigToHash(big.NewInt(capacity.Load())).Bytes(), nil
+	}
+	s := newTestServer(t, backend)
+	s.config.SupplyTotalMethod = "totalSupply"
+	s.config.SupplyMaxMethod = "maxSupply"
+	supply, err := NewSupplyCache("totalSupply", "maxSupply", ttl)
+	if err != nil {
+		t.Fatalf("NewSupplyCache: %v", err)
+	}
+	s.supply = supply
+	return s
+}
+
+func TestSupplyCapRereadAfterSellOut(t *testing.T) {
+	var total, capacity atomic.Int64
+	var reads atomic.Int32
+	total.Store(10)
+	capacity.Store(10)
+	ttl := 20 * time.Millisecond
+	s := supplyServer(t, ttl, &total, &capacity, &reads, 0)
+
+	if soldOut, err := s.checkSupplyCap(); err != nil || !soldOut {
+		t.Fatalf("checkSupplyCap = %v, %v, want sold out", soldOut, err)
+	}
+
+	// The owner raises the cap; the sell-out holds only until the TTL passes
+	capacity.Store(20)
+	if soldOut, _ := s.checkSupplyCap(); !soldOut {
+		t.Fatal("cached sell-out re-read before the TTL")
+	}
+	time.Sleep(2 * ttl)
+	if soldOut, err := s.checkSupplyCap(); err != nil || soldOut {
+		t.Fatalf("checkSupplyCap = %v, %v after the cap was raised", soldOut, err)
+	}
+}
+
+func TestSupplyCapSharesConcurrentReads(t *testing.T) {
+	var total, capacity atomic.Int64
+	var reads atomic.Int32
+	total.Store(1)
+	capacity.Store(10)
+	s := supplyServer(t, time.Minute, &total, &capacity, &reads, 50*time.Millisecond)
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.checkSupplyCap(); err != nil {
+				t.Errorf("checkSupplyCap: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := reads.Load(); got != 2 {
+		t.Fatalf("%d contract reads, want one totalSupply and one maxSupply", got)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+// decodeTokenURI extracts the tokenUri argument from mint calldata. ok is
+// false when callData isn't a mint call.
+func decodeTokenURI(callData []byte) (uri string, ok bool, err error) {
+	parsedABI, err := abi.JSON(strings.NewReader(nftABI))
+	if err != nil {
+		return "", false, err
+	}
+	method := parsedABI.Methods["mint"]
+	if len(callData) < 4 || !bytes.Equal(callData[:4], method.ID) {
+		return "", false, nil
+	}
+
+	args, err := method.Inputs.Unpack(callData[4:])
+	if err != nil {
+		return "", false, fmt.Errorf("failed to decode mint arguments: %v", err)
+	}
+	return args[0].(string), true, nil
+}
+
+// uriHostAllowed reports whether uri's host is one of hosts or a subdomain
+// of one
+func uriHostAllowed(uri string, hosts []string) bool {
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(parsed.Hostname())
+	if host == "" {
+		return false
+	}
+
+	for _, allowed := range hosts {
+		allowed = strings.ToLower(allowed)
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultMintCallData builds mint(DEFAULT_TOKEN_URI) calldata for requests
+// that omit callData under SERVER_CONSTRUCTS_CALLDATA.
+//
+// The signing contract is unchanged: the client must still sign a forward
+// whose dataHash is keccak256 of this exact calldata, i.e.
+// keccak256(abi.encodeWithSignature("mint(string)", DEFAULT_TOKEN_URI)), so
+// the relayer can't substitute a different call.
+func defaultMintCallData(uri string) ([]byte, error) {
+	parsedABI, err := abi.JSON(strings.NewReader(nftABI))
+	if err != nil {
+		return nil, err
+	}
+	data, err := parsedABI.Pack("mint", uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack mint: %v", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,436 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/gorilla/mux"
+)
+
+// Transaction record states
+const (
+	txPending   = "pending"
+	txConfirmed = "confirmed"
+	txFailed    = "failed"
+	txDropped   = "dropped"
+	txAbandoned = "abandoned"
+	txReplaced  = "replaced"
+)
+
+// TxRecord is a broadcast relay transaction and its outcome. It carries
+// enough of the request to finish its bookkeeping if the request stops
+// waiting on it.
+type TxRecord struct {
+	TxHash      string            `json:"txHash"`
+	RequestID   string            `json:"requestId"`
+	DataHashID  string            `json:"dataHashId,omitempty"`
+	From        string            `json:"from"`
+	Sender      string            `json:"sender,omitempty"`
+	Hub         string            `json:"hub"`
+	Fee         *FeeAuthorization `json:"fee,omitempty"`
+	Status      string            `json:"status"`
+	BlockNumber uint64            `json:"blockNumber,omitempty"`
+	GasUsed     string            `json:"gasUsed,omitempty"`
+	Error       string            `json:"error,omitempty"`
+	SubmittedAt int64             `json:"submittedAt"`
+	UpdatedAt   int64             `json:"updatedAt"`
+}
+
+// TxStore tracks broadcast transactions so their outcome can be looked up
+// and, when backed by a file, so pending ones survive a restart
+type TxStore struct {
+	mu   sync.Mutex
+	path string
+	txs  map[string]*TxRecord
+	// held counts the running relays still waiting on each transaction
+	held map[string]int
+	// dirty is set when records changed since the last write
+	dirty bool
+}
+
+// NewTxStore opens a store persisted at path. An empty path keeps records
+// in memory only.
+func NewTxStore(path string) (*TxStore, error) {
+	store := &TxStore{path: path, txs: make(map[string]*TxRecord), held: make(map[string]int)}
+	if path == "" {
+		return store, nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tx store: %v", err)
+	}
+	if err := json.Unmarshal(raw, &store.txs); err != nil {
+		return nil, fmt.Errorf("failed to parse tx store: %v", err)
+	}
+	return store, nil
+}
+
+// Put adds or replaces a record. A new broadcast is written out right away
+// so it can be resumed after a crash; other changes wait for Flush.
+func (t *TxStore) Put(rec TxRecord) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	_, known := t.txs[rec.TxHash]
+	rec.UpdatedAt = time.Now().Unix()
+	t.txs[rec.TxHash] = &rec
+	t.dirty = true
+	if !known {
+		t.save()
+	}
+}
+
+// Update applies fn to the record for txHash, if present
+func (t *TxStore) Update(txHash string, fn func(rec *TxRecord)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rec, ok := t.txs[txHash]
+	if !ok {
+		return
+	}
+	fn(rec)
+	rec.UpdatedAt = time.Now().Unix()
+	t.dirty = true
+}
+
+// Settle applies fn to the record for txHash if it is still unresolved:
+// pending, dropped or abandoned. It reports whether it did, so that when a
+// relay and a reconcile pass see the same receipt only one records it.
+func (t *TxStore) Settle(txHash string, fn func(rec *TxRecord)) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rec, ok := t.txs[txHash]
+	if !ok || (rec.Status != txPending && rec.Status != txDropped && rec.Status != txAbandoned) {
+		return false
+	}
+	fn(rec)
+	rec.UpdatedAt = time.Now().Unix()
+	t.dirty = true
+	return true
+}
+
+// Hold marks txHash as waited on by a running relay until Release
+func (t *TxStore) Hold(txHash string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.held[txHash]++
+}
+
+// Release ends a Hold on txHash
+func (t *TxStore) Release(txHash string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.held[txHash]--; t.held[txHash] <= 0 {
+		delete(t.held, txHash)
+	}
+}
+
+// Get returns the record for txHash
+func (t *TxStore) Get(txHash string) (TxRecord, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rec, ok := t.txs[txHash]
+	if !ok {
+		return TxRecord{}, false
+	}
+	return *rec, true
+}
+
+// Pending returns all records still awaiting a receipt
+func (t *TxStore) Pending() []TxRecord {
+	return t.withStatus(txPending)
+}
+
+// Unresolved returns records whose outcome is unknown because the relay
+// was abandoned, no receipt arrived, or the record is still pending longer
+// than any receipt wait as of now with no running relay waiting on it,
+// e.g. after its waiter was lost
+func (t *TxStore) Unresolved(now time.Time) []TxRecord {
+	out := t.withStatus(txAbandoned, txDropped)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	cutoff := now.Add(-receiptTimeout).Unix()
+	for hash, rec := range t.txs {
+		if rec.Status == txPending && rec.SubmittedAt < cutoff && t.held[hash] == 0 {
+			out = append(out, *rec)
+		}
+	}
+	return out
+}
+
+// withStatus returns all records in any of the given states
+func (t *TxStore) withStatus(statuses ...string) []TxRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var out []TxRecord
+	for _, rec := range t.txs {
+		for _, status := range statuses {
+			if rec.Status == status {
+				out = append(out, *rec)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// Prune drops settled records last updated before now - retention.
+// Abandoned records are kept until reconciled.
+func (t *TxStore) Prune(now time.Time, retention time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	cutoff := now.Add(-retention).Unix()
+	for hash, rec := range t.txs {
+		if rec.Status != txPending && rec.Status != txAbandoned && rec.UpdatedAt < cutoff {
+			delete(t.txs, hash)
+			t.dirty = true
+		}
+	}
+}
+
+// Flush writes the store if it changed since the last write
+func (t *TxStore) Flush() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.write()
+}
+
+// save writes the store atomically, logging failures. Callers hold t.mu.
+func (t *TxStore) save() {
+	if err := t.write(); err != nil {
+		log.Printf("⚠️  %v\n", err)
+	}
+}
+
+// write writes the store atomically if it changed. Callers hold t.mu.
+func (t *TxStore) write() error {
+	if t.path == "" || !t.dirty {
+		return nil
+	}
+
+	raw, err := json.Marshal(t.txs)
+	if err != nil {
+		return fmt.Errorf("failed to encode tx store: %v", err)
+	}
+	if err := writeFileAtomic(t.path, raw); err != nil {
+		return fmt.Errorf("failed to write tx store: %v", err)
+	}
+	t.dirty = false
+	return nil
+}
+
+// Close flushes the store one last time on shutdown
+func (t *TxStore) Close() error {
+	return t.Flush()
+}
+
+// settleTx records a receipt outcome for a tracked transaction and charges
+// its gas to the key that sent it, pricing it at fallbackPrice when the
+// receipt has no effective price. It reports whether this call settled the
+// transaction; false means its outcome was already recorded and nothing
+// more should be done with it.
+func (s *Server) settleTx(txHash common.Hash, receipt *types.Receipt, fallbackPrice *big.Int, failure error) bool {
+	sender := s.relayer()
+	settled := s.txStore.Settle(txHash.Hex(), func(rec *TxRecord) {
+		rec.Status = txConfirmed
+		if failure != nil {
+			rec.Status = txFailed
+			rec.Error = s.parseError(failure)
+		}
+		rec.BlockNumber = receipt.BlockNumber.Uint64()
+		rec.GasUsed = fmt.Sprintf("%d", receipt.GasUsed)
+		if rec.Sender != "" {
+			sender = common.HexToAddress(rec.Sender)
+		}
+	})
+	if !settled {
+		return false
+	}
+
+	// Reverted transactions cost gas too
+	s.budget.Record(sender, receiptCost(receipt, fallbackPrice))
+	return true
+}
+
+// supersedeTxs marks every other broadcast of a relay as replaced once mined
+// has been mined: only one transaction per nonce can ever confirm
+func (s *Server) supersedeTxs(sub *submission, mined common.Hash) {
+	for _, tx := range sub.sent {
+		if tx.Hash() == mined {
+			continue
+		}
+		s.txStore.Update(tx.Hash().Hex(), func(rec *TxRecord) {
+			if rec.Status == txPending || rec.Status == txDropped || rec.Status == txAbandoned {
+				rec.Status = txReplaced
+				rec.Error = "replaced by " + mined.Hex()
+			}
+		})
+	}
+}
+
+// dropTx records that no receipt arrived for a broadcast in time. The
+// outcome is unknown until reconciliation, or until a replacement at the
+// same nonce is mined.
+func (s *Server) dropTx(txHash common.Hash, reason string) {
+	s.txStore.Update(txHash.Hex(), func(rec *TxRecord) {
+		if rec.Status == txPending {
+			rec.Status = txDropped
+			rec.Error = reason
+		}
+	})
+}
+
+// resumePendingTxs re-attaches receipt watchers to transactions that were
+// still pending when the relayer last stopped
+func (s *Server) resumePendingTxs() {
+	pending := s.txStore.Pending()
+	if len(pending) == 0 {
+		return
+	}
+
+	log.Printf("♻️  Resuming %d pending transaction(s) from the previous run\n", len(pending))
+	for _, rec := range pending {
+		s.txStore.Hold(rec.TxHash)
+		s.relays.Add(1)
+		go func(rec TxRecord) {
+			defer s.relays.Done()
+			defer s.txStore.Release(rec.TxHash)
+			s.resumeTx(rec)
+		}(rec)
+	}
+}
+
+// resumeTx waits for a pending transaction from a previous run and records
+// its outcome as if the original request were still waiting on it
+func (s *Server) resumeTx(rec TxRecord) {
+	txHash := common.HexToHash(rec.TxHash)
+	receipt, err := s.waitForReceipt(txHash)
+	if err != nil {
+		log.Printf("⚠️  No receipt for resumed tx %s: %v\n", rec.TxHash, err)
+		s.dropTx(txHash, "no receipt after restart")
+		return
+	}
+
+	s.applyReceipt(rec, receipt)
+}
+
+// applyReceipt records the outcome of a transaction whose original request
+// is no longer waiting on it, doing the bookkeeping and firing the webhook
+// the request would have. It reports false when the outcome had already
+// been recorded, e.g. by an abandoned relay that saw the receipt first.
+func (s *Server) applyReceipt(rec TxRecord, receipt *types.Receipt) bool {
+	txHash := common.HexToHash(rec.TxHash)
+	if receipt.Status == 0 {
+		if !s.settleTx(txHash, receipt, nil, errTxReverted) {
+			return false
+		}
+		log.Printf("❌ Tx %s reverted\n", rec.TxHash)
+		s.sendWebhook(WebhookEvent{
+			Event:  webhookRelayFailed,
+			From:   rec.From,
+			TxHash: rec.TxHash,
+			Error:  s.parseError(errTxReverted),
+		})
+		return true
+	}
+
+	if !s.settleTx(txHash, receipt, nil, nil) {
+		return false
+	}
+	log.Printf("✅ Tx %s confirmed in block %d\n", rec.TxHash, receipt.BlockNumber.Uint64())
+	gasUsed := new(big.Int).SetUint64(receipt.GasUsed)
+	result := &TxResult{
+		TxHash:      rec.TxHash,
+		BlockNumber: receipt.BlockNumber.Uint64(),
+		BlockHash:   receipt.BlockHash,
+		GasUsed:     gasUsed,
+	}
+	if receipt.EffectiveGasPrice != nil {
+		result.EffectiveGasPrice = receipt.EffectiveGasPrice
+		result.CostWei = new(big.Int).Mul(gasUsed, receipt.EffectiveGasPrice)
+	}
+	s.recordSuccess(common.HexToAddress(rec.From), rec.RequestID, rec.DataHashID, rec.Fee, result)
+	return true
+}
+
+// ReconcileResponse summarizes a reconciliation pass
+type ReconcileResponse struct {
+	Checked    int `json:"checked"`
+	Resolved   int `json:"resolved"`
+	Confirmed  int `json:"confirmed"`
+	Failed     int `json:"failed"`
+	Unresolved int `json:"unresolved"`
+}
+
+// reconcileHandler re-checks abandoned, dropped and stale pending
+// transactions once, recording any receipt that has since appeared
+func (s *Server) reconcileHandler(w http.ResponseWriter, r *http.Request) {
+	var summary ReconcileResponse
+	for _, rec := range s.txStore.Unresolved(time.Now()) {
+		summary.Checked++
+
+		ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+		receipt, err := s.client.TransactionReceipt(ctx, common.HexToHash(rec.TxHash))
+		cancel()
+		if err != nil {
+			if !errors.Is(err, ethereum.NotFound) {
+				log.Printf("⚠️  Reconcile: failed to fetch receipt for %s: %v\n", rec.TxHash, err)
+			}
+			summary.Unresolved++
+			continue
+		}
+
+		// The relay that sent it may have recorded it in the meantime
+		summary.Resolved++
+		if !s.applyReceipt(rec, receipt) {
+			continue
+		}
+		if receipt.Status == 0 {
+			summary.Failed++
+		} else {
+			summary.Confirmed++
+		}
+	}
+
+	log.Printf("🧮 Reconciled %d of %d unresolved transaction(s)\n", summary.Resolved, summary.Checked)
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// txHandler looks up a relayed transaction by hash
+func (s *Server) txHandler(w http.ResponseWriter, r *http.Request) {
+	hash := mux.Vars(r)["hash"]
+	if len(strings.TrimPrefix(hash, "0x")) != 64 {
+		s.sendError(w, http.StatusBadRequest, "Invalid transaction hash", "")
+		return
+	}
+
+	rec, ok := s.txStore.Get(common.HexToHash(hash).Hex())
+	if !ok {
+		s.sendError(w, http.StatusNotFound, "Unknown transaction", "")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rec)
+}
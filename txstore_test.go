@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// testBroadcasts records n broadcasts of the same nonce as pending
+func testBroadcasts(s *Server, n int) *submission {
+	sub := &submission{}
+	for i := 0; i < n; i++ {
+		tx := types.NewTransaction(0, common.Address{}, big.NewInt(0), 21000, big.NewInt(int64(i+1)), nil)
+		sub.sent = append(sub.sent, tx)
+		s.txStore.Put(TxRecord{TxHash: tx.Hash().Hex(), Status: txPending, SubmittedAt: time.Now().Unix()})
+	}
+	return sub
+}
+
+func TestSupersedeMarksSiblingsReplaced(t *testing.T) {
+	s := newTestServer(t, newFakeBackend())
+	sub := testBroadcasts(s, 3)
+	first, second, mined := sub.sent[0].Hash(), sub.sent[1].Hash(), sub.sent[2].Hash()
+
+	// The first broadcast timed out before its replacements went out
+	s.dropTx(first, "no receipt before timeout")
+	s.settleTx(mined, &types.Receipt{Status: types.ReceiptStatusSuccessful, BlockNumber: big.NewInt(1)}, nil, nil)
+	s.supersedeTxs(sub, mined)
+
+	for _, hash := range []common.Hash{first, second} {
+		rec, _ := s.txStore.Get(hash.Hex())
+		if rec.Status != txReplaced {
+			t.Fatalf("%s status = %q, want %q", hash.Hex(), rec.Status, txReplaced)
+		}
+	}
+	if rec, _ := s.txStore.Get(mined.Hex()); rec.Status != txConfirmed {
+		t.Fatalf("mined status = %q, want %q", rec.Status, txConfirmed)
+	}
+	if unresolved := s.txStore.Unresolved(time.Now()); len(unresolved) != 0 {
+		t.Fatalf("%d replaced record(s) left to reconcile", len(unresolved))
+	}
+
+	s.txStore.Prune(time.Now().Add(time.Hour), time.Minute)
+	for _, hash := range []common.Hash{first, second, mined} {
+		if _, ok := s.txStore.Get(hash.Hex()); ok {
+			t.Fatalf("%s survived pruning", hash.Hex())
+		}
+	}
+}
+
+func TestDropTxOnlyTouchesPendingRecords(t *testing.T) {
+	s := newTestServer(t, newFakeBackend())
+	sub := testBroadcasts(s, 2)
+	pending, confirmed := sub.sent[0].Hash(), sub.sent[1].Hash()
+	s.settleTx(confirmed, &types.Receipt{Status: types.ReceiptStatusSuccessful, BlockNumber: big.NewInt(1)}, nil, nil)
+
+	s.dropTx(pending, "no receipt before timeout")
+	s.dropTx(confirmed, "no receipt before timeout")
+
+	if rec, _ := s.txStore.Get(pending.Hex()); rec.Status != txDropped {
+		t.Fatalf("timed out status = %q, want %q", rec.Status, txDropped)
+	}
+	if rec, _ := s.txStore.Get(confirmed.Hex()); rec.Status != txConfirmed {
+		t.Fatalf("confirmed status = %q, want it unchanged", rec.Status)
+	}
+}
+
+func TestResumedTxDoesRelayBookkeeping(t *testing.T) {
+	backend := newFakeBackend()
+	s := newTestServer(t, backend)
+	s.config.DedupeDataHash = true
+	s.dailyCap = NewDailyCap(1)
+	from := common.HexToAddress("0x00000000000000000000000000000000000000f1")
+
+	tx := types.NewTransaction(0, common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil)
+	backend.mine(tx)
+	rec := TxRecord{TxHash: tx.Hash().Hex(), RequestID: "request", DataHashID: "data", From: from.Hex(), Status: txPending}
+	s.txStore.Put(rec)
+
+	s.resumeTx(rec)
+
+	if !s.isProcessed("request") || !s.isProcessed("data") {
+		t.Fatal("resumed relay not marked processed")
+	}
+	if ok, _ := s.dailyCap.Allow(from.Hex()); ok {
+		t.Fatal("resumed relay not counted against the daily cap")
+	}
+	if rec, _ := s.txStore.Get(tx.Hash().Hex()); rec.Status != txConfirmed {
+		t.Fatalf("status = %q, want %q", rec.Status, txConfirmed)
+	}
+}
+
+func TestUnresolvedIncludesStalePending(t *testing.T) {
+	store, _ := NewTxStore("")
+	now := time.Now()
+	records := map[string]TxRecord{
+		"fresh":     {Status: txPending, SubmittedAt: now.Add(-receiptTimeout / 2).Unix()},
+		"stale":     {Status: txPending, SubmittedAt: now.Add(-2 * receiptTimeout).Unix()},
+		"dropped":   {Status: txDropped, SubmittedAt: now.Unix()},
+		"abandoned": {Status: txAbandoned, SubmittedAt: now.Unix()},
+		"confirmed": {Status: txConfirmed, SubmittedAt: now.Add(-2 * receiptTimeout).Unix()},
+		"replaced":  {Status: txReplaced, SubmittedAt: now.Add(-2 * receiptTimeout).Unix()},
+		"held":      {Status: txPending, SubmittedAt: now.Add(-2 * receiptTimeout).Unix()},
+	}
+	for hash, rec := range records {
+		rec.TxHash = hash
+		store.Put(rec)
+	}
+	// A running relay is still waiting on this one
+	store.Hold("held")
+
+	got := make(map[string]bool)
+	for _, rec := range store.Unresolved(now) {
+		got[rec.TxHash] = true
+	}
+	want := map[string]bool{"stale": true, "dropped": true, "abandoned": true}
+	if len(got) != len(want) {
+		t.Fatalf("unresolved = %v, want %v", got, want)
+	}
+	for hash := range want {
+		if !got[hash] {
+			t.Fatalf("unresolved = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestReconcileResolvesStalePending(t *testing.T) {
+	backend := newFakeBackend()
+	s := newTestServer(t, backend)
+	tx := types.NewTransaction(0, common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil)
+	// The relay crashed after broadcasting, leaving the record pending
+	s.txStore.Put(TxRecord{TxHash: tx.Hash().Hex(), Status: txPending, SubmittedAt: time.Now().Add(-2 * receiptTimeout).Unix()})
+	backend.mine(tx)
+
+	w := httptest.NewRecorder()
+	s.reconcileHandler(w, httptest.NewRequest("POST", "/admin/reconcile", nil))
+
+	var summary ReconcileResponse
+	json.Unmarshal(w.Body.Bytes(), &summary)
+	if summary.Checked != 1 || summary.Confirmed != 1 {
+		t.Fatalf("summary = %+v, want the stale record confirmed", summary)
+	}
+	if rec, _ := s.txStore.Get(tx.Hash().Hex()); rec.Status != txConfirmed {
+		t.Fatalf("status = %q, want %q", rec.Status, txConfirmed)
+	}
+}
+
+func TestSettleTxOnlyOnce(t *testing.T) {
+	s := newTestServer(t, newFakeBackend())
+	s.budget = NewGasBudget(big.NewInt(1e18))
+	sender := common.HexToAddress("0x00000000000000000000000000000000000000e1")
+	tx := types.NewTransaction(0, common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil)
+	s.txStore.Put(TxRecord{TxHash: tx.Hash().Hex(), Sender: sender.Hex(), Status: txAbandoned})
+	receipt := &types.Receipt{Status: types.ReceiptStatusSuccessful, BlockNumber: big.NewInt(1), GasUsed: 21000, EffectiveGasPrice: big.NewInt(1e9)}
+
+	if !s.settleTx(tx.Hash(), receipt, nil, nil) {
+		t.Fatal("abandoned record not settled")
+	}
+	if s.settleTx(tx.Hash(), receipt, nil, nil) {
+		t.Fatal("settled record settled again")
+	}
+	// Charged once, to the key that sent it rather than the current one
+	if state := s.budget.State(sender); state.SpentWei != "21000000000000" {
+		t.Fatalf("sender spent %s, want one charge of 21000000000000", state.SpentWei)
+	}
+	if state := s.budget.State(s.relayer()); state.SpentWei != "0" {
+		t.Fatalf("current key charged %s for another key's transaction", state.SpentWei)
+	}
+}
+
+func TestAbandonedRelayAndReconcileRecordOnce(t *testing.T) {
+	recorder := &webhookRecorder{}
+	webhook := httptest.NewServer(recorder)
+	defer webhook.Close()
+
+	backend := newFakeBackend()
+	backend.noMine = true
+	s := newTestServer(t, backend)
+	s.budget = NewGasBudget(big.NewInt(1e18))
+	s.config.WebhookURL = webhook.URL
+	s.config.WebhookTimeout = time.Second
+	s.config.MaxRelayDuration = 50 * time.Millisecond
+	s.dailyCap = NewDailyCap(10)
+
+	req := testRelayRequest()
+	req.Forward.Caller = s.relayer()
+	if status, response := relayResponse(t, s, req, ""); status != http.StatusGatewayTimeout {
+		t.Fatalf("relay: %d %+v, want it abandoned", status, response)
+	}
+	sent := backend.sentTxs()
+	if len(sent) != 1 {
+		t.Fatalf("sent %d transactions, want 1", len(sent))
+	}
+
+	// The abandoned relay's watcher and a reconcile pass both see it mined
+	backend.mine(sent[0])
+	w := httptest.NewRecorder()
+	s.reconcileHandler(w, httptest.NewRequest("POST", "/admin/reconcile", nil))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.drainRelays(ctx); err != nil {
+		t.Fatalf("drainRelays: %v", err)
+	}
+
+	if events := recorder.waitFor(2, 10*s.config.ReceiptPoll); len(events) != 1 || events[0].Event != webhookRelaySucceeded {
+		t.Fatalf("webhooks = %+v, want one success", events)
+	}
+	cost := new(big.Int).Mul(big.NewInt(21000), sent[0].GasPrice())
+	if state := s.budget.State(s.relayer()); state.SpentWei != cost.String() {
+		t.Fatalf("spent %s, want one charge of %s", state.SpentWei, cost)
+	}
+	if count := s.mintHistory.Get(req.Forward.From.Hex()); count.Successes != 1 {
+		t.Fatalf("mint history = %d, want 1", count.Successes)
+	}
+	if used := s.dailyCap.Used(req.Forward.From.Hex()); used != 1 {
+		t.Fatalf("daily cap used = %d, want 1", used)
+	}
+}
+
+func TestTxStoreWritesNewBroadcastsAndBatchesUpdates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "txs.json")
+	store, err := NewTxStore(path)
+	if err != nil {
+		t.Fatalf("NewTxStore: %v", err)
+	}
+	reload := func() *TxStore {
+		t.Helper()
+		reloaded, err := NewTxStore(path)
+		if err != nil {
+			t.Fatalf("reload: %v", err)
+		}
+		return reloaded
+	}
+
+	// A new broadcast must survive a crash straight away
+	store.Put(TxRecord{TxHash: "0x01", Status: txPending})
+	if _, ok := reload().Get("0x01"); !ok {
+		t.Fatal("new broadcast not written")
+	}
+
+	store.Update("0x01", func(rec *TxRecord) { rec.Status = txConfirmed })
+	if rec, _ := reload().Get("0x01"); rec.Status != txPending {
+		t.Fatalf("Update wrote the file before a flush: status %q", rec.Status)
+	}
+	if err := store.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if rec, _ := reload().Get("0x01"); rec.Status != txConfirmed {
+		t.Fatalf("flushed status = %q, want %q", rec.Status, txConfirmed)
+	}
+
+	store.Update("0x01", func(rec *TxRecord) { rec.GasUsed = "21000" })
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if rec, _ := reload().Get("0x01"); rec.GasUsed != "21000" {
+		t.Fatalf("gas used after Close = %q, want 21000", rec.GasUsed)
+	}
+}
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ValidationError is one failed relay check, as listed with ?all_errors=true
+type ValidationError struct {
+	Code    string `json:"code,omitempty"`
+	Error   string `json:"error"`
+	Details string `json:"details,omitempty"`
+	status  int
+}
+
+// validation tracks failed checks for one relay request. By default the
+// first failure is sent straight away. With ?all_errors=true failures are
+// collected and sent together once the pipeline reaches a point it can't
+// continue past, so clients can fix every problem at once.
+type validation struct {
+	s    *Server
+	w    http.ResponseWriter
+	all  bool
+	errs []ValidationError
+}
+
+// newValidation starts tracking checks for r
+func (s *Server) newValidation(w http.ResponseWriter, r *http.Request) *validation {
+	return &validation{s: s, w: w, all: r.URL.Query().Get("all_errors") == "true"}
+}
+
+// newItemValidation tracks checks for one item of a batch. Nothing is
+// written to the response: the first failure stops the checks and is
+// returned by first.
+func (s *Server) newItemValidation() *validation {
+	return &validation{s: s}
+}
+
+// reject records a failed check and reports whether the caller must stop:
+// always when failing fast, never when collecting
+func (v *validation) reject(status int, code, message, details string) bool {
+	if v.w == nil {
+		v.errs = append(v.errs, ValidationError{Code: code, Error: message, Details: details, status: status})
+		return true
+	}
+	if !v.all {
+		v.s.sendErrorCode(v.w, status, code, message, details)
+		return true
+	}
+	log.Printf("   Collected validation error: %s\n", message)
+	v.errs = append(v.errs, ValidationError{Code: code, Error: message, Details: details, status: status})
+	return false
+}
+
+// fatal records a failure later checks depend on and sends everything
+// collected so far. The caller must stop.
+func (v *validation) fatal(status int, code, message, details string) {
+	if v.reject(status, code, message, details) {
+		return
+	}
+	v.flush()
+}
+
+// flush sends the collected failures, if any, and reports whether it did.
+// The first failure sets the status, error and code so fail-fast clients
+// still read the response the same way.
+func (v *validation) flush() bool {
+	if len(v.errs) == 0 {
+		return false
+	}
+
+	first := v.errs[0]
+	log.Printf("\n❌ ERROR RESPONSE [%d]: %d validation error(s)\n", first.status, len(v.errs))
+	writeJSON(v.w, first.status, RelayResponse{
+		Success: false,
+		Error:   first.Error,
+		Code:    first.Code,
+		Details: first.Details,
+		Errors:  v.errs,
+	})
+	return true
+}
+
+// first returns the first recorded failure, if any
+func (v *validation) first() (ValidationError, bool) {
+	if len(v.errs) == 0 {
+		return ValidationError{}, false
+	}
+	return v.errs[0], true
+}
+
+// checkForwardFields runs the checks on the forward's own fields that every
+// relay path shares. It reports whether the caller must stop.
+func (s *Server) checkForwardFields(v *validation, fwd Forward) bool {
+	// Later checks read these, so a forward without them goes no further
+	if fwd.Nonce == nil || fwd.Deadline == nil {
+		log.Println("❌ Validation failed: Missing forward nonce or deadline")
+		v.fatal(http.StatusBadRequest, "MISSING_FORWARD_FIELD", "forward.nonce and forward.deadline are required", "")
+		return true
+	}
+
+	// Reject zero addresses before any further work
+	zeroChecks := []struct {
+		name string
+		addr common.Address
+		code string
+	}{
+		{"from", fwd.From, "ZERO_FROM_ADDRESS"},
+		{"to", fwd.To, "ZERO_TO_ADDRESS"},
+		{"caller", fwd.Caller, "ZERO_CALLER_ADDRESS"},
+	}
+	for _, check := range zeroChecks {
+		// With ALLOW_ANY_CALLER a zero caller means any relayer may submit
+		if check.name == "caller" && s.config.AllowAnyCaller {
+			continue
+		}
+		// A zero target requests a contract deployment where those are enabled
+		if check.name == "to" && s.isDeploy(fwd.To) && s.config.AllowDeploy {
+			continue
+		}
+		if check.addr == (common.Address{}) {
+			log.Printf("❌ Validation failed: forward.%s is the zero address\n", check.name)
+			if v.reject(http.StatusBadRequest, check.code, fmt.Sprintf("forward.%s must not be the zero address", check.name), "") {
+				return true
+			}
+		}
+	}
+
+	// Optionally catch wildly wrong nonces, e.g. a timestamp sent as a nonce
+	if s.config.MaxNonce != nil {
+		if fwd.Nonce.Sign() < 0 || fwd.Nonce.Cmp(s.config.MaxNonce) > 0 {
+			log.Printf("❌ Nonce out of range: %s\n", fwd.Nonce.String())
+			if v.reject(http.StatusBadRequest, "NONCE_OUT_OF_RANGE", fmt.Sprintf("forward.nonce must be between 0 and %s", s.config.MaxNonce.String()), "") {
+				return true
+			}
+		}
+	}
+
+	if code, message := s.checkValue(fwd.Value); code != "" {
+		log.Printf("❌ Value not allowed: %s\n", bigOrZero(fwd.Value).String())
+		if v.reject(http.StatusBadRequest, code, message, "") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkForward runs the relay checks against the forward's target, caller,
+// callData, deadline and on-chain state that /relay, /relay/merkle and
+// /simulate/batch share. It returns the decoded callData and reports whether
+// the caller must stop.
+func (s *Server) checkForward(v *validation, hub HubConfig, fwd Forward, callData string) ([]byte, bool) {
+	deploy := s.isDeploy(fwd.To)
+
+	// Verify target contract
+	log.Printf("🔍 Verifying target contract...\n")
+	log.Printf("   Expected: %s\n", s.config.NFTContract.Hex())
+	log.Printf("   Received: %s\n", fwd.To.Hex())
+	if deploy {
+		log.Println("🏗️  Deployment request")
+	} else if !bytes32Equal(fwd.To, s.config.NFTContract) {
+		log.Printf("❌ Invalid target contract: %s\n", fwd.To.Hex())
+		if v.reject(http.StatusBadRequest, "", "Invalid target contract", "") {
+			return nil, true
+		}
+	} else {
+		log.Println("✅ Target contract verification passed")
+	}
+
+	// Verify caller
+	log.Printf("🔍 Verifying caller address...\n")
+	log.Printf("   Expected: %s\n", s.expectedCaller().Hex())
+	log.Printf("   Received: %s\n", fwd.Caller.Hex())
+	if !s.callerAllowed(fwd.Caller) {
+		log.Printf("❌ Caller mismatch. Expected: %s, Got: %s\n", s.expectedCaller().Hex(), fwd.Caller.Hex())
+		if v.reject(http.StatusBadRequest, "", "Invalid caller address", "") {
+			return nil, true
+		}
+	} else {
+		log.Println("✅ Caller verification passed")
+	}
+
+	// Verify dataHash
+	log.Println("🔍 Verifying dataHash...")
+	log.Printf("   CallData: %s\n", callData)
+	callDataBytes, err := decodeHex(callData)
+	if err != nil {
+		log.Printf("❌ Invalid callData format: %v\n", err)
+		v.fatal(http.StatusBadRequest, "", "Invalid callData format", err.Error())
+		return nil, true
+	}
+	log.Printf("   CallData bytes length: %d\n", len(callDataBytes))
+
+	computedHash := crypto.Keccak256Hash(callDataBytes)
+	receivedHash := common.BytesToHash(fwd.DataHash[:])
+	log.Printf("   Computed hash: %s\n", computedHash.Hex())
+	log.Printf("   Received hash: %s\n", receivedHash.Hex())
+
+	if computedHash != receivedHash {
+		log.Println("❌ DataHash mismatch!")
+		log.Printf("   Computed: %s\n", computedHash.Hex())
+		log.Printf("   Received: %s\n", receivedHash.Hex())
+		if v.reject(http.StatusBadRequest, "", "DataHash mismatch - signature invalid", "") {
+			return nil, true
+		}
+	} else {
+		log.Println("✅ DataHash verification passed")
+	}
+
+	if deploy {
+		if code, message := s.checkDeploy(callDataBytes); code != "" {
+			log.Printf("❌ %s\n", message)
+			if v.reject(http.StatusBadRequest, code, message, "") {
+				return nil, true
+			}
+		} else {
+			log.Printf("✅ Deployment bytecode accepted (%d bytes)\n", len(callDataBytes))
+		}
+	}
+
+	// Optionally require the minted metadata to live on an approved host
+	if len(s.config.AllowedURIHosts) > 0 {
+		tokenURI, isMint, err := decodeTokenURI(callDataBytes)
+		if err != nil {
+			if v.reject(http.StatusBadRequest, "", "Invalid mint callData", err.Error()) {
+				return nil, true
+			}
+		} else if isMint && !uriHostAllowed(tokenURI, s.config.AllowedURIHosts) {
+			log.Printf("❌ tokenUri host not allowed: %s\n", tokenURI)
+			if v.reject(http.StatusBadRequest, "URI_HOST_NOT_ALLOWED", "tokenUri must point at an allowed host", "") {
+				return nil, true
+			}
+		}
+	}
+
+	// Check deadline
+	now := time.Now().Unix()
+	log.Printf("🔍 Checking deadline...\n")
+	if s.config.DeadlineClock == "block" {
+		blockTime, err := s.latestBlockTime()
+		if err != nil {
+			log.Printf("⚠️  Failed to get block time, using wall clock: %v\n", err)
+		} else {
+			log.Printf("   Using block time (wall clock skew: %d seconds)\n", now-blockTime)
+			now = blockTime
+		}
+	}
+	log.Printf("   Current time: %d (%s)\n", now, time.Unix(now, 0).Format(time.RFC3339))
+	deadline := fwd.Deadline.Int64()
+	log.Printf("   Deadline: %d (%s)\n", deadline, time.Unix(deadline, 0).Format(time.RFC3339))
+	log.Printf("   Time remaining: %d seconds\n", deadline-now)
+
+	if now > deadline && s.config.DeadlineClock == "both" {
+		// Give the deadline a second chance against chain time, which may lag
+		// the wall clock
+		blockTime, err := s.latestBlockTime()
+		if err != nil {
+			log.Printf("⚠️  Failed to get block time, keeping wall clock result: %v\n", err)
+		} else if blockTime <= deadline {
+			log.Printf("   Wall clock says expired but block time %d does not, accepting\n", blockTime)
+			s.metrics.Inc("deadline_block_time_rescues_total")
+			now = blockTime
+		}
+	}
+
+	if now > deadline {
+		log.Println("❌ Transaction deadline expired")
+		if v.reject(http.StatusBadRequest, "", "Transaction deadline expired", "") {
+			return nil, true
+		}
+	} else {
+		log.Println("✅ Deadline check passed")
+	}
+
+	// Read minted, nonce and caller state from chain, batched when possible
+	log.Println("🔍 Checking minted status, nonce and caller permission...")
+	reads, err := s.readValidationState(hub, fwd, !deploy)
+	if err != nil && s.config.TestMode {
+		log.Printf("⚠️  TEST_MODE: ignoring validation read error: %v\n", err)
+		reads = ValidationReads{}
+	} else if err != nil {
+		log.Printf("❌ Error reading validation state: %v\n", err)
+		v.fatal(http.StatusInternalServerError, "", "Failed to verify minting status", err.Error())
+		return nil, true
+	}
+
+	if reads.NonceUsed {
+		log.Printf("❌ Nonce already used: space %d nonce %s\n", fwd.Space, fwd.Nonce.String())
+		if v.reject(http.StatusBadRequest, "NONCE_ALREADY_USED", "This nonce has already been used", "") {
+			return nil, true
+		}
+	}
+	if reads.CallerChecked && !reads.CallerAllowed {
+		log.Printf("❌ Relayer %s is not an allowed caller on hub %s\n", s.relayer().Hex(), hub.Version)
+		if v.reject(http.StatusServiceUnavailable, "RELAYER_NOT_ALLOWED", "The relayer is not currently allowed to submit to this hub", "") {
+			return nil, true
+		}
+	}
+
+	// Check if user already minted
+	if !deploy {
+		if reads.Minted {
+			log.Printf("❌ User already minted: %s\n", fwd.From.Hex())
+			if v.reject(http.StatusBadRequest, "", "You already minted an NFT", "") {
+				return nil, true
+			}
+		} else {
+			log.Println("✅ User has not minted yet")
+		}
+
+		// Optionally reject mints once a capped collection has sold out
+		if s.config.EnforceSupplyCap {
+			soldOut, err := s.checkSupplyCap()
+			if err != nil {
+				log.Printf("❌ Error checking supply cap: %v\n", err)
+				v.fatal(http.StatusInternalServerError, "", "Failed to verify collection supply", err.Error())
+				return nil, true
+			}
+			if soldOut {
+				log.Println("❌ Collection sold out")
+				if v.reject(http.StatusGone, "SOLD_OUT", "Collection sold out", "") {
+					return nil, true
+				}
+			}
+		}
+	}
+
+	// Optionally require prior on-chain activity to deter sybils
+	if code, message, err := s.checkFromActivity(fwd.From); err != nil {
+		log.Printf("❌ Error checking account activity: %v\n", err)
+		v.fatal(http.StatusInternalServerError, "", "Failed to verify account activity", err.Error())
+		return nil, true
+	} else if code != "" {
+		log.Printf("❌ %s: %s\n", message, fwd.From.Hex())
+		if v.reject(http.StatusForbidden, code, message, "") {
+			return nil, true
+		}
+	}
+	return callDataBytes, false
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDeadlineClock(t *testing.T) {
+	now := time.Now().Unix()
+	// The chain lags the wall clock by ten minutes
+	blockTime := now - 600
+
+	tests := []struct {
+		name     string
+		clock    string
+		deadline int64
+		expired  bool
+		rescued  bool
+	}{
+		{"wall clock, not yet due", "", now + 60, false, false},
+		{"wall clock, expired", "", now - 60, true, false},
+		{"block clock, expired by wall clock only", "block", now - 60, false, false},
+		{"block clock, expired", "block", blockTime - 60, true, false},
+		{"both, not yet due", "both", now + 60, false, false},
+		{"both, expired by wall clock only", "both", now - 60, false, true},
+		{"both, expired by both clocks", "both", blockTime - 60, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newFakeBackend()
+			backend.blockTime = uint64(blockTime)
+			s := newTestServer(t, backend)
+			s.config.DeadlineClock = tt.clock
+
+			req := testRelayRequest()
+			req.Forward.Caller = s.relayer()
+			req.Forward.Deadline.SetInt64(tt.deadline)
+
+			v := s.newItemValidation()
+			s.checkForward(v, testHub(t), req.Forward, req.CallData)
+			failure, failed := v.first()
+			expired := failed && failure.Error == "Transaction deadline expired"
+			if expired != tt.expired {
+				t.Fatalf("expired = %v (%+v), want %v", expired, failure, tt.expired)
+			}
+			if failed && !expired {
+				t.Fatalf("unexpected rejection: %+v", failure)
+			}
+
+			rescues := strings.Contains(metricsText(s.metrics), "deadline_block_time_rescues_total 1")
+			if rescues != tt.rescued {
+				t.Fatalf("block time rescue recorded = %v, want %v", rescues, tt.rescued)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// parseAllowedValues parses ALLOWED_VALUES wei amounts
+func parseAllowedValues(entries []string) ([]*big.Int, error) {
+	values := make([]*big.Int, 0, len(entries))
+	for _, entry := range entries {
+		value, ok := new(big.Int).SetString(strings.TrimSpace(entry), 10)
+		if !ok || value.Sign() < 0 {
+			return nil, fmt.Errorf("invalid ALLOWED_VALUES entry %q", entry)
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// checkValue validates forward.value. Without ALLOW_VALUE only zero is
+// accepted; with it, ALLOWED_VALUES (when set) lists the exact amounts the
+// relayer will pay along with the call. Returns an error code and message
+// when the value is rejected.
+func (s *Server) checkValue(value *big.Int) (code, message string) {
+	value = bigOrZero(value)
+	if value.Sign() == 0 {
+		return "", ""
+	}
+	if !s.config.AllowValue {
+		return "VALUE_NOT_ALLOWED", "forward.value must be 0"
+	}
+	if len(s.config.AllowedValues) == 0 {
+		return "", ""
+	}
+
+	allowed := make([]string, len(s.config.AllowedValues))
+	for i, v := range s.config.AllowedValues {
+		if v.Cmp(value) == 0 {
+			return "", ""
+		}
+		allowed[i] = v.String()
+	}
+	return "VALUE_NOT_ALLOWED", fmt.Sprintf("forward.value must be one of: %s", strings.Join(allowed, ", "))
+}
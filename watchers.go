@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// WatcherPool bounds how many receipt watchers poll the node at once.
+// Watchers beyond the limit queue until a slot frees up.
+type WatcherPool struct {
+	slots  chan struct{}
+	active atomic.Int64
+	queued atomic.Int64
+}
+
+// NewWatcherPool creates a pool of size slots. A size of 0 means unbounded.
+func NewWatcherPool(size int) *WatcherPool {
+	pool := &WatcherPool{}
+	if size > 0 {
+		pool.slots = make(chan struct{}, size)
+	}
+	return pool
+}
+
+// Acquire waits for a free slot or for ctx to expire
+func (p *WatcherPool) Acquire(ctx context.Context) error {
+	if p.slots != nil {
+		p.queued.Add(1)
+		defer p.queued.Add(-1)
+		select {
+		case p.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	p.active.Add(1)
+	return nil
+}
+
+// Release frees a slot taken by Acquire
+func (p *WatcherPool) Release() {
+	p.active.Add(-1)
+	if p.slots != nil {
+		<-p.slots
+	}
+}
+
+// WatcherStats reports receipt watcher usage
+type WatcherStats struct {
+	Active int64 `json:"active"`
+	Queued int64 `json:"queued"`
+	Limit  int   `json:"limit,omitempty"`
+}
+
+// Stats returns the current watcher usage
+func (p *WatcherPool) Stats() WatcherStats {
+	return WatcherStats{Active: p.active.Load(), Queued: p.queued.Load(), Limit: cap(p.slots)}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Webhook event types
+const (
+	webhookRelaySucceeded = "relay.succeeded"
+	webhookRelayFailed    = "relay.failed"
+	webhookRelayReorged   = "relay.reorged"
+)
+
+// WebhookEvent is POSTed as JSON to WEBHOOK_URL when a relay completes
+type WebhookEvent struct {
+	Event       string `json:"event"`
+	From        string `json:"from"`
+	TxHash      string `json:"txHash,omitempty"`
+	BlockNumber uint64 `json:"blockNumber,omitempty"`
+	GasUsed     string `json:"gasUsed,omitempty"`
+	Error       string `json:"error,omitempty"`
+	Timestamp   int64  `json:"timestamp"`
+}
+
+// webhookConfirmTimeout bounds how long a success webhook waits for
+// WEBHOOK_CONFIRMATIONS before it is dropped
+const webhookConfirmTimeout = 30 * time.Minute
+
+// signWebhook returns the hex HMAC-SHA256 of body keyed with secret.
+//
+// Receivers verify a callback by computing HMAC-SHA256 over the exact raw
+// request body with the shared WEBHOOK_SECRET and comparing it, in constant
+// time, against the X-Signature header value after its "sha256=" prefix.
+func signWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// sendSuccessWebhook delivers a relay.succeeded event once the transaction
+// mined in blockHash is WEBHOOK_CONFIRMATIONS deep. The HTTP response doesn't
+// wait for this. If the transaction is reorged out meanwhile, no success is
+// sent.
+func (s *Server) sendSuccessWebhook(event WebhookEvent, blockHash common.Hash) {
+	if s.config.WebhookURL == "" {
+		return
+	}
+	// Simulated TEST_MODE transactions never gain confirmations
+	if s.config.WebhookConfirmations <= 1 || s.config.TestMode {
+		s.sendWebhook(event)
+		return
+	}
+
+	q := &s.webhookQueue
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending = append(q.pending, pendingWebhook{
+		event:     event,
+		blockHash: blockHash,
+		target:    event.BlockNumber + s.config.WebhookConfirmations - 1,
+		expires:   time.Now().Add(webhookConfirmTimeout),
+	})
+	if !q.running {
+		q.running = true
+		s.relays.Add(1)
+		go s.confirmWebhooks()
+	}
+}
+
+// pendingWebhook is a success event waiting for its confirmations
+type pendingWebhook struct {
+	event     WebhookEvent
+	blockHash common.Hash
+	target    uint64
+	expires   time.Time
+}
+
+// webhookQueue holds success webhooks waiting for WEBHOOK_CONFIRMATIONS. A
+// single loop checks all of them and only runs while the queue is non-empty.
+type webhookQueue struct {
+	mu      sync.Mutex
+	pending []pendingWebhook
+	running bool
+}
+
+// confirmWebhooks checks queued webhooks every RECEIPT_POLL until none remain
+func (s *Server) confirmWebhooks() {
+	defer s.relays.Done()
+	ticker := time.NewTicker(s.config.ReceiptPoll)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if !s.checkWebhookConfirmations() {
+			return
+		}
+	}
+}
+
+// checkWebhookConfirmations sends every queued webhook that is deep enough,
+// drops those reorged out or expired, and reports whether any remain
+func (s *Server) checkWebhookConfirmations() bool {
+	q := &s.webhookQueue
+	q.mu.Lock()
+	pending := q.pending
+	q.pending = nil
+	q.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	head, headErr := s.client.BlockNumber(ctx)
+	var waiting []pendingWebhook
+	for _, p := range pending {
+		if headErr == nil && head >= p.target {
+			receipt, err := s.client.TransactionReceipt(ctx, common.HexToHash(p.event.TxHash))
+			if err == nil && receipt.BlockHash == p.blockHash {
+				s.sendWebhook(p.event)
+				continue
+			}
+			if err == nil || errors.Is(err, ethereum.NotFound) {
+				log.Printf("⚠️  Not sending success webhook for %s: no longer in block %d\n", p.event.TxHash, p.event.BlockNumber)
+				continue
+			}
+		}
+		if time.Now().After(p.expires) {
+			log.Printf("⚠️  Dropping webhook for %s: %d confirmations not reached\n", p.event.TxHash, s.config.WebhookConfirmations)
+			continue
+		}
+		waiting = append(waiting, p)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.pending = append(q.pending, waiting...)
+	if len(q.pending) == 0 {
+		q.running = false
+		return false
+	}
+	return true
+}
+
+// sendWebhook delivers event to the configured webhook in the background
+func (s *Server) sendWebhook(event WebhookEvent) {
+	if s.config.WebhookURL == "" {
+		return
+	}
+	event.Timestamp = time.Now().Unix()
+
+	s.relays.Add(1)
+	go func() {
+		defer s.relays.Done()
+		body, err := json.Marshal(event)
+		if err != nil {
+			log.Printf("⚠️  Failed to encode webhook: %v\n", err)
+			return
+		}
+
+		req, err := http.NewRequest(http.MethodPost, s.config.WebhookURL, bytes.NewReader(body))
+		if err != nil {
+			log.Printf("⚠️  Failed to build webhook request: %v\n", err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if s.config.WebhookSecret != "" {
+			req.Header.Set("X-Signature", "sha256="+signWebhook(s.config.WebhookSecret, body))
+		}
+
+		client := &http.Client{Timeout: s.config.WebhookTimeout}
+		resp, err := client.Do(req)
+		if err != nil {
+			log.Printf("⚠️  Webhook %s delivery failed: %v\n", event.Event, err)
+			s.metrics.Inc("webhook_deliveries_total", "result", "error")
+			return
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= 300 {
+			log.Printf("⚠️  Webhook %s rejected with status %d\n", event.Event, resp.StatusCode)
+			s.metrics.Inc("webhook_deliveries_total", "result", "rejected")
+			return
+		}
+		s.metrics.Inc("webhook_deliveries_total", "result", "ok")
+	}()
+}
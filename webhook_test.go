@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// webhookRecorder collects delivered webhook events
+type webhookRecorder struct {
+	mu     sync.Mutex
+	events []WebhookEvent
+}
+
+func (rec *webhookRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var event WebhookEvent
+	json.NewDecoder(r.Body).Decode(&event)
+	rec.mu.Lock()
+	rec.events = append(rec.events, event)
+	rec.mu.Unlock()
+}
+
+// waitFor waits up to timeout until n events were delivered, then returns
+// what was delivered
+func (rec *webhookRecorder) waitFor(n int, timeout time.Duration) []WebhookEvent {
+	deadline := time.Now().Add(timeout)
+	for {
+		rec.mu.Lock()
+		events := append([]WebhookEvent(nil), rec.events...)
+		rec.mu.Unlock()
+		if len(events) >= n || time.Now().After(deadline) {
+			return events
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// mineEmpty advances the fake chain by one block
+func mineEmpty(backend *fakeBackend) {
+	backend.mine(types.NewTransaction(1<<32, common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil))
+}
+
+func TestSuccessWebhookWaitsForDeeperConfirmationThanResponse(t *testing.T) {
+	recorder := &webhookRecorder{}
+	webhook := httptest.NewServer(recorder)
+	defer webhook.Close()
+
+	backend := newFakeBackend()
+	s := newTestServer(t, backend)
+	s.config.WebhookURL = webhook.URL
+	s.config.WebhookTimeout = time.Second
+	s.config.WebhookConfirmations = 3
+
+	// Two relays answered at one confirmation each, in blocks 1 and 2
+	var hashes []string
+	for nonce := int64(7); nonce <= 8; nonce++ {
+		req := testRelayRequest()
+		req.Forward.Caller = s.relayer()
+		req.Forward.Nonce = big.NewInt(nonce)
+		status, response := relayResponse(t, s, req, "")
+		if status != http.StatusOK {
+			t.Fatalf("relay: %d %+v", status, response)
+		}
+		hashes = append(hashes, response.TxHash)
+	}
+	if events := recorder.waitFor(1, 10*s.config.ReceiptPoll); len(events) != 0 {
+		t.Fatalf("webhook fired at one confirmation: %+v", events)
+	}
+
+	// Block 3 gives the first relay its third confirmation only
+	mineEmpty(backend)
+	events := recorder.waitFor(1, 2*time.Second)
+	if len(events) != 1 || events[0].TxHash != hashes[0] || events[0].BlockNumber != 1 {
+		t.Fatalf("after block 3 got %+v, want only the block 1 relay", events)
+	}
+
+	mineEmpty(backend)
+	events = recorder.waitFor(2, 2*time.Second)
+	if len(events) != 2 || events[1].TxHash != hashes[1] || events[1].Event != webhookRelaySucceeded {
+		t.Fatalf("after block 4 got %+v, want the block 2 relay too", events)
+	}
+
+	// With the queue empty the shared confirmation loop stops
+	time.Sleep(5 * s.config.ReceiptPoll)
+	s.webhookQueue.mu.Lock()
+	running := s.webhookQueue.running
+	s.webhookQueue.mu.Unlock()
+	if running {
+		t.Fatal("confirmation loop still running with nothing queued")
+	}
+}
+
+func TestSuccessWebhookDroppedAfterReorg(t *testing.T) {
+	recorder := &webhookRecorder{}
+	webhook := httptest.NewServer(recorder)
+	defer webhook.Close()
+
+	backend := newFakeBackend()
+	s := newTestServer(t, backend)
+	s.config.WebhookURL = webhook.URL
+	s.config.WebhookConfirmations = 2
+
+	tx := types.NewTransaction(0, common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil)
+	receipt := backend.mine(tx)
+	s.sendSuccessWebhook(WebhookEvent{Event: webhookRelaySucceeded, TxHash: tx.Hash().Hex(), BlockNumber: 1}, receipt.BlockHash)
+
+	// The transaction is re-mined in another block before it is deep enough
+	backend.mu.Lock()
+	delete(backend.receipts, tx.Hash())
+	backend.mu.Unlock()
+	backend.mine(tx)
+
+	if events := recorder.waitFor(1, 10*s.config.ReceiptPoll); len(events) != 0 {
+		t.Fatalf("webhook sent for a reorged transaction: %+v", events)
+	}
+}